@@ -0,0 +1,305 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/storage"
+)
+
+// newLogger creates the JSON logger used throughout the server.
+// LOG_LEVEL sets the minimum level: debug, info (the default), warn, or error.
+func newLogger() *slog.Logger {
+	var level slog.Level
+	value := os.Getenv("LOG_LEVEL")
+	err := level.UnmarshalText([]byte(value))
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	if value != "" && err != nil {
+		logger.Warn("ignoring invalid environment variable", "name", "LOG_LEVEL", "value", value, "error", err)
+	}
+	return logger
+}
+
+// loadConfig builds the game configuration from environment variables,
+// falling back to the defaults for anything that is not set.
+// The game server logs through logger.
+func loadConfig(logger *slog.Logger) game.Config {
+	config := game.DefaultConfig()
+	config.Logger = logger
+	config.LayawayEnabled = envBool("LAYAWAY_ENABLED", config.LayawayEnabled)
+	config.BatchNotifications = envBool("BATCH_NOTIFICATIONS", config.BatchNotifications)
+	config.TickInterval = envDuration("TICK_INTERVAL", config.TickInterval)
+	config.BroadcastEveryNTicks = envIntMin("BROADCAST_EVERY_N_TICKS", config.BroadcastEveryNTicks, 1)
+	config.MaxUpdatesPerBroadcast = envIntMin("MAX_UPDATES_PER_BROADCAST", config.MaxUpdatesPerBroadcast, 0)
+	config.BattleWorkers = envIntMin("BATTLE_WORKERS", config.BattleWorkers, 0)
+	config.SaveInterval = envDuration("SAVE_INTERVAL", config.SaveInterval)
+	config.MaxOfflineDuration = envDuration("MAX_OFFLINE_DURATION", config.MaxOfflineDuration)
+	config.RandomSeed = int64(envInt("RANDOM_SEED", int(config.RandomSeed)))
+	config.PrestigeThreshold = envInt("PRESTIGE_THRESHOLD", config.PrestigeThreshold)
+	config.BossInterval = envInt("BOSS_INTERVAL", config.BossInterval)
+	config.RandomEnemyTypes = envBool("RANDOM_ENEMY_TYPES", config.RandomEnemyTypes)
+	config.ArmorPenStartLevel = envInt("ARMOR_PEN_START_LEVEL", config.ArmorPenStartLevel)
+	config.ArmorPenPerLevel = envFloat("ARMOR_PEN_PER_LEVEL", config.ArmorPenPerLevel)
+	config.ArmorPenMax = envFloat("ARMOR_PEN_MAX", config.ArmorPenMax)
+	config.EnemyCurve = envEnemyCurve("ENEMY_CURVE", config.EnemyCurve)
+	config.StationCurves = envStationCurves("STATION_CURVES", config.StationCurves)
+	config.MaxStationLevel = envIntMin("MAX_STATION_LEVEL", config.MaxStationLevel, 0)
+	config.ZoneSize = envIntMin("ZONE_SIZE", config.ZoneSize, 1)
+	config.LootTables = envLootTables("LOOT_TABLES", config.LootTables)
+	config.SoftCapThreshold = envFloat("SOFT_CAP_THRESHOLD", config.SoftCapThreshold)
+	config.PassiveGold = envFloat("PASSIVE_GOLD", config.PassiveGold)
+	config.StartingGold = envIntMin("STARTING_GOLD", config.StartingGold, 0)
+	config.StartingDungeonLevel = envIntMin("STARTING_DUNGEON_LEVEL", config.StartingDungeonLevel, 1)
+	config.StartingStationLevels = envStationLevels("STARTING_STATION_LEVELS", config.StartingStationLevels, config.MaxStationLevel)
+	config.EvictAfter = envDuration("EVICT_AFTER", config.EvictAfter)
+	config.UpgradeRateLimit = envFloatMin("UPGRADE_RATE_LIMIT", config.UpgradeRateLimit, 0)
+	config.MaxClients = envInt("MAX_CLIENTS", config.MaxClients)
+	config.DisplayNameLength = envIntMin("DISPLAY_NAME_LENGTH", config.DisplayNameLength, 1)
+	config.CompressMessages = envBool("WS_COMPRESSION", config.CompressMessages)
+	config.CompressionLevel = envInt("WS_COMPRESSION_LEVEL", config.CompressionLevel)
+	config.AllowedOrigins = envList("ALLOWED_ORIGINS", config.AllowedOrigins)
+	config.ExportSecret = []byte(os.Getenv("EXPORT_SECRET"))
+	config.ExportTTL = envDuration("EXPORT_TTL", config.ExportTTL)
+	return config
+}
+
+// loadPersister selects where the game state is stored.
+// STORAGE_BACKEND picks "json" (the default) or "sqlite". For the JSON backend,
+// STATE_FILE overrides the file location and an empty value disables persistence
+// entirely, keeping all state in memory. For SQLite, SQLITE_PATH sets the database file.
+func loadPersister() (models.Persister, error) {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "sqlite":
+		path := os.Getenv("SQLITE_PATH")
+		if path == "" {
+			path = "idle-dungeon.db"
+		}
+		slog.Info("💾 Persisting game state to SQLite", "path", path)
+		return storage.NewSQLitePersister(path)
+
+	case "", "json":
+		path, set := os.LookupEnv("STATE_FILE")
+		if !set {
+			path = "idle-dungeon-state.json"
+		}
+		if path == "" {
+			slog.Info("💾 Persistence disabled, state is kept in memory only")
+			return nil, nil
+		}
+		slog.Info("💾 Persisting game state to a JSON file", "path", path)
+		return storage.NewJSONFilePersister(path), nil
+
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q (expected json or sqlite)", backend)
+	}
+}
+
+// envBool reads a boolean environment variable, returning fallback when it is unset or invalid.
+func envBool(name string, fallback bool) bool {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		slog.Warn("ignoring invalid environment variable", "name", name, "value", value, "error", err)
+		return fallback
+	}
+	return parsed
+}
+
+// envInt reads an integer environment variable, returning fallback when it is unset or invalid.
+func envInt(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Warn("ignoring invalid environment variable", "name", name, "value", value, "error", err)
+		return fallback
+	}
+	return parsed
+}
+
+// envIntMin reads an integer environment variable that must be at least minimum,
+// returning fallback when it is unset, invalid, or too small.
+func envIntMin(name string, fallback, minimum int) int {
+	value := envInt(name, fallback)
+	if value < minimum {
+		slog.Warn("ignoring invalid environment variable", "name", name, "value", os.Getenv(name), "minimum", minimum)
+		return fallback
+	}
+	return value
+}
+
+// envFloat reads a floating-point environment variable, returning fallback when it is unset or invalid.
+func envFloat(name string, fallback float64) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		slog.Warn("ignoring invalid environment variable", "name", name, "value", value, "error", err)
+		return fallback
+	}
+	return parsed
+}
+
+// envFloatMin reads a floating-point environment variable that must be at least
+// minimum, returning fallback when it is unset, invalid, or too small.
+func envFloatMin(name string, fallback, minimum float64) float64 {
+	value := envFloat(name, fallback)
+	if value < minimum {
+		slog.Warn("ignoring invalid environment variable", "name", name, "value", os.Getenv(name), "minimum", minimum)
+		return fallback
+	}
+	return value
+}
+
+// envList reads a comma-separated environment variable, trimming each entry and
+// dropping empty ones. It returns fallback when the variable is unset.
+func envList(name string, fallback []string) []string {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	var list []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+// envEnemyCurve reads the enemy scaling curve from a JSON object, such as
+// {"hp":{"quadratic":0.5},"attack":{"exponential":0.02}}. Fields it sets
+// override fallback and the rest are kept. An unparsable value, or an
+// exponential rate of -1 or below, leaves fallback unchanged with a warning.
+func envEnemyCurve(name string, fallback game.EnemyCurve) game.EnemyCurve {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	curve := fallback
+	if err := json.Unmarshal([]byte(value), &curve); err != nil {
+		slog.Warn("ignoring invalid environment variable", "name", name, "value", value, "error", err)
+		return fallback
+	}
+	if curve.HP.Exponential <= -1 || curve.Attack.Exponential <= -1 {
+		slog.Warn("ignoring invalid enemy curve in environment variable", "name", name, "value", value)
+		return fallback
+	}
+	return curve
+}
+
+// envStationCurves reads per-station upgrade curves from a JSON object keyed by
+// station type, such as {"loot":{"costGrowth":1.3},"attack":{"multiplierIncrement":0.25}}.
+// Each entry overrides only the fields it sets on top of fallback. Unknown
+// station types and curves with a negative increment or a cost growth below 1
+// are ignored with a warning, and an unparsable value leaves fallback unchanged.
+func envStationCurves(name string, fallback map[models.StationType]game.StationCurve) map[models.StationType]game.StationCurve {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	var overrides map[models.StationType]json.RawMessage
+	if err := json.Unmarshal([]byte(value), &overrides); err != nil {
+		slog.Warn("ignoring invalid environment variable", "name", name, "value", value, "error", err)
+		return fallback
+	}
+
+	curves := make(map[models.StationType]game.StationCurve, len(fallback))
+	for stationType, curve := range fallback {
+		curves[stationType] = curve
+	}
+	for stationType, override := range overrides {
+		curve, known := curves[stationType]
+		if !known {
+			slog.Warn("ignoring unknown station in environment variable", "name", name, "station", stationType)
+			continue
+		}
+		if err := json.Unmarshal(override, &curve); err != nil || !curve.Valid() {
+			slog.Warn("ignoring invalid station curve in environment variable", "name", name, "station", stationType, "value", string(override))
+			continue
+		}
+		curves[stationType] = curve
+	}
+	return curves
+}
+
+// envLootTables reads per-zone loot tables from a JSON object keyed by zone
+// number, such as {"1":[{"stat":"attack","rarity":"common","weight":3}]}. An
+// unparsable value leaves fallback unchanged with a warning; NewServer rejects
+// tables with unknown stats or rarities.
+func envLootTables(name string, fallback map[int][]game.LootEntry) map[int][]game.LootEntry {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	var tables map[int][]game.LootEntry
+	if err := json.Unmarshal([]byte(value), &tables); err != nil || tables == nil {
+		slog.Warn("ignoring invalid environment variable", "name", name, "value", value, "error", err)
+		return fallback
+	}
+	return tables
+}
+
+// envStationLevels reads starting station levels from a JSON object keyed by
+// station type, such as {"hp":5,"attack":3}. Unknown station types and levels
+// below 1 are ignored with a warning, levels above maxLevel are lowered to it
+// with a warning unless maxLevel is 0, and an unparsable value leaves fallback unchanged.
+func envStationLevels(name string, fallback map[models.StationType]int, maxLevel int) map[models.StationType]int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	var decoded map[models.StationType]int
+	if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+		slog.Warn("ignoring invalid environment variable", "name", name, "value", value, "error", err)
+		return fallback
+	}
+
+	levels := make(map[models.StationType]int, len(decoded))
+	for stationType, level := range decoded {
+		if !slices.Contains(models.StationTypes, stationType) {
+			slog.Warn("ignoring unknown station in environment variable", "name", name, "station", stationType)
+			continue
+		}
+		if level < 1 {
+			slog.Warn("ignoring invalid station level in environment variable", "name", name, "station", stationType, "level", level)
+			continue
+		}
+		if maxLevel > 0 && level > maxLevel {
+			slog.Warn("lowering station level in environment variable to the maximum", "name", name, "station", stationType, "level", level, "maximum", maxLevel)
+			level = maxLevel
+		}
+		levels[stationType] = level
+	}
+	return levels
+}
+
+// envDuration reads a duration environment variable such as "30s", returning fallback when it is unset or invalid.
+func envDuration(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		slog.Warn("ignoring invalid environment variable", "name", name, "value", value)
+		return fallback
+	}
+	return parsed
+}
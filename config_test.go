@@ -0,0 +1,123 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// testConfig loads the configuration from the environment, discarding its logs.
+func testConfig() game.Config {
+	return loadConfig(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestLoadConfigStartingState(t *testing.T) {
+	t.Setenv("STARTING_GOLD", "2500")
+	t.Setenv("STARTING_DUNGEON_LEVEL", "10")
+	t.Setenv("STARTING_STATION_LEVELS", `{"loot":5,"attack":3}`)
+
+	config := testConfig()
+	if config.StartingGold != 2500 || config.StartingDungeonLevel != 10 {
+		t.Errorf("starting gold %d on level %d, want 2500 on level 10", config.StartingGold, config.StartingDungeonLevel)
+	}
+	want := map[models.StationType]int{models.StationLoot: 5, models.StationAttack: 3}
+	if !reflect.DeepEqual(config.StartingStationLevels, want) {
+		t.Errorf("starting station levels = %v, want %v", config.StartingStationLevels, want)
+	}
+}
+
+func TestLoadConfigRejectsInvalidStartingState(t *testing.T) {
+	t.Setenv("STARTING_GOLD", "-1")
+	t.Setenv("STARTING_DUNGEON_LEVEL", "0")
+	t.Setenv("STARTING_STATION_LEVELS", `{"loot":0,"speed":4,"hp":2}`)
+
+	config, defaults := testConfig(), game.DefaultConfig()
+	if config.StartingGold != defaults.StartingGold || config.StartingDungeonLevel != defaults.StartingDungeonLevel {
+		t.Errorf("invalid values loaded as %d gold on level %d, want the defaults", config.StartingGold, config.StartingDungeonLevel)
+	}
+	want := map[models.StationType]int{models.StationHP: 2}
+	if !reflect.DeepEqual(config.StartingStationLevels, want) {
+		t.Errorf("starting station levels = %v, want only the valid %v", config.StartingStationLevels, want)
+	}
+
+	t.Setenv("STARTING_STATION_LEVELS", `not json`)
+	if levels := testConfig().StartingStationLevels; levels != nil {
+		t.Errorf("malformed station levels loaded as %v, want none", levels)
+	}
+}
+
+func TestLoadConfigClampsStartingStationLevels(t *testing.T) {
+	t.Setenv("MAX_STATION_LEVEL", "10")
+	t.Setenv("STARTING_STATION_LEVELS", `{"loot":50,"hp":10,"attack":3}`)
+	want := map[models.StationType]int{models.StationLoot: 10, models.StationHP: 10, models.StationAttack: 3}
+	if levels := testConfig().StartingStationLevels; !reflect.DeepEqual(levels, want) {
+		t.Errorf("starting station levels = %v, want %v", levels, want)
+	}
+
+	// Without a maximum any level is kept
+	t.Setenv("MAX_STATION_LEVEL", "0")
+	if levels := testConfig().StartingStationLevels; levels[models.StationLoot] != 50 {
+		t.Errorf("starting station levels = %v without a maximum, want loot at 50", levels)
+	}
+}
+
+func TestLoadConfigEnemyCurve(t *testing.T) {
+	t.Setenv("ENEMY_CURVE", `{"hp":{"base":100,"quadratic":1.5}}`)
+	config := testConfig()
+	// Fields left out keep their default
+	want := game.DefaultEnemyCurve
+	want.HP.Base, want.HP.Quadratic = 100, 1.5
+	if config.EnemyCurve != want {
+		t.Errorf("enemy curve = %+v, want %+v", config.EnemyCurve, want)
+	}
+
+	for _, value := range []string{`{"attack":{"exponential":-1}}`, `{"hp":`} {
+		t.Setenv("ENEMY_CURVE", value)
+		if curve := testConfig().EnemyCurve; curve != game.DefaultEnemyCurve {
+			t.Errorf("invalid ENEMY_CURVE %s loaded as %+v, want the default", value, curve)
+		}
+	}
+}
+
+func TestLoadConfigLootTables(t *testing.T) {
+	t.Setenv("ZONE_SIZE", "5")
+	t.Setenv("LOOT_TABLES", `{"1":[{"stat":"attack","rarity":"common","weight":3}],"2":[]}`)
+	config := testConfig()
+	if config.ZoneSize != 5 {
+		t.Errorf("ZONE_SIZE=5 loaded as %d", config.ZoneSize)
+	}
+	want := map[int][]game.LootEntry{
+		1: {{Stat: models.StationAttack, Rarity: models.RarityCommon, Weight: 3}},
+		2: {},
+	}
+	if !reflect.DeepEqual(config.LootTables, want) {
+		t.Errorf("loot tables = %+v, want %+v", config.LootTables, want)
+	}
+
+	t.Setenv("LOOT_TABLES", `[1, 2]`)
+	if tables := testConfig().LootTables; tables != nil {
+		t.Errorf("malformed LOOT_TABLES loaded as %+v, want none", tables)
+	}
+}
+
+func TestLoadConfigUpgradeRateLimit(t *testing.T) {
+	t.Setenv("UPGRADE_RATE_LIMIT", "0.5")
+	if limit := testConfig().UpgradeRateLimit; limit != 0.5 {
+		t.Errorf("UPGRADE_RATE_LIMIT=0.5 loaded as %g", limit)
+	}
+	t.Setenv("UPGRADE_RATE_LIMIT", "0")
+	if limit := testConfig().UpgradeRateLimit; limit != 0 {
+		t.Errorf("UPGRADE_RATE_LIMIT=0 loaded as %g, want the limit disabled", limit)
+	}
+
+	for _, value := range []string{"-1", "-0.5", "fast"} {
+		t.Setenv("UPGRADE_RATE_LIMIT", value)
+		if limit, want := testConfig().UpgradeRateLimit, game.DefaultConfig().UpgradeRateLimit; limit != want {
+			t.Errorf("invalid UPGRADE_RATE_LIMIT %s loaded as %g, want the default %g", value, limit, want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package game
+
+import (
+	"errors"
+	"math"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// ErrUnknownPlayer is returned when an admin operation names a player that does not exist.
+var ErrUnknownPlayer = errors.New("unknown player")
+
+// GrantResult reports the effect of a GrantGold adjustment.
+type GrantResult struct {
+	PlayerID   string `json:"playerId"`   // Player whose gold was adjusted
+	Delta      int    `json:"delta"`      // Requested change in gold
+	GoldBefore int    `json:"goldBefore"` // Gold before the adjustment
+	GoldAfter  int    `json:"goldAfter"`  // Gold after the adjustment, never below zero
+}
+
+// GrantGold adds delta gold to an existing player, or removes it when delta is
+// negative, clamping the balance at zero. A deduction also voids the player's
+// earlier exports, so importing one cannot undo it. It never creates a player
+// and returns ErrUnknownPlayer when there is none with the ID. Every grant is
+// logged with the balance before and after.
+func (s *Server) GrantGold(playerID string, delta int) (*GrantResult, error) {
+	result := &GrantResult{PlayerID: playerID, Delta: delta}
+	exists := s.gameState.UpdatePlayer(playerID, func(player *models.Player) {
+		result.GoldBefore = player.Progress.Gold
+		player.Progress.Gold = max(0, player.Progress.Gold+delta)
+		result.GoldAfter = player.Progress.Gold
+		if delta < 0 {
+			player.ExportsVoidBefore = s.clock.Now()
+		}
+	})
+	if !exists {
+		return nil, ErrUnknownPlayer
+	}
+
+	s.logger.Info("granted gold", "event", "admin_grant", "player_id", playerID,
+		"delta", delta, "gold_before", result.GoldBefore, "gold_after", result.GoldAfter)
+	return result, nil
+}
+
+// RecomputeResult summarizes a RecomputeDerived pass over all players.
+type RecomputeResult struct {
+	PlayersChecked int `json:"playersChecked"` // Number of players examined
+	PlayersChanged int `json:"playersChanged"` // Number of players whose derived fields were repaired
+}
+
+// RecomputeDerived reapplies the canonical derivation functions to every player,
+// repairing station multipliers, costs, prestige multipliers, hero levels, and
+// the scores derived from them, passive gold per tick and the deepest dungeon
+// level reached, that have drifted from their sources. A player missing their
+// factory, progress, or a station is repaired with defaults first, as before a battle.
+// The game loop is paused for the duration so no tick observes a half-repaired player.
+// Running it twice in a row changes nothing the second time.
+func (s *Server) RecomputeDerived() RecomputeResult {
+	s.loopMutex.Lock()
+	defer s.loopMutex.Unlock()
+
+	var result RecomputeResult
+	for _, player := range s.gameState.GetAllPlayers() {
+		result.PlayersChecked++
+		var changed bool
+		s.gameState.Update(func() {
+			changed = s.recomputePlayer(player)
+		})
+		if changed {
+			result.PlayersChanged++
+		}
+	}
+
+	s.logger.Info("recomputed derived fields", "event", "recompute",
+		"players_checked", result.PlayersChecked, "players_changed", result.PlayersChanged)
+	return result
+}
+
+// recomputePlayer repairs a single player's derived station, prestige, hero level, and score fields and reports whether anything changed.
+// The caller holds the game-state write lock.
+func (s *Server) recomputePlayer(player *models.Player) bool {
+	changed := false
+	if player.Malformed() {
+		s.validatePlayer(player, "recompute")
+		changed = true
+	}
+	if level := models.HeroLevel(player.Progress.Experience); player.Progress.HeroLevel != level {
+		s.logger.Info("recomputed hero level", "event", "recompute", "player_id", player.ID,
+			"experience", player.Progress.Experience, "from", player.Progress.HeroLevel, "to", level)
+		player.Progress.HeroLevel = level
+		changed = true
+	}
+	if multiplier := prestigeMultiplier(player.PrestigeLevel); math.Abs(player.PrestigeMultiplier-multiplier) > 1e-9 {
+		s.logger.Info("recomputed prestige multiplier", "event", "recompute", "player_id", player.ID,
+			"prestige_level", player.PrestigeLevel, "from", player.PrestigeMultiplier, "to", multiplier)
+		player.PrestigeMultiplier = multiplier
+		changed = true
+	}
+
+	for _, stationType := range models.StationTypes {
+		station := player.Factory.Station(stationType)
+		curve := s.stationCurve(stationType)
+		multiplier := stationMultiplier(curve, station.Level)
+		cost := stationCost(curve, station.Level)
+
+		if math.Abs(station.Multiplier-multiplier) > 1e-9 || station.Cost != cost {
+			s.logger.Info("recomputed station", "event", "recompute", "player_id", player.ID,
+				"station", stationType, "level", station.Level,
+				"multiplier_from", station.Multiplier, "multiplier_to", multiplier,
+				"cost_from", station.Cost, "cost_to", cost)
+			station.Multiplier = multiplier
+			station.Cost = cost
+			changed = true
+		}
+	}
+
+	// Scores derived from the repaired fields, passive income last since it follows the loot station
+	deepest := player.Progress.MaxDungeonLevel
+	player.TrackMaxDungeonLevel()
+	if player.Progress.MaxDungeonLevel != deepest {
+		s.logger.Info("recomputed deepest dungeon level", "event", "recompute", "player_id", player.ID,
+			"from", deepest, "to", player.Progress.MaxDungeonLevel)
+		changed = true
+	}
+	if income := s.goldPerTick(player); player.Progress.GoldPerTick != income {
+		s.logger.Info("recomputed gold per tick", "event", "recompute", "player_id", player.ID,
+			"from", player.Progress.GoldPerTick, "to", income)
+		player.Progress.GoldPerTick = income
+		changed = true
+	}
+	return changed
+}
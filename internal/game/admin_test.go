@@ -0,0 +1,73 @@
+package game_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game/testutil"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+func TestRecomputeDerivedRepairsDrift(t *testing.T) {
+	config := game.DefaultConfig()
+	h := testutil.New(t, config)
+	alice, _ := h.Server.GetOrCreatePlayer("alice")
+	h.Server.GetOrCreatePlayer("bob")
+	h.Server.View(func() {
+		loot := alice.Factory.Station(models.StationLoot)
+		loot.Level, loot.Multiplier, loot.Cost = 3, 9, 1
+		alice.Progress.Experience, alice.Progress.HeroLevel = 0, 50
+		alice.PrestigeMultiplier = 3
+		alice.Progress.GoldPerTick = 999
+		alice.Heroes = []*models.HeroSlot{{DungeonLevel: 25}}
+	})
+	bob := h.Player("bob")
+
+	result := h.Server.RecomputeDerived()
+	if result.PlayersChecked != 2 || result.PlayersChanged != 1 {
+		t.Errorf("recompute = %+v, want 2 players checked and only the drifted one changed", result)
+	}
+	repaired := h.Player("alice")
+	if loot := repaired.Factory.Station(models.StationLoot); loot.Level != 3 || loot.Multiplier != 1.4 || loot.Cost != 225 {
+		t.Errorf("loot station recomputed as %+v, want level 3 at 1.4x costing 225", *loot)
+	}
+	if repaired.Progress.HeroLevel != 1 || repaired.PrestigeMultiplier != 1 {
+		t.Errorf("hero level %d and prestige multiplier %g, want 1 and 1", repaired.Progress.HeroLevel, repaired.PrestigeMultiplier)
+	}
+	if want := config.GoldPerTick(1.4); repaired.Progress.GoldPerTick != want {
+		t.Errorf("gold per tick recomputed as %d, want %d from the repaired loot station", repaired.Progress.GoldPerTick, want)
+	}
+	if repaired.Progress.MaxDungeonLevel != 25 {
+		t.Errorf("deepest dungeon level recomputed as %d, want the 25 a hero reached", repaired.Progress.MaxDungeonLevel)
+	}
+	if after := h.Player("bob"); !reflect.DeepEqual(after, bob) {
+		t.Error("recompute changed a player whose fields were already correct")
+	}
+
+	if again := h.Server.RecomputeDerived(); again.PlayersChanged != 0 {
+		t.Errorf("second recompute changed %d players, want none", again.PlayersChanged)
+	}
+}
+
+func TestRecomputeDerivedRepairsMalformedPlayers(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	carol, _ := h.Server.GetOrCreatePlayer("carol")
+	dave, _ := h.Server.GetOrCreatePlayer("dave")
+	h.Server.View(func() {
+		delete(carol.Factory.Stations, models.StationCrit)
+		dave.Factory = nil
+	})
+
+	if result := h.Server.RecomputeDerived(); result.PlayersChanged != 2 {
+		t.Errorf("recompute changed %d players, want both malformed ones", result.PlayersChanged)
+	}
+	for _, id := range []string{"carol", "dave"} {
+		if station := h.Player(id).Factory.Station(models.StationCrit); station == nil || *station != *models.NewStation() {
+			t.Errorf("%s's crit station repaired as %+v, want a new station", id, station)
+		}
+	}
+	if again := h.Server.RecomputeDerived(); again.PlayersChanged != 0 {
+		t.Errorf("second recompute changed %d players, want none", again.PlayersChanged)
+	}
+}
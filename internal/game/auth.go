@@ -0,0 +1,86 @@
+package game
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// ErrInvalidToken is returned when a request presents the wrong token for a player.
+var ErrInvalidToken = errors.New("invalid player token")
+
+// ErrNoToken is returned when a request names a player saved before tokens
+// existed, who has none yet. An admin issues one with IssueToken.
+var ErrNoToken = errors.New("player has no token")
+
+// AuthenticatePlayer checks that token grants access to the player with the
+// given ID, creating the player if it does not exist yet. A new player is
+// issued a token, which is returned so it can be handed to the caller; this
+// is the only time the token is available, since only its hash is stored.
+// Otherwise the returned token is empty, and ErrInvalidToken reports a
+// mismatch. A player saved before tokens existed is refused with ErrNoToken
+// rather than issued one, since anyone could be asking. A banned player is
+// refused with ErrBanned before anything else, and is never created.
+func (s *Server) AuthenticatePlayer(playerID, token string) (*models.Player, string, error) {
+	if s.IsBanned(playerID) {
+		return nil, "", ErrBanned
+	}
+	player, exists, err := s.findPlayer(playerID)
+	if err != nil {
+		return nil, "", fmt.Errorf("load player: %w", err)
+	}
+	if !exists {
+		player, _ = s.GetOrCreatePlayer(playerID)
+	}
+
+	candidate := rand.Text()
+	var issued string
+	s.gameState.Update(func() {
+		switch {
+		case player.TokenHash == "" && !exists:
+			player.TokenHash = hashToken(candidate)
+			issued = candidate
+		case player.TokenHash == "":
+			err = ErrNoToken
+		case subtle.ConstantTimeCompare([]byte(hashToken(token)), []byte(player.TokenHash)) != 1:
+			err = ErrInvalidToken
+		}
+	})
+	if issued != "" {
+		s.logger.Info("issued player token", "event", "token", "player_id", playerID)
+	}
+	return player, issued, err
+}
+
+// IssueToken gives an existing player a new token and returns it, for players
+// saved before tokens existed and players who lost theirs. Any earlier token
+// stops working. It returns ErrUnknownPlayer when there is no player with the ID.
+func (s *Server) IssueToken(playerID string) (string, error) {
+	_, exists, err := s.findPlayer(playerID)
+	if err != nil {
+		return "", fmt.Errorf("load player: %w", err)
+	}
+	if !exists {
+		return "", ErrUnknownPlayer
+	}
+
+	token := rand.Text()
+	if !s.gameState.UpdatePlayer(playerID, func(player *models.Player) {
+		player.TokenHash = hashToken(token)
+	}) {
+		return "", ErrUnknownPlayer
+	}
+	s.logger.Info("issued player token", "event", "admin_token", "player_id", playerID)
+	return token, nil
+}
+
+// hashToken returns the hex-encoded SHA-256 hash stored in place of a player token.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,111 @@
+package game_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game/testutil"
+)
+
+// clearToken makes the player look as if saved before tokens existed.
+func clearToken(t *testing.T, h *testutil.Harness, playerID string) {
+	t.Helper()
+	player, exists := h.Server.GetPlayer(playerID)
+	if !exists {
+		t.Fatalf("player %q not found", playerID)
+	}
+	h.Server.View(func() {
+		player.TokenHash = ""
+	})
+}
+
+func TestAuthenticatePlayerIssuesTokenOnlyOnCreation(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+
+	_, token, err := h.Server.AuthenticatePlayer("alice", "")
+	if err != nil || token == "" {
+		t.Fatalf("first request for a new player: token %q, %v; want a token", token, err)
+	}
+	if _, issued, err := h.Server.AuthenticatePlayer("alice", token); err != nil || issued != "" {
+		t.Errorf("request with the token: issued %q, %v; want accepted", issued, err)
+	}
+	if _, issued, err := h.Server.AuthenticatePlayer("alice", ""); !errors.Is(err, game.ErrInvalidToken) || issued != "" {
+		t.Errorf("request without the token: issued %q, %v; want ErrInvalidToken", issued, err)
+	}
+}
+
+func TestAuthenticatePlayerRefusesLegacyPlayer(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	h.Server.AuthenticatePlayer("alice", "")
+	clearToken(t, h, "alice")
+
+	for _, token := range []string{"", "guess"} {
+		if _, issued, err := h.Server.AuthenticatePlayer("alice", token); !errors.Is(err, game.ErrNoToken) || issued != "" {
+			t.Errorf("request for a player without a token: issued %q, %v; want ErrNoToken", issued, err)
+		}
+	}
+
+	token, err := h.Server.IssueToken("alice")
+	if err != nil {
+		t.Fatalf("issue token: %v", err)
+	}
+	if _, _, err := h.Server.AuthenticatePlayer("alice", token); err != nil {
+		t.Errorf("request with the issued token: %v", err)
+	}
+	if _, err := h.Server.IssueToken("nobody"); !errors.Is(err, game.ErrUnknownPlayer) {
+		t.Errorf("issue token for an unknown player: %v, want ErrUnknownPlayer", err)
+	}
+}
+
+func TestIssueTokenReplacesOldToken(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	_, old, _ := h.Server.AuthenticatePlayer("alice", "")
+
+	token, err := h.Server.IssueToken("alice")
+	if err != nil {
+		t.Fatalf("issue token: %v", err)
+	}
+	if _, _, err := h.Server.AuthenticatePlayer("alice", old); !errors.Is(err, game.ErrInvalidToken) {
+		t.Errorf("request with the replaced token: %v, want ErrInvalidToken", err)
+	}
+	if _, _, err := h.Server.AuthenticatePlayer("alice", token); err != nil {
+		t.Errorf("request with the new token: %v", err)
+	}
+}
+
+func TestTokenHashStaysOutOfPlayerJSON(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	_, token, _ := h.Server.AuthenticatePlayer("alice", "")
+	player, _ := h.Server.GetPlayer("alice")
+
+	var encoded []byte
+	h.Server.View(func() {
+		encoded, _ = json.Marshal(player)
+	})
+	if strings.Contains(string(encoded), "tokenHash") {
+		t.Errorf("player JSON holds the token hash: %s", encoded)
+	}
+	export, err := h.Server.ExportPlayer(player)
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if strings.Contains(string(export.Data), "tokenHash") {
+		t.Errorf("export holds the token hash: %s", export.Data)
+	}
+
+	// Backups keep it, so restoring one keeps the token working
+	backup := writeBackup(t, h.Server)
+	if _, err := h.Server.IssueToken("alice"); err != nil {
+		t.Fatalf("issue token: %v", err)
+	}
+	if _, err := h.Server.Restore(bytes.NewReader(backup)); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if _, _, err := h.Server.AuthenticatePlayer("alice", token); err != nil {
+		t.Errorf("request with the backed up token after a restore: %v", err)
+	}
+}
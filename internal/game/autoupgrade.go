@@ -0,0 +1,64 @@
+package game
+
+import "github.com/evevioletrose-hash/idle-dungeon/internal/models"
+
+// SetAutoUpgrade validates and applies a player's auto-upgrade mode, then
+// pushes an update so the player's clients show it right away. From then on,
+// every battle the player's heroes fight, online or offline, is followed by
+// buying every affordable upgrade the mode allows.
+func (s *Server) SetAutoUpgrade(player *models.Player, mode string) error {
+	autoUpgrade, err := models.ValidateAutoUpgrade(mode)
+	if err != nil {
+		return err
+	}
+	s.gameState.Update(func() {
+		player.AutoUpgrade = autoUpgrade
+	})
+
+	if autoUpgrade == "" {
+		autoUpgrade = models.AutoUpgradeOff
+	}
+	s.logger.Info("auto-upgrade changed", "event", "autoUpgrade", "player_id", player.ID, "mode", autoUpgrade)
+	s.requestUpdates()
+	return nil
+}
+
+// applyAutoUpgrade buys upgrades for a player with auto-upgrade on until the
+// next one is unaffordable or the station is at MaxStationLevel. Each purchase
+// goes through upgradeStation, exactly like a manual upgrade.
+// The caller holds the game-state write lock.
+func (s *Server) applyAutoUpgrade(player *models.Player) {
+	switch player.AutoUpgrade {
+	case "":
+		return
+	case models.AutoUpgradeCheapest:
+		for {
+			stationType, ok := s.cheapestUpgrade(player)
+			if !ok || s.upgradeStation(player, string(stationType)) != nil {
+				return
+			}
+		}
+	default:
+		for s.upgradeStation(player, player.AutoUpgrade) == nil {
+		}
+	}
+}
+
+// cheapestUpgrade returns the station whose next level costs least, the
+// first in display order on a tie, skipping stations at MaxStationLevel. It
+// reports false when every station is at the maximum.
+// The caller holds the game-state lock.
+func (s *Server) cheapestUpgrade(player *models.Player) (models.StationType, bool) {
+	var cheapest models.StationType
+	var cheapestCost int64
+	for _, stationType := range models.StationTypes {
+		station := player.Factory.Station(stationType)
+		if s.config.MaxStationLevel > 0 && station.Level >= s.config.MaxStationLevel {
+			continue
+		}
+		if cheapest == "" || station.Cost < cheapestCost {
+			cheapest, cheapestCost = stationType, station.Cost
+		}
+	}
+	return cheapest, cheapest != ""
+}
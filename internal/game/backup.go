@@ -0,0 +1,98 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// WriteBackup streams a versioned snapshot of the entire game state to w.
+// The game loop is paused while the snapshot is encoded so it is taken between ticks.
+func (s *Server) WriteBackup(w io.Writer) error {
+	s.loopMutex.Lock()
+	defer s.loopMutex.Unlock()
+
+	backup := models.Backup{
+		Version:   models.BackupVersion,
+		CreatedAt: s.clock.Now(),
+		Players:   s.gameState.GetAllPlayers(),
+
+		HallOfFame: s.HallOfFame(),
+		Bans:       s.Bans(),
+	}
+
+	var err error
+	s.gameState.View(func() {
+		err = json.NewEncoder(w).Encode(backup)
+	})
+	return err
+}
+
+// Restore replaces the entire game state, hall of fame and banlist included, with a backup read from r.
+// The document is fully decoded and validated before anything is touched, so a
+// partial or corrupt backup leaves the current state unchanged. The swap itself
+// happens with the game loop paused, and open connections are rebound to the
+// restored copy of their player.
+//
+// With a persister, the backup first replaces everything stored, so players
+// and seasons it lacks are not loaded back on the next start or when an
+// evicted player returns. A failed write leaves both the stored and the live
+// state unchanged.
+func (s *Server) Restore(r io.Reader) (int, error) {
+	var backup models.Backup
+	if err := json.NewDecoder(r).Decode(&backup); err != nil {
+		return 0, fmt.Errorf("decode backup: %w", err)
+	}
+	if err := backup.Validate(); err != nil {
+		return 0, fmt.Errorf("invalid backup: %w", err)
+	}
+
+	for _, player := range backup.Players {
+		player.TrackMaxDungeonLevel() // Backups made before the deepest level was tracked lack it
+		s.validatePlayer(player, "restore")
+	}
+
+	if backup.Bans == nil {
+		backup.Bans = make(map[string]models.Ban)
+	}
+
+	s.loopMutex.Lock()
+	defer s.loopMutex.Unlock()
+
+	if s.persister != nil {
+		restored := models.NewGameState()
+		restored.ReplacePlayers(backup.Players)
+		restored.HallOfFame = backup.HallOfFame
+		restored.Bans = backup.Bans
+		if err := s.replaceStored(restored); err != nil {
+			return 0, fmt.Errorf("save restored state: %w", err)
+		}
+	}
+
+	s.gameState.ReplacePlayers(backup.Players)
+	s.gameState.Update(func() {
+		s.gameState.HallOfFame = backup.HallOfFame
+		s.gameState.Bans = backup.Bans
+	})
+
+	// Point connected clients at the restored players, recreating any that the backup lacks
+	s.mutex.Lock()
+	for _, client := range s.clients {
+		restored, exists := backup.Players[client.player.ID]
+		if !exists {
+			restored = s.newPlayer(client.player.ID, s.clock.Now())
+			restored.TokenHash = client.player.TokenHash // So the recreated player's token still works
+			s.gameState.SetPlayer(restored)
+		}
+		client.player = restored
+	}
+	s.mutex.Unlock()
+	s.gameState.Update(s.rebuildGuilds)
+
+	s.logger.Info("restored game state from backup", "event", "restore",
+		"backup_created_at", backup.CreatedAt.Format(time.RFC3339), "players", len(backup.Players))
+	return len(backup.Players), nil
+}
@@ -0,0 +1,163 @@
+package game_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game/testutil"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/storage"
+)
+
+// writeBackup returns a backup of the server's whole state.
+func writeBackup(t *testing.T, server *game.Server) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := server.WriteBackup(&buf); err != nil {
+		t.Fatalf("write backup: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// worldOf decodes a backup with its creation time cleared, so backups of the
+// same state encode the same.
+func worldOf(t *testing.T, backup []byte) string {
+	t.Helper()
+	var decoded models.Backup
+	if err := json.Unmarshal(backup, &decoded); err != nil {
+		t.Fatalf("decode backup: %v", err)
+	}
+	decoded.CreatedAt = time.Time{}
+	encoded, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatalf("encode backup: %v", err)
+	}
+	return string(encoded)
+}
+
+func TestBackupRestoreRoundTrip(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	h.Connect("alice")
+	h.Connect("bob")
+	h.Advance(20)
+	if _, err := h.Server.ResetSeason(); err != nil {
+		t.Fatalf("reset season: %v", err)
+	}
+	h.Advance(5)
+	h.Server.KickPlayer("mallory", true, "cheating")
+
+	backup := writeBackup(t, h.Server)
+	want := worldOf(t, backup)
+
+	// Change everything the backup covers before restoring it
+	h.Advance(10)
+	h.Server.GetOrCreatePlayer("carol")
+	if _, err := h.Server.ResetSeason(); err != nil {
+		t.Fatalf("reset season: %v", err)
+	}
+	h.Server.UnbanPlayer("mallory")
+	h.Server.KickPlayer("bob", true, "")
+
+	restored, err := h.Server.Restore(bytes.NewReader(backup))
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if restored != 2 {
+		t.Errorf("restored %d players, want 2", restored)
+	}
+	if got := worldOf(t, writeBackup(t, h.Server)); got != want {
+		t.Errorf("state after restore differs from the backup\n got: %s\nwant: %s", got, want)
+	}
+	if _, exists := h.Server.GetPlayer("carol"); exists {
+		t.Error("player created after the backup survived the restore")
+	}
+	if !h.Server.IsBanned("mallory") || h.Server.IsBanned("bob") {
+		t.Error("banlist was not restored")
+	}
+}
+
+func TestRestoreRejectsCorruptBackup(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	h.Connect("alice")
+	h.Advance(3)
+	before := worldOf(t, writeBackup(t, h.Server))
+
+	for name, backup := range map[string]string{
+		"truncated":     `{"version":1,"players":{"alice":{"id":"alice"`,
+		"wrong version": `{"version":99,"players":{}}`,
+		"no players":    `{"version":1}`,
+		"no factory":    `{"version":1,"players":{"x":{"id":"x","progress":{}}}}`,
+		"misfiled":      `{"version":1,"players":{"x":{"id":"y","factory":{},"progress":{}}}}`,
+	} {
+		if _, err := h.Server.Restore(bytes.NewReader([]byte(backup))); err == nil {
+			t.Errorf("%s backup was restored", name)
+		}
+	}
+	if after := worldOf(t, writeBackup(t, h.Server)); after != before {
+		t.Error("a rejected backup changed the game state")
+	}
+}
+
+func TestRestoreReplacesStoredState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "game.db")
+	persister, err := storage.NewSQLitePersister(path)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	defer persister.Close()
+
+	config := game.DefaultConfig()
+	config.Clock = testutil.NewClock(testutil.StartTime)
+	config.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	config.ExportSecret = []byte("test")
+	server, err := game.NewServer(config, persister)
+	if err != nil {
+		t.Fatalf("create server: %v", err)
+	}
+
+	server.GetOrCreatePlayer("kept")
+	backup := writeBackup(t, server)
+
+	server.GetOrCreatePlayer("dropped")
+	if _, err := server.ResetSeason(); err != nil {
+		t.Fatalf("reset season: %v", err)
+	}
+	server.KickPlayer("banned", true, "")
+	if err := server.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if _, err := server.Restore(bytes.NewReader(backup)); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	// A save of the live state adds nothing the backup lacks
+	if err := server.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	stored, err := persister.Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if _, exists := stored.GetPlayer("kept"); !exists {
+		t.Error("player in the backup is not stored")
+	}
+	if _, exists := stored.GetPlayer("dropped"); exists {
+		t.Error("player missing from the backup is still stored")
+	}
+	if len(stored.HallOfFame) != 0 {
+		t.Errorf("stored hall of fame has %d seasons, want none", len(stored.HallOfFame))
+	}
+	if len(stored.Bans) != 0 {
+		t.Errorf("stored banlist has %d bans, want none", len(stored.Bans))
+	}
+	if _, exists, err := persister.LoadPlayer("dropped"); err != nil || exists {
+		t.Errorf("LoadPlayer(dropped) = %v, %v; want not stored", exists, err)
+	}
+}
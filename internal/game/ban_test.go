@@ -0,0 +1,26 @@
+package game_test
+
+import (
+	"testing"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game/testutil"
+)
+
+func TestKickPlayerClosesOnlyTheirConnections(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	h.Dial("mallory")
+	h.Dial("bob")
+
+	if result := h.Server.KickPlayer("mallory", true, "cheating"); result.ConnectionsClosed != 1 || !result.Banned {
+		t.Errorf("kick = %+v, want one connection closed and a ban", result)
+	}
+	waitForClients(t, h, 1)
+	if !h.Server.IsBanned("mallory") || h.Server.IsBanned("bob") {
+		t.Error("ban recorded for the wrong player")
+	}
+
+	// Other players stay connected, and more can connect
+	h.Dial("carol")
+	waitForClients(t, h, 2)
+}
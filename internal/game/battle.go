@@ -1,88 +1,483 @@
 package game
 
 import (
+	"runtime/debug"
+	"time"
+
 	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
 )
 
 // processPlayer handles the battle logic for a single player.
-// It creates a hero based on factory stats, simulates battle, and updates progress.
+// It creates a hero based on factory stats, simulates a battle on each of the
+// player's dungeon tracks, and updates progress, including the player's
+// daily quests; the player is notified of each quest the battles complete.
+// Every tick also pays the player's passive gold, whatever the battles' outcome.
+//
+// The hero is built and the battles are fought from a deep copy of the player
+// taken under the game-state read lock; the simulations themselves run without any lock, and
+// the outcomes are applied to the live player through a short UpdatePlayer
+// write lock. The results are therefore applied atomically per player, but
+// they are based on the factory as it was when the battles started: an upgrade
+// bought mid-simulation takes effect from the next tick.
+//
+// A player whose record lacks its factory, progress, or a station is repaired
+// with defaults first, and a panic while battling the player is logged and
+// skips only that player, so one malformed record never stops the tick for
+// everyone else.
 func (s *Server) processPlayer(player *models.Player) {
-	// Create hero based on current factory station multipliers
-	hero := s.createHero(player.Factory)
-	
-	// Simulate battle against dungeon enemy
-	battleResult := s.simulateBattle(hero, player.Progress.DungeonLevel)
-	
-	// Update player progress based on battle outcome
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			s.logger.Error("skipped player after a panic in their battles", "event", "battle", "player_id", player.ID,
+				"panic", recovered, "stack", string(debug.Stack()))
+		}
+	}()
+
+	// A player missing their factory, progress, or a station is repaired
+	// before anything dereferences it
+	var malformed bool
+	s.gameState.View(func() { malformed = player.Malformed() })
+	if malformed {
+		s.gameState.Update(func() { s.validatePlayer(player, "battle") })
+	}
+
+	// Create hero based on current factory station multipliers; every one of
+	// the player's heroes comes from the same factory
+	var snapshot *models.Player
+	var hero *models.Hero
+	s.gameState.View(func() {
+		snapshot = player.Clone()
+		hero = s.createHero(snapshot)
+	})
+	var dungeonLevels []int
+	for _, level := range snapshot.DungeonLevels() {
+		dungeonLevels = append(dungeonLevels, *level)
+	}
+
+	// Simulate each hero's battle against its dungeon enemy; all of them fight
+	// with the combo the player had when the tick started
+	battleResults := make([]models.BattleResult, len(dungeonLevels))
+	for i, dungeonLevel := range dungeonLevels {
+		battleResults[i] = s.simulateBattle(hero, dungeonLevel, snapshot.Difficulty, snapshot.Progress.Combo)
+	}
+
+	now := s.clock.Now()
+	var completedQuests []models.Quest
+	s.gameState.UpdatePlayer(player.ID, func(live *models.Player) {
+		s.applyPassiveIncome(live) // Paid first, so reservations completed after the battles can spend it
+		for i, battleResult := range battleResults {
+			s.applyBattleResult(live, i, battleResult)
+			recordBattleEvents(live, dungeonLevels[i], battleResult, now)
+			completedQuests = append(completedQuests, s.trackQuests(live, battleResult, now)...)
+		}
+		live.PruneBuffs(now)
+
+		// Connected players are seen every tick, so offline progress starts from here
+		live.LastSeen = now
+		live.Progress.PlaytimeSeconds += s.config.TickInterval.Seconds()
+	})
+	s.sendDeferredNotifications()
+
+	for i, battleResult := range battleResults {
+		if battleResult.Item != nil {
+			s.notify(player.ID, models.Notification{Type: "itemDrop", Data: battleResult.Item})
+		}
+		if battleResult.Buff != "" {
+			s.notify(player.ID, models.Notification{Type: "buffDrop", Data: map[string]interface{}{"buff": battleResult.Buff}})
+		}
+		if battleResult.IsBoss {
+			s.notify(player.ID, models.Notification{
+				Type: "bossBattle",
+				Data: map[string]interface{}{
+					"hero":         i,
+					"dungeonLevel": dungeonLevels[i],
+					"victory":      battleResult.Victory,
+					"gems":         battleResult.Gems,
+				},
+			})
+		}
+	}
+	for _, quest := range completedQuests {
+		s.notify(player.ID, models.Notification{Type: "questComplete", Data: quest})
+	}
+}
+
+// applyBattleResult updates player progress based on the outcome of a battle
+// fought by the hero at the given index of the player's DungeonLevels, and
+// completes any reserved upgrades the new gold covers, then buys whatever the
+// player's auto-upgrade mode allows. Every battle counts
+// toward the lifetime battle totals. Victory advances that
+// hero's dungeon level and the player's combo, which a defeat resets to zero;
+// gold, experience, items, and buff items are shared. The first
+// hero's result is kept as the player's LastBattle so the next update shows
+// what happened. A defeat never changes the dungeon level, so a hero who loses
+// to a boss simply fights it again next tick.
+// The caller holds the game-state write lock.
+func (s *Server) applyBattleResult(player *models.Player, heroIndex int, battleResult models.BattleResult) {
+	// Update player progress based on battle outcome; a defeat's reward is
+	// already scaled down by runBattle
+	player.Progress.Gold += battleResult.GoldReward
+	player.Progress.TotalBattles++
 	if battleResult.Victory {
-		player.Progress.DungeonLevel++
-		player.Progress.Gold += battleResult.GoldReward
+		player.Progress.BattlesWon++
+		player.Progress.Combo++
+		*player.DungeonLevels()[heroIndex]++
+		player.TrackMaxDungeonLevel()
 		player.Progress.Experience += battleResult.ExpReward
+		player.Progress.HeroLevel = models.HeroLevel(player.Progress.Experience)
+		if battleResult.Item != nil {
+			player.AddItem(*battleResult.Item)
+		}
+		if battleResult.Buff != "" {
+			player.AddBuffItem(battleResult.Buff)
+		}
+		player.Progress.Gems += battleResult.Gems
 	} else {
-		// Partial rewards even on defeat to maintain progression
-		player.Progress.Gold += battleResult.GoldReward / 2
+		player.Progress.Combo = 0
+	}
+
+	if heroIndex == 0 {
+		player.LastBattle = &battleResult
+	}
+	s.metrics.battles.WithLabelValues(battleResultLabel(battleResult.Victory)).Inc()
+
+	// Complete any reserved upgrades the new gold now covers, ahead of auto-upgrades
+	s.completeReservations(player)
+	s.applyAutoUpgrade(player)
+}
+
+// baseHeroStats holds the hero statistic each station type scales, before multipliers.
+// The crit station scales baseCritChance instead.
+var baseHeroStats = map[models.StationType]float64{
+	models.StationHP:     100,
+	models.StationArmor:  10,
+	models.StationAttack: 20,
+	models.StationLoot:   1,
+}
+
+// A hero's crit chance is baseCritChance times the crit station multiplier,
+// capped at maxCritChance so some attacks always land normally.
+const (
+	baseCritChance = 0.1
+	maxCritChance  = 0.5
+)
+
+// A hero's dodge chance is baseDodgeChance times the armor station multiplier,
+// and its double-strike chance is baseDoubleStrikeChance times the crit station
+// multiplier, each capped so no build dodges or double-strikes every time.
+const (
+	baseDodgeChance        = 0.05
+	maxDodgeChance         = 0.3
+	baseDoubleStrikeChance = 0.05
+	maxDoubleStrikeChance  = 0.25
+)
+
+// heroLevelBonuses holds the flat bonus each hero level beyond the first adds
+// to a stat, on top of the multiplied value.
+var heroLevelBonuses = map[models.StationType]int{
+	models.StationHP:     5,
+	models.StationAttack: 1,
+}
+
+// createHero generates a hero with stats based on a player's factory station multipliers.
+// Base stats are modified by each station's effective multiplier, softened past
+// the soft cap, then by the player's permanent prestige and gem multipliers
+// and their guild's bonus. The hero level earned from
+// experience adds a flat bonus to HP and attack. Crit and double-strike chance
+// come from the crit station alone and dodge chance from the armor station; as
+// probabilities they are not scaled by prestige, gems, or the guild.
+// Active buffs multiply their stats on top of that. Equipped items add their
+// flat bonuses last. A missing factory, station, or progress counts as an
+// unupgraded one, so a malformed player still gets a hero rather than a panic.
+// The caller holds the game-state lock.
+func (s *Server) createHero(player *models.Player) *models.Hero {
+	return s.createHeroAt(player, s.clock.Now())
+}
+
+// createHeroAt performs createHero with the buffs active at the given time,
+// for battles fast-forwarded from the past.
+// The caller holds the game-state lock.
+func (s *Server) createHeroAt(player *models.Player, now time.Time) *models.Hero {
+	return &s.heroBreakdownAt(player, now).Hero
+}
+
+// heroBreakdownAt builds the hero createHeroAt returns together with how each
+// of its stats was derived, one entry per station type in display order.
+// The caller holds the game-state lock.
+func (s *Server) heroBreakdownAt(player *models.Player, now time.Time) *models.HeroBreakdown {
+	prestige := player.PrestigeMultiplier
+	if prestige <= 0 {
+		prestige = 1.0 // Players saved before prestige existed
+	}
+	var experience int
+	if player.Progress != nil {
+		experience = player.Progress.Experience
+	}
+	levelsGained := models.HeroLevel(experience) - 1
+	breakdown := &models.HeroBreakdown{
+		HeroLevel:          levelsGained + 1,
+		PrestigeMultiplier: prestige,
+		GuildMultiplier:    s.guildMultiplier(player),
+		GemMultiplier:      player.GemMultiplier(),
+		Stats:              make([]models.StatBreakdown, 0, len(models.StationTypes)),
+	}
+
+	for _, stationType := range models.StationTypes {
+		station := player.Factory.Station(stationType)
+		if station == nil {
+			station = models.NewStation() // A missing station or factory counts as an unupgraded one
+		}
+		stat := models.StatBreakdown{
+			Stat:                stationType,
+			StationLevel:        station.Level,
+			StationMultiplier:   station.Multiplier,
+			EffectiveMultiplier: s.config.EffectiveMultiplier(station.Multiplier),
+			BuffMultiplier:      1,
+			ResearchMultiplier:  s.researchMultiplier(player, stationType),
+		}
+
+		if stationType == models.StationCrit {
+			stat.Base = baseCritChance
+			stat.Value = min(maxCritChance, baseCritChance*stat.EffectiveMultiplier*stat.ResearchMultiplier)
+			breakdown.Hero.CritChance = stat.Value
+			breakdown.Hero.DoubleStrikeChance = min(maxDoubleStrikeChance, baseDoubleStrikeChance*stat.EffectiveMultiplier)
+			breakdown.Stats = append(breakdown.Stats, stat)
+			continue
+		}
+
+		stat.Base = baseHeroStats[stationType]
+		stat.BuffMultiplier = player.BuffMultiplier(stationType, now)
+		stat.LevelBonus = heroLevelBonuses[stationType] * levelsGained
+		for _, item := range player.Inventory {
+			if item.Equipped && item.Stat == stationType {
+				stat.ItemBonus += item.Bonus
+			}
+		}
+		value := int(stat.Base*stat.EffectiveMultiplier*prestige*breakdown.GuildMultiplier*breakdown.GemMultiplier*stat.BuffMultiplier*stat.ResearchMultiplier) +
+			stat.LevelBonus + stat.ItemBonus
+		stat.Value = float64(value)
+		breakdown.Stats = append(breakdown.Stats, stat)
+
+		switch stationType {
+		case models.StationHP:
+			breakdown.Hero.HP = value
+		case models.StationArmor:
+			breakdown.Hero.Armor = value
+			breakdown.Hero.DodgeChance = min(maxDodgeChance, baseDodgeChance*stat.EffectiveMultiplier)
+		case models.StationAttack:
+			breakdown.Hero.Attack = value
+		case models.StationLoot:
+			breakdown.Hero.Loot = value
+		}
 	}
+	return breakdown
 }
 
-// createHero generates a hero with stats based on factory station multipliers.
-// Base stats are modified by each station's current multiplier value.
-func (s *Server) createHero(factory *models.Factory) *models.Hero {
-	// Base hero statistics
-	const (
-		baseHP     = 100
-		baseArmor  = 10
-		baseAttack = 20
-		baseLoot   = 1
-	)
-
-	return &models.Hero{
-		HP:     int(float64(baseHP) * factory.HPStation.Multiplier),
-		Armor:  int(float64(baseArmor) * factory.ArmorStation.Multiplier),
-		Attack: int(float64(baseAttack) * factory.AttackStation.Multiplier),
-		Loot:   int(float64(baseLoot) * factory.LootStation.Multiplier),
+// Hero damage rolls between minDamageRoll and maxDamageRoll times the attack stat,
+// and a critical hit, landing with the hero's crit chance, doubles the damage of a single attack.
+const (
+	minDamageRoll = 0.8
+	maxDamageRoll = 1.2
+)
+
+// Boss enemies scale the regular enemy stats and rewards by these factors.
+const (
+	bossHPMultiplier     = 3.0
+	bossAttackMultiplier = 1.5
+	bossGoldMultiplier   = 5
+)
+
+// armorPen returns the fraction of hero armor ignored by the enemy on the given dungeon level.
+func (s *Server) armorPen(dungeonLevel int) float64 {
+	levelsBeyond := dungeonLevel - s.config.ArmorPenStartLevel
+	if levelsBeyond <= 0 {
+		return 0
 	}
+	return min(s.config.ArmorPenMax, s.config.ArmorPenPerLevel*float64(levelsBeyond))
+}
+
+// isBossLevel reports whether the enemy on the given dungeon level is a boss.
+func (s *Server) isBossLevel(dungeonLevel int) bool {
+	return s.config.BossInterval > 0 && dungeonLevel > 0 && dungeonLevel%s.config.BossInterval == 0
 }
 
 // simulateBattle performs turn-based combat between a hero and dungeon enemy.
 // Enemy difficulty scales with dungeon level, and rewards are based on enemy strength.
-func (s *Server) simulateBattle(hero *models.Hero, dungeonLevel int) models.BattleResult {
-	// Enemy stats scale with dungeon level
-	enemyHP := 50 + (dungeonLevel * 10)
-	enemyAttack := 15 + (dungeonLevel * 5)
-	
+// Each hero attack rolls its damage and a chance to crit from the server's random source.
+// The hero may strike twice in a turn, and either side may dodge an attack,
+// which then deals no damage.
+// Beyond ArmorPenStartLevel enemies ignore a growing share of the hero's armor.
+// The enemy's archetype then reshapes its HP, attack, and rewards; see enemyProfile.
+// On boss levels the enemy is tougher. Harder difficulty tiers scale up the
+// enemy, and the server's RewardCalculator decides the gold and experience.
+// A victory may also drop an item, more likely with more loot and on deeper
+// levels, and a victory over a boss always drops a buff item and awards gems.
+// The player's combo of victories in a row raises the hero's attack for the
+// battle; see comboMultiplier. No turn-by-turn log is built on this hot path; SimulateBattleVerbose fights
+// the same battle with one.
+func (s *Server) simulateBattle(hero *models.Hero, dungeonLevel int, tier models.DifficultyTier, combo int) models.BattleResult {
+	return s.runBattle(comboHero(hero, combo), dungeonLevel, tier, s.rng.Int64(), nil)
+}
+
+// SimulateBattleVerbose fights a battle exactly as the game loop would, from
+// a fresh seed and with the attack bonus of the given combo, and also returns every attack made, so a player can inspect
+// how a fight plays out at their current stats. The result is not applied to
+// any player; its seed replays the same battle through ReplayBattle.
+func (s *Server) SimulateBattleVerbose(hero *models.Hero, dungeonLevel int, tier models.DifficultyTier, combo int) (models.BattleResult, []models.BattleTurn) {
+	return s.ReplayBattle(comboHero(hero, combo), dungeonLevel, tier, s.rng.Int64())
+}
+
+// ReplayBattle reruns a battle from the seed recorded in its BattleResult and
+// returns the result along with every attack made. Given the same hero,
+// dungeon level, and difficulty tier it reproduces the original battle exactly.
+func (s *Server) ReplayBattle(hero *models.Hero, dungeonLevel int, tier models.DifficultyTier, seed int64) (models.BattleResult, []models.BattleTurn) {
+	var turns []models.BattleTurn
+	result := s.runBattle(hero, dungeonLevel, tier, seed, &turns)
+	return result, turns
+}
+
+// runBattle simulates a battle rolling from the given seed. When turns is not
+// nil, each attack is appended to it as it happens.
+func (s *Server) runBattle(hero *models.Hero, dungeonLevel int, tier models.DifficultyTier, seed int64, turns *[]models.BattleTurn) models.BattleResult {
+	rng := newBattleRand(seed)
+	record := func(turn models.BattleTurn) {
+		if turns != nil {
+			turn.Turn = len(*turns) + 1
+			*turns = append(*turns, turn)
+		}
+	}
+
+	// Enemy stats scale with dungeon level and difficulty tier
+	scale := difficulty(tier)
+	baseHP, baseAttack := s.config.EnemyStats(dungeonLevel)
+	enemyHP := int(float64(baseHP) * scale.enemy)
+	enemyAttack := int(float64(baseAttack) * scale.enemy)
+
+	isBoss := s.isBossLevel(dungeonLevel)
+	if isBoss {
+		enemyHP = int(float64(enemyHP) * bossHPMultiplier)
+		enemyAttack = int(float64(enemyAttack) * bossAttackMultiplier)
+	}
+
+	// The archetype reshapes the enemy; the hero's damage is still reduced by
+	// the level's attack, so only HP decides how long the enemy lasts
+	enemyType := s.enemyType(dungeonLevel, rng)
+	profile := enemyProfiles[enemyType]
+	enemyHP = max(1, int(float64(enemyHP)*profile.hp))
+	enemyMaxHP := enemyHP
+
 	// Combat variables
 	heroHP := hero.HP
-	heroDamage := max(1, hero.Attack-enemyAttack/2) // Hero damage reduced by enemy attack/2
-	enemyDamage := max(1, enemyAttack-hero.Armor)   // Enemy damage reduced by hero armor
-	
+	armorPen := min(1, s.armorPen(dungeonLevel)+profile.armorPen)
+	effectiveArmor := int(float64(hero.Armor) * (1 - armorPen)) // Deep enemies and some archetypes pierce part of the armor
+	hitAttack := int(float64(enemyAttack) * profile.attack / float64(profile.hits))
+	enemyDamage := max(1, hitAttack-effectiveArmor) // Each enemy hit is reduced by hero armor
+
+	// Dodge is capped here too, so a hero with overridden stats cannot dodge forever
+	heroDodge := min(maxDodgeChance, hero.DodgeChance)
+
 	// Turn-based battle simulation
 	for heroHP > 0 && enemyHP > 0 {
-		// Hero attacks first
-		enemyHP -= heroDamage
+		// Hero attacks first, sometimes twice, rolling damage around its attack stat
+		strikes := 1
+		if rng.Float64() < hero.DoubleStrikeChance {
+			strikes = 2
+		}
+		for strike := 0; strike < strikes && enemyHP > 0; strike++ {
+			attack := models.BattleTurn{Attacker: "hero", DoubleStrike: strike > 0}
+			if rng.Float64() < profile.dodge {
+				attack.Dodged = true
+			} else {
+				roll := minDamageRoll + (maxDamageRoll-minDamageRoll)*rng.Float64()
+				attack.Damage = max(1, int(float64(hero.Attack)*roll)-enemyAttack/2) // Hero damage reduced by enemy attack/2
+				attack.Crit = rng.Float64() < hero.CritChance
+				if attack.Crit {
+					attack.Damage *= 2
+				}
+				enemyHP -= attack.Damage
+			}
+			attack.HeroHP, attack.EnemyHP = heroHP, enemyHP
+			record(attack)
+		}
 		if enemyHP <= 0 {
 			break // Hero wins
 		}
-		
-		// Enemy counter-attacks
-		heroHP -= enemyDamage
+
+		// Enemy counter-attacks, once per hit
+		for hit := 0; hit < profile.hits && heroHP > 0; hit++ {
+			attack := models.BattleTurn{Attacker: "enemy"}
+			if rng.Float64() < heroDodge {
+				attack.Dodged = true
+			} else {
+				attack.Damage = enemyDamage
+				heroHP -= enemyDamage
+			}
+			attack.HeroHP, attack.EnemyHP = heroHP, enemyHP
+			record(attack)
+		}
 	}
-	
+
 	// Determine battle outcome and calculate rewards
 	victory := heroHP > 0
-	goldReward := (10 + dungeonLevel*2) * hero.Loot // Gold scales with level and loot multiplier
-	expReward := 5 + dungeonLevel                   // Experience scales with dungeon level
-	
+	outcome := BattleOutcome{
+		Hero:         hero,
+		DungeonLevel: dungeonLevel,
+		Difficulty:   tier,
+		EnemyType:    enemyType,
+		IsBoss:       isBoss,
+		Victory:      victory,
+		DamageShare:  1,
+	}
+	if !victory {
+		outcome.DamageShare = float64(enemyMaxHP-enemyHP) / float64(enemyMaxHP)
+	}
+	goldReward, expReward := s.rewards.Rewards(outcome)
+	if !victory {
+		goldReward = min(goldReward, s.defeatGoldCap(hero, dungeonLevel, tier))
+	}
+
+	var item *models.Item
+	var buff models.BuffType
+	var gems int
+	if victory {
+		item = s.dropItem(rng, hero.Loot, dungeonLevel)
+		if isBoss {
+			buff = rollBuffDrop(rng)
+			gems = s.bossGems(dungeonLevel)
+		}
+	}
+
 	return models.BattleResult{
-		Victory:     victory,
-		GoldReward:  goldReward,
-		ExpReward:   expReward,
+		Victory:    victory,
+		GoldReward: goldReward,
+		ExpReward:  expReward,
+		IsBoss:     isBoss,
+		EnemyType:  enemyType,
+		Item:       item,
+		Buff:       buff,
+		Gems:       gems,
+		Seed:       seed,
 	}
 }
 
-// max returns the larger of two integers.
-func max(a, b int) int {
-	if a > b {
-		return a
+// defeatGoldCap returns the most gold a defeat on the given dungeon level
+// pays, whatever the reward rules: what beating the previous level's regular
+// enemy pays, even when that level has a boss, so losing never out-earns
+// progressing. Level 1 has no previous level, so a defeat there pays at most
+// the defeat share of beating level 1's regular enemy.
+func (s *Server) defeatGoldCap(hero *models.Hero, dungeonLevel int, tier models.DifficultyTier) int {
+	cleared := BattleOutcome{
+		Hero:         hero,
+		DungeonLevel: dungeonLevel - 1,
+		Difficulty:   tier,
+		EnemyType:    models.EnemyStandard,
+		Victory:      true,
+		DamageShare:  1,
 	}
-	return b
-}
\ No newline at end of file
+	if dungeonLevel <= 1 {
+		cleared.DungeonLevel = 1
+		gold, _ := s.rewards.Rewards(cleared)
+		return int(float64(gold) * defeatGoldShare)
+	}
+	gold, _ := s.rewards.Rewards(cleared)
+	return gold
+}
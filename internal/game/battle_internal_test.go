@@ -0,0 +1,44 @@
+package game
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"testing"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// benchmarkPlayers is how many players each tick of the battle benchmarks processes.
+const benchmarkPlayers = 10_000
+
+// benchmarkProcessPlayers measures the battles of one tick for benchmarkPlayers
+// players spread across the given number of workers.
+func benchmarkProcessPlayers(b *testing.B, workers int) {
+	config := DefaultConfig()
+	config.BattleWorkers = workers
+	config.RandomSeed = 1
+	config.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	config.ExportSecret = []byte("benchmark")
+	server, err := NewServer(config, nil)
+	if err != nil {
+		b.Fatalf("create server: %v", err)
+	}
+	players := make([]*models.Player, benchmarkPlayers)
+	for i := range players {
+		players[i], _ = server.GetOrCreatePlayer(fmt.Sprintf("player-%d", i))
+	}
+
+	for b.Loop() {
+		server.processPlayers(players)
+	}
+}
+
+func BenchmarkProcessPlayersSequential(b *testing.B) {
+	benchmarkProcessPlayers(b, 1)
+}
+
+func BenchmarkProcessPlayersPooled(b *testing.B) {
+	benchmarkProcessPlayers(b, runtime.GOMAXPROCS(0))
+}
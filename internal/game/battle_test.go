@@ -0,0 +1,242 @@
+package game_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game/testutil"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+func TestHeroDamageRollsAroundAttack(t *testing.T) {
+	config := game.DefaultConfig()
+	h := testutil.New(t, config)
+	hero := &models.Hero{HP: 1_000_000, Attack: 1000, Loot: 1, CritChance: 0.5}
+	_, enemyAttack := config.EnemyStats(1)
+	low, high := 800-enemyAttack/2, 1200-enemyAttack/2
+
+	var lowest, highest, crits, hits int
+	lowest = high
+	for seed := int64(1); seed <= 500; seed++ {
+		_, turns := h.Server.ReplayBattle(hero, 1, models.DifficultyNormal, seed)
+		for _, turn := range turns {
+			if turn.Attacker != "hero" || turn.Dodged {
+				continue
+			}
+			hits++
+			damage := turn.Damage
+			if turn.Crit {
+				crits++
+				if damage%2 != 0 {
+					t.Fatalf("seed %d: crit dealt odd damage %d, want double a roll", seed, damage)
+				}
+				damage /= 2
+			}
+			if damage < low || damage > high {
+				t.Fatalf("seed %d: hit rolled %d damage, want %d to %d", seed, damage, low, high)
+			}
+			lowest, highest = min(lowest, damage), max(highest, damage)
+		}
+	}
+
+	if lowest > 900-enemyAttack/2 || highest < 1100-enemyAttack/2 {
+		t.Errorf("damage rolls spanned only %d to %d, want most of %d to %d", lowest, highest, low, high)
+	}
+	if crits < hits/3 || crits > hits*2/3 {
+		t.Errorf("%d of %d hits crit at a 50%% crit chance", crits, hits)
+	}
+}
+
+func TestNoCritChanceNeverCrits(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	hero := &models.Hero{HP: 1_000_000, Attack: 100, Loot: 1}
+	for seed := int64(1); seed <= 100; seed++ {
+		_, turns := h.Server.ReplayBattle(hero, 5, models.DifficultyNormal, seed)
+		for _, turn := range turns {
+			if turn.Crit {
+				t.Fatalf("seed %d: a hero without crit chance crit", seed)
+			}
+		}
+	}
+}
+
+func TestBattleReplaysFromSeed(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	hero := &models.Hero{HP: 300, Armor: 5, Attack: 40, Loot: 1, CritChance: 0.2, DodgeChance: 0.1}
+
+	result, turns := h.Server.SimulateBattleVerbose(hero, 3, models.DifficultyNormal, 0)
+	replayed, replayedTurns := h.Server.ReplayBattle(hero, 3, models.DifficultyNormal, result.Seed)
+	if !reflect.DeepEqual(replayed, result) || !reflect.DeepEqual(replayedTurns, turns) {
+		t.Errorf("replay of seed %d differs from the original battle", result.Seed)
+	}
+}
+
+func TestSeededServersRollTheSameBattles(t *testing.T) {
+	hero := &models.Hero{HP: 300, Armor: 5, Attack: 40, Loot: 1, CritChance: 0.2}
+	seeds := func() []int64 {
+		h := testutil.New(t, game.DefaultConfig())
+		var seeds []int64
+		for i := 0; i < 10; i++ {
+			result, _ := h.Server.SimulateBattleVerbose(hero, 3, models.DifficultyNormal, 0)
+			seeds = append(seeds, result.Seed)
+		}
+		return seeds
+	}
+	if first, second := seeds(), seeds(); !reflect.DeepEqual(first, second) {
+		t.Errorf("servers with the same seed rolled battles %v and %v", first, second)
+	}
+}
+
+func TestConcurrentBattles(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	hero := &models.Hero{HP: 300, Armor: 5, Attack: 40, Loot: 1, CritChance: 0.2}
+
+	var wg sync.WaitGroup
+	seeds := make(chan int64, 8*50)
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				result, _ := h.Server.SimulateBattleVerbose(hero, 3, models.DifficultyNormal, 0)
+				seeds <- result.Seed
+			}
+		}()
+	}
+	wg.Wait()
+	close(seeds)
+
+	seen := make(map[int64]bool)
+	for seed := range seeds {
+		seen[seed] = true
+	}
+	if len(seen) < 8*50*9/10 {
+		t.Errorf("concurrent battles drew only %d distinct seeds out of %d", len(seen), 8*50)
+	}
+}
+
+// lastBattleOf returns the lastBattle field of alice's next update.
+func lastBattleOf(t *testing.T, h *testutil.Harness) map[string]interface{} {
+	t.Helper()
+	player, _ := h.Server.GetPlayer("alice")
+	var update struct {
+		Player struct {
+			LastBattle map[string]interface{} `json:"lastBattle"`
+		} `json:"player"`
+	}
+	if err := json.Unmarshal(h.Server.MarshalUpdate(player), &update); err != nil {
+		t.Fatalf("decode update: %v", err)
+	}
+	if update.Player.LastBattle == nil {
+		t.Fatal("update without a lastBattle")
+	}
+	return update.Player.LastBattle
+}
+
+func TestUpdatesCarryLastBattle(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	h.Connect("alice")
+
+	h.Advance(1)
+	first := lastBattleOf(t, h)
+	for _, field := range []string{"victory", "goldReward", "isBoss", "seed"} {
+		if _, found := first[field]; !found {
+			t.Errorf("lastBattle %v lacks %s", first, field)
+		}
+	}
+
+	// Each tick replaces it with that tick's battle
+	h.Advance(1)
+	second := lastBattleOf(t, h)
+	if second["seed"] == first["seed"] {
+		t.Errorf("lastBattle after the second tick is still the first battle: %v", second)
+	}
+	if seed := h.Player("alice").LastBattle.Seed; float64(seed) != second["seed"] {
+		t.Errorf("player's lastBattle has seed %d, the update %v", seed, second["seed"])
+	}
+}
+
+func TestLastBattleLeftOutOfBackups(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	h.Connect("alice")
+	h.Advance(1)
+	if h.Player("alice").LastBattle == nil {
+		t.Fatal("no lastBattle after a tick")
+	}
+
+	if backup := writeBackup(t, h.Server); strings.Contains(string(backup), "lastBattle") {
+		t.Errorf("backup holds the transient lastBattle: %s", backup)
+	}
+	if h.Player("alice").LastBattle == nil {
+		t.Error("writing a backup cleared the live player's lastBattle")
+	}
+}
+
+func TestArmorPenetration(t *testing.T) {
+	custom := game.DefaultConfig()
+	custom.ArmorPenStartLevel = 0
+	custom.ArmorPenPerLevel = 0.1
+	custom.ArmorPenMax = 0.5
+	none := game.DefaultConfig()
+	none.ArmorPenPerLevel = 0
+
+	for _, test := range []struct {
+		name   string
+		config game.Config
+		level  int
+		pen    float64 // Share of armor the level's standard enemy should ignore
+	}{
+		{"shallow", game.DefaultConfig(), 5, 0},
+		{"at the start level", game.DefaultConfig(), 21, 0.01},
+		{"deep", game.DefaultConfig(), 61, 0.41},
+		{"at the cap", game.DefaultConfig(), 101, 0.75},
+		{"configured", custom, 1, 0.1},
+		{"configured cap", custom, 9, 0.5},
+		{"turned off", none, 101, 0},
+	} {
+		h := testutil.New(t, test.config)
+		_, enemyAttack := test.config.EnemyStats(test.level)
+		// Armor that exactly cancels the enemy's attack, and a hero felled by its first hit
+		hero := &models.Hero{HP: 1, Armor: enemyAttack, Attack: 1, Loot: 1}
+		_, turns := h.Server.ReplayBattle(hero, test.level, models.DifficultyNormal, 1)
+
+		want := max(1, enemyAttack-int(float64(enemyAttack)*(1-test.pen)))
+		var hit *models.BattleTurn
+		for i := range turns {
+			if turns[i].Attacker == "enemy" && !turns[i].Dodged {
+				hit = &turns[i]
+				break
+			}
+		}
+		if hit == nil {
+			t.Fatalf("%s: enemy never hit the hero", test.name)
+		}
+		if hit.Damage != want {
+			t.Errorf("%s: level %d enemy hit for %d through %d armor, want %d", test.name, test.level, hit.Damage, hero.Armor, want)
+		}
+	}
+}
+
+func TestPooledTicksBattleEveryPlayerOnce(t *testing.T) {
+	config := game.DefaultConfig()
+	config.BattleWorkers = 8
+	h := testutil.New(t, config)
+	var ids []string
+	for i := 0; i < 40; i++ {
+		ids = append(ids, fmt.Sprintf("player-%d", i))
+		h.Connect(ids[i])
+	}
+
+	h.Advance(10)
+	for _, id := range ids {
+		player := h.Player(id)
+		if player.Progress.TotalBattles != 10 || player.Progress.PlaytimeSeconds != 10*config.TickInterval.Seconds() {
+			t.Errorf("%s fought %d battles over %gs in 10 ticks, want 10", id, player.Progress.TotalBattles, player.Progress.PlaytimeSeconds)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package game
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// queuedUpdates drains each client's send queue, returning how many messages
+// every player's client had waiting.
+func queuedUpdates(server *Server) map[string]int {
+	counts := make(map[string]int)
+	for _, client := range server.clients {
+		counts[client.player.ID] += len(client.send)
+		for len(client.send) > 0 {
+			<-client.send
+		}
+	}
+	return counts
+}
+
+func TestCappedBroadcastsSpreadUpdates(t *testing.T) {
+	server := newTestServer(t)
+	server.config.MaxUpdatesPerBroadcast = 3
+	for i := 0; i < 10; i++ {
+		player, _ := server.GetOrCreatePlayer(fmt.Sprintf("player-%d", i))
+		// Clients without a writeLoop, so every queued update stays in send
+		server.clients[&websocket.Conn{}] = &client{player: player, send: make(chan []byte, sendQueueSize)}
+	}
+
+	// Every client starts out changed; each broadcast reaches three new ones until all have theirs
+	total := make(map[string]int)
+	for broadcast, want := range []int{3, 3, 3, 1, 0} {
+		server.sendUpdates()
+		sent := 0
+		for id, count := range queuedUpdates(server) {
+			total[id] += count
+			sent += count
+		}
+		if sent != want {
+			t.Errorf("broadcast %d sent %d updates, want %d", broadcast+1, sent, want)
+		}
+	}
+	for id, count := range total {
+		if count != 1 {
+			t.Errorf("%s was sent %d updates, want exactly 1", id, count)
+		}
+	}
+
+	// Only changed players are sent anything, and they go ahead of the cap
+	for _, id := range []string{"player-4", "player-7"} {
+		player, _ := server.GetPlayer(id)
+		server.gameState.Update(func() { player.Progress.Gold += 10 })
+	}
+	server.sendUpdates()
+	counts := queuedUpdates(server)
+	if counts["player-4"] != 1 || counts["player-7"] != 1 {
+		t.Errorf("updates after two players changed = %v, want one each for player-4 and player-7", counts)
+	}
+	for id, count := range counts {
+		if count != 0 && id != "player-4" && id != "player-7" {
+			t.Errorf("unchanged %s was sent %d updates", id, count)
+		}
+	}
+
+	// When everyone changes every tick, the cap takes them in turns: with 10
+	// clients and 3 updates a broadcast, nobody waits more than 4 broadcasts
+	waited := make(map[string]int)
+	for broadcast := 0; broadcast < 20; broadcast++ {
+		server.gameState.Update(func() {
+			for _, player := range server.gameState.Players {
+				player.Progress.Gold++
+			}
+		})
+		server.sendUpdates()
+		for id, count := range queuedUpdates(server) {
+			if count > 0 {
+				waited[id] = 0
+			} else if waited[id]++; waited[id] >= 4 {
+				t.Fatalf("broadcast %d: %s has gone %d broadcasts without an update", broadcast+1, id, waited[id])
+			}
+		}
+	}
+}
+
+func TestUncappedBroadcastsUpdateEveryone(t *testing.T) {
+	server := newTestServer(t)
+	for i := 0; i < 10; i++ {
+		player, _ := server.GetOrCreatePlayer(fmt.Sprintf("player-%d", i))
+		server.clients[&websocket.Conn{}] = &client{player: player, send: make(chan []byte, sendQueueSize)}
+	}
+	server.sendUpdates()
+	for id, count := range queuedUpdates(server) {
+		if count != 1 {
+			t.Errorf("uncapped broadcast sent %s %d updates, want 1", id, count)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package game
+
+import (
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// ActivateBuff uses up one of the player's buff items of the given type,
+// multiplying a hero stat until the buff expires, and pushes an update so the
+// player's clients show it right away. Activating a buff that is already
+// active extends it. It returns models.ErrUnknownBuff or models.ErrNoBuffItem
+// when the buff cannot be activated.
+func (s *Server) ActivateBuff(player *models.Player, buffType string) (models.Buff, error) {
+	var buff models.Buff
+	var err error
+	s.gameState.Update(func() {
+		buff, err = player.ActivateBuff(models.BuffType(buffType), s.clock.Now())
+	})
+
+	if err != nil {
+		s.logger.Debug("buff activation rejected", "event", "buff", "player_id", player.ID, "buff", buffType, "error", err)
+		return models.Buff{}, err
+	}
+	s.logger.Info("buff activated", "event", "buff", "player_id", player.ID,
+		"buff", buffType, "expires_at", buff.ExpiresAt.Format(time.RFC3339))
+	s.requestUpdates()
+	return buff, nil
+}
@@ -0,0 +1,98 @@
+package game
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+	"github.com/gorilla/websocket"
+)
+
+// Outgoing messages wait in a per-client queue of sendQueueSize messages, so a
+// slow client never holds up sends to anyone else. Each write may take up to
+// writeWait, which absorbs short stalls in the client's network. A message
+// that finds the queue full is dropped, and a client whose queue is still full
+// after maxQueueOverflows sends in a row has stopped keeping up and is
+// disconnected; it resyncs with a fresh gameState when it reconnects.
+const (
+	sendQueueSize     = 64
+	writeWait         = 10 * time.Second
+	maxQueueOverflows = 8
+)
+
+// client is a registered WebSocket connection and the player it belongs to.
+// gorilla/websocket allows only one concurrent writer per connection, so every
+// data frame sent to the connection is queued with enqueue and written by the
+// client's writeLoop. That single writer also keeps permessage-deflate safe,
+// since each message is compressed while it is written. Control frames (pings, close) may still use the
+// connection's WriteControl directly, since the library allows that
+// concurrently with other writes.
+type client struct {
+	conn       *websocket.Conn // Underlying WebSocket connection
+	encoding   Encoding        // Wire format of the messages written to the connection
+	player     *models.Player  // Player the connection belongs to; guarded by the server's clients mutex
+	send       chan []byte     // Messages waiting to be written by writeLoop
+	overflows  atomic.Int32    // Consecutive messages dropped because send was full
+	lastUpdate []byte          // Last update queued by the game loop; touched only by sendUpdates
+	updatedAt  uint64          // Server.sends when lastUpdate was queued; touched only by sendUpdates
+
+	ctx    context.Context    // Done when the connection's context ends or the client is removed, stopping writeLoop
+	cancel context.CancelFunc // Cancels ctx
+}
+
+// newClient creates a client for a connection and starts its writeLoop, which
+// runs until ctx is cancelled or the client is stopped.
+func newClient(ctx context.Context, conn *websocket.Conn, player *models.Player, encoding Encoding) *client {
+	c := &client{
+		conn:     conn,
+		encoding: encoding,
+		player:   player,
+		send:     make(chan []byte, sendQueueSize),
+	}
+	c.ctx, c.cancel = context.WithCancel(ctx)
+	go c.writeLoop()
+	return c
+}
+
+// enqueue queues a JSON message for the client without blocking and reports
+// whether it was queued; when the queue is full the message is dropped.
+func (c *client) enqueue(message []byte) bool {
+	select {
+	case c.send <- message:
+		c.overflows.Store(0)
+		return true
+	default:
+		return false
+	}
+}
+
+// writeLoop writes queued messages to the connection in order until the client
+// is stopped, converting each from JSON to the client's encoding first; a
+// message that cannot be converted is sent as JSON instead. A write that fails, including one that exceeds writeWait, leaves
+// a gorilla/websocket connection unusable, so it is not retried: the connection
+// is closed instead.
+func (c *client) writeLoop() {
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case message := <-c.send:
+			frameType, data, err := encodeMessage(c.encoding, message)
+			if err != nil {
+				frameType, data = websocket.TextMessage, message
+			}
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(frameType, data); err != nil {
+				c.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// stop ends the client's writeLoop, discarding any messages still queued.
+// It may be called more than once.
+func (c *client) stop() {
+	c.cancel()
+}
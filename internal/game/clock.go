@@ -0,0 +1,22 @@
+package game
+
+import "time"
+
+// Clock tells the server the current time. Game logic reads the time through
+// the server's clock rather than time.Now, so tests can control it.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the Clock backed by the real wall clock.
+type systemClock struct{}
+
+// Now returns the current wall-clock time.
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Now returns the current time according to the server's clock.
+func (s *Server) Now() time.Time {
+	return s.clock.Now()
+}
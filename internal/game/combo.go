@@ -0,0 +1,28 @@
+package game
+
+import "github.com/evevioletrose-hash/idle-dungeon/internal/models"
+
+// Every victory in a row since the last defeat adds comboAttackBonus to the
+// hero's attack, up to maxComboBonus, so a long streak helps without letting
+// heroes outscale the dungeon.
+const (
+	comboAttackBonus = 0.02
+	maxComboBonus    = 0.5
+)
+
+// comboMultiplier returns the attack multiplier for a streak of combo victories.
+func comboMultiplier(combo int) float64 {
+	return 1 + min(maxComboBonus, comboAttackBonus*float64(combo))
+}
+
+// comboHero returns hero with the attack bonus of a streak of combo
+// victories, as a copy so the caller's hero is left unchanged. Without a
+// streak it returns hero itself.
+func comboHero(hero *models.Hero, combo int) *models.Hero {
+	if combo <= 0 {
+		return hero
+	}
+	boosted := *hero
+	boosted.Attack = int(float64(hero.Attack) * comboMultiplier(combo))
+	return &boosted
+}
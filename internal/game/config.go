@@ -0,0 +1,340 @@
+package game
+
+import (
+	"compress/flate"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// Config holds tunable settings for the game server.
+// The zero value is not meant to be used directly; start from DefaultConfig.
+type Config struct {
+	// LayawayEnabled makes unaffordable upgrade requests reserve the upgrade
+	// instead of rejecting it. Reserved upgrades complete automatically once
+	// the player has enough gold.
+	LayawayEnabled bool
+
+	// BatchNotifications collects the notifications generated for a player
+	// during a tick and delivers them as a single "events" message at the end
+	// of the tick. When disabled, each notification is sent immediately.
+	BatchNotifications bool
+
+	// TickInterval is how often the game loop runs a battle for each connected
+	// player, and the length of a tick when simulating offline progress.
+	// Zero or negative uses the default of one second.
+	TickInterval time.Duration
+
+	// BroadcastEveryNTicks makes the game loop send clients their state and
+	// batched notifications only on every Nth tick, so fast ticks do not
+	// flood the network. The ticks in between still run their battles, and
+	// their results reach clients coalesced into the next update. Zero or
+	// negative broadcasts every tick.
+	BroadcastEveryNTicks int
+
+	// MaxUpdatesPerBroadcast caps how many clients one broadcast sends an
+	// update, so thousands of connections are not all written in the same
+	// instant. Only clients whose player changed count; the rest are skipped.
+	// Changed clients beyond the cap wait for the next broadcast, those
+	// updated longest ago going first. Zero or negative means no cap.
+	MaxUpdatesPerBroadcast int
+
+	// BattleWorkers is how many goroutines share the battles of a tick, each
+	// processing one player at a time, so large player counts finish within
+	// the tick. Zero or negative uses GOMAXPROCS, and 1 processes players one
+	// after another. With more than one worker, Rewards must be safe for
+	// concurrent use.
+	BattleWorkers int
+
+	// SaveInterval is how often the game state is flushed to the persister.
+	SaveInterval time.Duration
+
+	// MaxOfflineDuration caps how much time away is simulated when a
+	// disconnected player returns.
+	MaxOfflineDuration time.Duration
+
+	// RandomSeed seeds the battle random source. Zero seeds it from the
+	// clock, so every run rolls differently.
+	RandomSeed int64
+
+	// PrestigeThreshold is the dungeon level a player must pass before they
+	// can prestige, trading their progress for a permanent hero multiplier.
+	PrestigeThreshold int
+
+	// BossInterval spawns a boss on every dungeon level that is a multiple of
+	// it. Bosses are tougher than regular enemies but pay more gold. Zero
+	// disables bosses.
+	BossInterval int
+
+	// RandomEnemyTypes rolls the archetype of each enemy at random instead of
+	// cycling through them by dungeon level.
+	RandomEnemyTypes bool
+
+	// ArmorPenStartLevel is the last dungeon level whose enemies ignore none
+	// of the hero's armor. Deeper enemies ignore ArmorPenPerLevel more of it
+	// for every level beyond, up to ArmorPenMax, so stacking armor cannot make
+	// a hero invincible.
+	ArmorPenStartLevel int
+	ArmorPenPerLevel   float64
+	ArmorPenMax        float64
+
+	// EnemyCurve sets how an enemy's HP and attack grow with dungeon level,
+	// before difficulty, boss, and archetype multipliers; see EnemyStats. A
+	// stat whose curve is left zero uses DefaultEnemyCurve.
+	EnemyCurve EnemyCurve
+
+	// StationCurves sets how each station type's multiplier and cost grow
+	// per upgrade. Station types missing from the map use DefaultStationCurve.
+	StationCurves map[models.StationType]StationCurve
+
+	// MaxStationLevel is the highest level a station can be upgraded to,
+	// rejecting further upgrades with ErrMaxStationLevel. Zero means no limit;
+	// costs stop growing at a fixed maximum either way.
+	MaxStationLevel int
+
+	// ResearchTree lists the research nodes players can unlock with gold, each
+	// after the nodes it requires. NewServer rejects a tree with duplicate IDs
+	// or a prerequisite that is not listed before the node needing it. Nil
+	// uses DefaultResearchTree; an empty, non-nil tree disables research.
+	ResearchTree []ResearchNode
+
+	// ZoneSize is how many consecutive dungeon levels make up a zone: zone 1
+	// is levels 1 to ZoneSize, zone 2 the next ZoneSize levels, and so on.
+	// Zero or negative uses the default of 10.
+	ZoneSize int
+
+	// LootTables holds each zone's item drops, keyed by zone number. A
+	// victory drops an item at the usual chance and picks it from the zone's
+	// table by weight; a zone without a table drops nothing. NewServer rejects
+	// entries with an unknown stat or rarity or a weight that is not
+	// positive. Nil keeps the default drops, which roll any stat at the
+	// standard rarity odds everywhere.
+	LootTables map[int][]LootEntry
+
+	// SoftCapThreshold is the station multiplier beyond which further upgrades
+	// have diminishing returns on hero stats; see EffectiveMultiplier. Zero
+	// disables the soft cap.
+	SoftCapThreshold float64
+
+	// PassiveGold is the gold every player earns each tick regardless of
+	// their battles, at a loot station multiplier of 1; see GoldPerTick.
+	// Zero disables passive income.
+	PassiveGold float64
+
+	// StartingGold, StartingDungeonLevel, and StartingStationLevels set what a
+	// newly created player begins with: their gold, the dungeon level of their
+	// first hero, and the level of each station, whose multiplier and cost
+	// follow from its curve. Station types missing from the map start at
+	// level 1. Prestige still resets players to gold 0 and level 1.
+	StartingGold          int
+	StartingDungeonLevel  int
+	StartingStationLevels map[models.StationType]int
+
+	// EvictAfter drops players from memory once they have not been seen for
+	// this long and have no open connection, after saving them, so players
+	// who never return do not stay in memory forever. An evicted player is
+	// reloaded from storage the next time they are looked up. Eviction needs
+	// a persister that implements models.PlayerLoader and is ignored with a
+	// warning otherwise. Zero disables it.
+	EvictAfter time.Duration
+
+	// UpgradeRateLimit is how many upgrade requests per second each player
+	// may make, over HTTP and WebSocket combined. Zero disables the limit.
+	UpgradeRateLimit float64
+
+	// MaxClients caps the number of open WebSocket connections. Zero means no limit.
+	MaxClients int
+
+	// DisplayNameLength is the most characters of a player's name shown to
+	// other players: on the leaderboard, in the hall of fame, in name
+	// searches, and in profiles. Longer names end in an ellipsis. Zero or
+	// negative uses models.MaxNameLength.
+	DisplayNameLength int
+
+	// CompressMessages negotiates permessage-deflate compression with
+	// WebSocket clients that support it, so every message the server sends
+	// them is compressed at CompressionLevel, a compress/flate level from -2
+	// (Huffman only) to 9 (best compression). Levels outside that range use
+	// flate.BestSpeed.
+	CompressMessages bool
+	CompressionLevel int
+
+	// AllowedOrigins lists the browser origins, such as "https://game.example.com",
+	// that may open WebSocket connections and call the HTTP API. A "*" entry
+	// allows every origin. Pages served by the server itself are always
+	// allowed, so when the list is empty only same-origin requests are.
+	AllowedOrigins []string
+
+	// ExportSecret is the key player exports are signed with. When empty, a
+	// random key is generated at startup, so exports only import into the
+	// same run of the server.
+	ExportSecret []byte
+
+	// ExportTTL is how long a player export can be imported after it was
+	// taken. Each export can be imported once, and never after a season
+	// reset or an admin gold deduction that came later.
+	ExportTTL time.Duration
+
+	// Rewards decides the gold and experience each battle pays. Nil uses DefaultRewards.
+	Rewards RewardCalculator
+
+	// Clock supplies the current time for game logic such as offline progress
+	// and last-seen tracking. Nil uses the system clock.
+	Clock Clock
+
+	// Logger receives the server's structured logs. Nil uses slog.Default().
+	Logger *slog.Logger
+}
+
+// StationCurve is the progression of one station type: every upgrade adds
+// MultiplierIncrement to the station's multiplier and multiplies the cost of
+// the next upgrade by CostGrowth.
+type StationCurve struct {
+	MultiplierIncrement float64 `json:"multiplierIncrement"`
+	CostGrowth          float64 `json:"costGrowth"`
+}
+
+// DefaultStationCurve is the progression every station type follows unless configured otherwise.
+var DefaultStationCurve = StationCurve{MultiplierIncrement: 0.2, CostGrowth: 1.5}
+
+// Valid reports whether the curve never lowers a multiplier or a cost: the
+// increment is finite and not negative, and the cost growth is at least 1.
+// Growth below 1 would shrink costs until they truncate to 0 gold.
+func (c StationCurve) Valid() bool {
+	return c.MultiplierIncrement >= 0 && !math.IsInf(c.MultiplierIncrement, 1) && c.CostGrowth >= 1
+}
+
+// EnemyStatCurve is the growth of one enemy stat with dungeon level L:
+// (Base + Linear*L + Quadratic*L²) * (1 + Exponential)^(L-1). Exponential is
+// the extra growth rate per level, so 0.05 compounds 5% a level on top of the
+// polynomial, and 0 leaves it out.
+type EnemyStatCurve struct {
+	Base        float64 `json:"base"`
+	Linear      float64 `json:"linear"`
+	Quadratic   float64 `json:"quadratic"`
+	Exponential float64 `json:"exponential"`
+}
+
+// EnemyCurve is the growth of an enemy's HP and attack with dungeon level.
+type EnemyCurve struct {
+	HP     EnemyStatCurve `json:"hp"`
+	Attack EnemyStatCurve `json:"attack"`
+}
+
+// DefaultEnemyCurve is the linear growth enemies follow unless configured
+// otherwise: 50 + 10 HP and 15 + 5 attack per dungeon level.
+var DefaultEnemyCurve = EnemyCurve{
+	HP:     EnemyStatCurve{Base: 50, Linear: 10},
+	Attack: EnemyStatCurve{Base: 15, Linear: 5},
+}
+
+// maxEnemyStat bounds the base HP and attack EnemyStats returns, so a steep
+// curve cannot overflow int even after the difficulty and boss multipliers,
+// where int is 32 bits.
+const maxEnemyStat = 1 << 26
+
+// DefaultConfig returns the settings the game uses when nothing is configured.
+func DefaultConfig() Config {
+	return Config{
+		LayawayEnabled:       false,
+		BatchNotifications:   true,
+		TickInterval:         time.Second,
+		BroadcastEveryNTicks: 1,
+		SaveInterval:         30 * time.Second,
+		MaxOfflineDuration:   8 * time.Hour,
+		ExportTTL:            24 * time.Hour,
+		PrestigeThreshold:    50,
+		BossInterval:         10,
+		ArmorPenStartLevel:   20,
+		ArmorPenPerLevel:     0.01,
+		ArmorPenMax:          0.75,
+		EnemyCurve:           DefaultEnemyCurve,
+		StationCurves:        defaultStationCurves(),
+		PassiveGold:          2,
+		StartingDungeonLevel: 1,
+		UpgradeRateLimit:     10,
+		MaxClients:           1000,
+		DisplayNameLength:    models.MaxNameLength,
+		CompressionLevel:     flate.BestSpeed,
+	}
+}
+
+// Zone returns the zone the given dungeon level belongs to; see ZoneSize.
+func (c Config) Zone(dungeonLevel int) int {
+	size := c.ZoneSize
+	if size <= 0 {
+		size = defaultZoneSize
+	}
+	return (max(dungeonLevel, 1)-1)/size + 1
+}
+
+// EffectiveMultiplier returns the multiplier a station with the given raw
+// multiplier applies to hero stats. Below SoftCapThreshold it is the raw value;
+// above it, growth turns logarithmic as threshold * (1 + ln(raw / threshold)),
+// which meets the raw value at the threshold with the same slope, so there is
+// no visible step, but doubling a large multiplier only adds a constant amount.
+// It returns raw unchanged when the soft cap is disabled.
+func (c Config) EffectiveMultiplier(raw float64) float64 {
+	threshold := c.SoftCapThreshold
+	if threshold <= 0 || raw <= threshold {
+		return raw
+	}
+	return threshold * (1 + math.Log(raw/threshold))
+}
+
+// EnemyStats returns the base HP and attack of an enemy on the given dungeon
+// level from EnemyCurve, before difficulty, boss, and archetype multipliers.
+// Each stat is truncated to an integer and kept between 1 and maxEnemyStat.
+func (c Config) EnemyStats(level int) (hp, attack int) {
+	return c.EnemyCurve.HP.stat(DefaultEnemyCurve.HP, level), c.EnemyCurve.Attack.stat(DefaultEnemyCurve.Attack, level)
+}
+
+// stat evaluates the curve at the given dungeon level, using fallback when
+// the curve is left zero.
+func (curve EnemyStatCurve) stat(fallback EnemyStatCurve, level int) int {
+	if curve == (EnemyStatCurve{}) {
+		curve = fallback
+	}
+	l := float64(level)
+	value := curve.Base + curve.Linear*l + curve.Quadratic*l*l
+	if curve.Exponential != 0 {
+		value *= math.Pow(1+curve.Exponential, l-1)
+	}
+	if math.IsNaN(value) {
+		return 1
+	}
+	return int(math.Max(1, math.Min(value, maxEnemyStat)))
+}
+
+// GoldPerTick returns the passive gold a player whose loot station has the
+// given raw multiplier earns every tick: PassiveGold times the loot
+// multiplier after the soft cap, truncated. It never returns less than zero.
+func (c Config) GoldPerTick(lootMultiplier float64) int {
+	return max(0, int(c.PassiveGold*c.EffectiveMultiplier(lootMultiplier)))
+}
+
+// defaultStationCurves returns a curve map giving every station type DefaultStationCurve.
+func defaultStationCurves() map[models.StationType]StationCurve {
+	curves := make(map[models.StationType]StationCurve, len(models.StationTypes))
+	for _, stationType := range models.StationTypes {
+		curves[stationType] = DefaultStationCurve
+	}
+	return curves
+}
+
+// validStationCurves returns a copy of curves in which every invalid curve is
+// replaced by DefaultStationCurve, each replacement logged as a warning.
+func validStationCurves(curves map[models.StationType]StationCurve, logger *slog.Logger) map[models.StationType]StationCurve {
+	valid := make(map[models.StationType]StationCurve, len(curves))
+	for stationType, curve := range curves {
+		if !curve.Valid() {
+			logger.Warn("replacing invalid station curve with the default", "station", stationType,
+				"multiplier_increment", curve.MultiplierIncrement, "cost_growth", curve.CostGrowth)
+			curve = DefaultStationCurve
+		}
+		valid[stationType] = curve
+	}
+	return valid
+}
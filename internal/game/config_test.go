@@ -0,0 +1,93 @@
+package game_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game/testutil"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+func TestEffectiveMultiplier(t *testing.T) {
+	softened := game.Config{SoftCapThreshold: 5}
+	for _, test := range []struct {
+		name   string
+		config game.Config
+		raw    float64
+		want   float64
+	}{
+		{"below the threshold", softened, 2.4, 2.4},
+		{"at the threshold", softened, 5, 5},
+		{"e times the threshold", softened, 5 * math.E, 10},
+		{"e squared times the threshold", softened, 5 * math.E * math.E, 15},
+		{"far above the threshold", softened, 1000, 5 * (1 + math.Log(200))},
+		{"soft cap disabled", game.Config{}, 1000, 1000},
+	} {
+		if got := test.config.EffectiveMultiplier(test.raw); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s: EffectiveMultiplier(%g) = %g, want %g", test.name, test.raw, got, test.want)
+		}
+	}
+}
+
+func TestEffectiveMultiplierHasNoStep(t *testing.T) {
+	config := game.Config{SoftCapThreshold: 5}
+	previous := config.EffectiveMultiplier(4)
+	for raw := 4.001; raw <= 50; raw += 0.001 {
+		got := config.EffectiveMultiplier(raw)
+		if got <= previous || got-previous > 0.0011 {
+			t.Fatalf("EffectiveMultiplier rose from %g to %g at %g", previous, got, raw)
+		}
+		previous = got
+	}
+}
+
+func TestHeroUsesEffectiveMultiplier(t *testing.T) {
+	config := game.DefaultConfig()
+	config.SoftCapThreshold = 5
+	h := testutil.New(t, config)
+	player, _ := h.Server.GetOrCreatePlayer("alice")
+	h.Server.View(func() {
+		player.Factory.Station(models.StationHP).Multiplier = 50
+	})
+
+	for _, stat := range h.Server.HeroBreakdown(player).Stats {
+		if stat.Stat != models.StationHP {
+			continue
+		}
+		if want := config.EffectiveMultiplier(50); stat.EffectiveMultiplier != want {
+			t.Errorf("hp station at 50x applies %gx, want %gx", stat.EffectiveMultiplier, want)
+		}
+		if hp := h.Server.Hero(player).HP; float64(hp) >= stat.Base*50 {
+			t.Errorf("hero has %d HP, want the softened %gx rather than 50x of %g", hp, stat.EffectiveMultiplier, stat.Base)
+		}
+		return
+	}
+	t.Error("hero breakdown has no hp stat")
+}
+
+func TestEnemyStats(t *testing.T) {
+	quadratic := game.Config{EnemyCurve: game.EnemyCurve{HP: game.EnemyStatCurve{Base: 40, Quadratic: 2}}}
+	exponential := game.Config{EnemyCurve: game.EnemyCurve{Attack: game.EnemyStatCurve{Base: 10, Exponential: 1}}}
+	extreme := game.Config{EnemyCurve: game.EnemyCurve{HP: game.EnemyStatCurve{Base: -100}, Attack: game.EnemyStatCurve{Base: 1, Exponential: 1}}}
+	for _, test := range []struct {
+		name       string
+		config     game.Config
+		level      int
+		hp, attack int
+	}{
+		{"default at level 1", game.DefaultConfig(), 1, 60, 20},
+		{"default at level 10", game.DefaultConfig(), 10, 150, 65},
+		{"default at level 100", game.DefaultConfig(), 100, 1050, 515},
+		{"unset curve", game.Config{}, 10, 150, 65},
+		{"quadratic at level 1", quadratic, 1, 42, 20},
+		{"quadratic at level 10", quadratic, 10, 240, 65},
+		{"exponential at level 1", exponential, 1, 60, 10},
+		{"exponential at level 4", exponential, 4, 90, 80},
+		{"floored and capped", extreme, 100, 1, 1 << 26},
+	} {
+		if hp, attack := test.config.EnemyStats(test.level); hp != test.hp || attack != test.attack {
+			t.Errorf("%s: EnemyStats(%d) = %d HP and %d attack, want %d and %d", test.name, test.level, hp, attack, test.hp, test.attack)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package game
+
+import "github.com/evevioletrose-hash/idle-dungeon/internal/models"
+
+// difficultyScale is how a difficulty tier scales the enemies on every dungeon
+// level and the gold and experience paid for fighting them.
+type difficultyScale struct {
+	enemy  float64 // Multiplier on enemy HP and attack
+	reward float64 // Multiplier on gold and experience rewards
+}
+
+// difficultyScales holds the scale of each difficulty tier. Rewards always grow
+// faster than the enemies, so a hero strong enough for a harder tier earns more there.
+var difficultyScales = map[models.DifficultyTier]difficultyScale{
+	models.DifficultyNormal:    {enemy: 1, reward: 1},
+	models.DifficultyHard:      {enemy: 2, reward: 3},
+	models.DifficultyNightmare: {enemy: 4, reward: 8},
+}
+
+// difficulty returns the scale of a difficulty tier. Players saved before
+// difficulty tiers existed have none and fight on normal.
+func difficulty(tier models.DifficultyTier) difficultyScale {
+	if scale, ok := difficultyScales[tier]; ok {
+		return scale
+	}
+	return difficultyScales[models.DifficultyNormal]
+}
+
+// SetDifficulty validates and applies a player's chosen difficulty tier, then
+// pushes an update so the player's clients show it right away. The new tier
+// applies from the player's next battle; dungeon levels are kept.
+func (s *Server) SetDifficulty(player *models.Player, name string) error {
+	tier, err := models.ValidateDifficulty(name)
+	if err != nil {
+		return err
+	}
+	s.gameState.Update(func() {
+		player.Difficulty = tier
+	})
+
+	s.logger.Info("difficulty changed", "event", "difficulty", "player_id", player.ID, "difficulty", tier)
+	s.requestUpdates()
+	return nil
+}
@@ -0,0 +1,132 @@
+package game
+
+import (
+	"errors"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// maxDuelRounds bounds a duel between two heroes that barely damage each other.
+const maxDuelRounds = 1000
+
+var (
+	// ErrSelfChallenge is returned when a player tries to duel themselves.
+	ErrSelfChallenge = errors.New("players cannot challenge themselves")
+	// ErrUnknownOpponent is returned when the challenged player does not exist.
+	ErrUnknownOpponent = errors.New("opponent not found")
+)
+
+// DuelHistory returns a copy of the player's recent duel results, oldest first.
+func (s *Server) DuelHistory(player *models.Player) []models.DuelResult {
+	var duels []models.DuelResult
+	s.gameState.View(func() {
+		duels = append([]models.DuelResult{}, player.Duels...)
+	})
+	return duels
+}
+
+// Duel resolves a challenge between two players' current heroes.
+// The opponent does not need to be online; their hero is built from stored state.
+// The result is recorded on both players and both are sent a "duel" notification.
+func (s *Server) Duel(challenger *models.Player, opponentID string) (*models.DuelResult, error) {
+	if challenger.ID == opponentID {
+		return nil, ErrSelfChallenge
+	}
+
+	opponent, exists := s.GetPlayer(opponentID)
+	if !exists {
+		return nil, ErrUnknownOpponent
+	}
+
+	var challengerHero, opponentHero *models.Hero
+	s.gameState.View(func() {
+		challengerHero = s.createHero(challenger)
+		opponentHero = s.createHero(opponent)
+	})
+	challengerWins, rounds := s.simulateDuel(challengerHero, opponentHero)
+
+	result := models.DuelResult{
+		ChallengerID: challenger.ID,
+		OpponentID:   opponent.ID,
+		WinnerID:     opponent.ID,
+		Rounds:       rounds,
+		Time:         s.clock.Now(),
+	}
+	if challengerWins {
+		result.WinnerID = challenger.ID
+	}
+
+	s.gameState.Update(func() {
+		challenger.AddDuel(result)
+		opponent.AddDuel(result)
+	})
+
+	// Notify both parties
+	notification := models.Notification{Type: "duel", Data: result}
+	s.notify(challenger.ID, notification)
+	s.notify(opponent.ID, notification)
+
+	return &result, nil
+}
+
+// PreviewChallenge predicts who would win a duel between two players' current
+// heroes without recording anything or notifying either player. It uses the
+// same hero-vs-hero simulation as Duel, so the preview matches a real duel
+// fought right now.
+func (s *Server) PreviewChallenge(attacker *models.Player, defenderID string) (*models.ChallengePreview, error) {
+	if attacker.ID == defenderID {
+		return nil, ErrSelfChallenge
+	}
+
+	defender, exists := s.GetPlayer(defenderID)
+	if !exists {
+		return nil, ErrUnknownOpponent
+	}
+
+	var attackerHero, defenderHero *models.Hero
+	s.gameState.View(func() {
+		attackerHero = s.createHero(attacker)
+		defenderHero = s.createHero(defender)
+	})
+	attackerWins, rounds := s.simulateDuel(attackerHero, defenderHero)
+
+	preview := &models.ChallengePreview{
+		AttackerID:   attacker.ID,
+		DefenderID:   defender.ID,
+		WinnerID:     defender.ID,
+		Rounds:       rounds,
+		AttackerHero: *attackerHero,
+		DefenderHero: *defenderHero,
+	}
+	if attackerWins {
+		preview.WinnerID = attacker.ID
+	}
+	return preview, nil
+}
+
+// simulateDuel performs turn-based combat between two heroes and reports whether the challenger won.
+// The challenger strikes first each round, and each hit is reduced by the defender's armor.
+// If neither hero falls within maxDuelRounds, the hero with the larger share of HP left wins,
+// with ties going to the opponent.
+func (s *Server) simulateDuel(challenger, opponent *models.Hero) (bool, int) {
+	challengerHP := challenger.HP
+	opponentHP := opponent.HP
+	challengerDamage := max(1, challenger.Attack-opponent.Armor)
+	opponentDamage := max(1, opponent.Attack-challenger.Armor)
+
+	for round := 1; round <= maxDuelRounds; round++ {
+		// Challenger attacks first
+		opponentHP -= challengerDamage
+		if opponentHP <= 0 {
+			return true, round
+		}
+
+		// Opponent counter-attacks
+		challengerHP -= opponentDamage
+		if challengerHP <= 0 {
+			return false, round
+		}
+	}
+
+	return challengerHP*opponent.HP > opponentHP*challenger.HP, maxDuelRounds
+}
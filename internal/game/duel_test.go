@@ -0,0 +1,107 @@
+package game_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game/testutil"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// strengthen spends a fortune on the player's attack and HP stations.
+func strengthen(t *testing.T, h *testutil.Harness, playerID string) {
+	t.Helper()
+	player, _ := h.Server.GetOrCreatePlayer(playerID)
+	for _, stationType := range []models.StationType{models.StationAttack, models.StationHP} {
+		setGold(t, h, playerID, 1_000_000)
+		if _, err := h.Server.UpgradeStationMax(player, string(stationType)); err != nil {
+			t.Fatalf("upgrade %s: %v", stationType, err)
+		}
+	}
+}
+
+func TestDuelStrongerHeroWins(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	alice, _ := h.Server.GetOrCreatePlayer("alice")
+	strengthen(t, h, "bob")
+
+	for _, pairing := range []struct{ challenger, opponent string }{{"alice", "bob"}, {"bob", "alice"}} {
+		challenger, _ := h.Server.GetPlayer(pairing.challenger)
+		result, err := h.Server.Duel(challenger, pairing.opponent)
+		if err != nil {
+			t.Fatalf("%s challenges %s: %v", pairing.challenger, pairing.opponent, err)
+		}
+		if result.WinnerID != "bob" {
+			t.Errorf("%s challenges %s: %s won, want bob", pairing.challenger, pairing.opponent, result.WinnerID)
+		}
+	}
+
+	// The same heroes always fight the same duel
+	first, _ := h.Server.Duel(alice, "bob")
+	second, _ := h.Server.Duel(alice, "bob")
+	if first.Rounds != second.Rounds || first.WinnerID != second.WinnerID {
+		t.Errorf("repeated duel differs: %+v, then %+v", first, second)
+	}
+
+	for _, id := range []string{"alice", "bob"} {
+		if duels := h.Player(id).Duels; len(duels) != 4 {
+			t.Errorf("%s has %d duels recorded, want 4", id, len(duels))
+		}
+	}
+}
+
+func TestDuelNotifiesBothPlayers(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	alice := h.Dial("alice")
+	bob := h.Dial("bob")
+
+	challenger, _ := h.Server.GetPlayer("alice")
+	result, err := h.Server.Duel(challenger, "bob")
+	if err != nil {
+		t.Fatalf("duel: %v", err)
+	}
+	h.Advance(1) // Notifications are delivered at the end of the tick
+
+	for name, client := range map[string]*testutil.Client{"alice": alice, "bob": bob} {
+		found := false
+		for _, event := range client.Next("events")["events"].([]interface{}) {
+			notification := event.(map[string]interface{})
+			data, _ := notification["data"].(map[string]interface{})
+			if notification["type"] == "duel" && data["challengerId"] == "alice" && data["winnerId"] == result.WinnerID {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s was not sent the duel result", name)
+		}
+	}
+}
+
+func TestDuelOfflineOpponent(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	h.Server.GetOrCreatePlayer("bob") // Never connects
+	alice, _ := h.Server.GetOrCreatePlayer("alice")
+
+	if _, err := h.Server.Duel(alice, "bob"); err != nil {
+		t.Fatalf("duel with an offline player: %v", err)
+	}
+	if duels := h.Player("bob").Duels; len(duels) != 1 {
+		t.Errorf("offline opponent has %d duels recorded, want 1", len(duels))
+	}
+}
+
+func TestDuelRejectsSelfAndUnknownOpponents(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	alice, _ := h.Server.GetOrCreatePlayer("alice")
+
+	if _, err := h.Server.Duel(alice, "alice"); !errors.Is(err, game.ErrSelfChallenge) {
+		t.Errorf("self-challenge: %v, want ErrSelfChallenge", err)
+	}
+	if _, err := h.Server.Duel(alice, "nobody"); !errors.Is(err, game.ErrUnknownOpponent) {
+		t.Errorf("challenge of an unknown player: %v, want ErrUnknownOpponent", err)
+	}
+	if duels := h.Player("alice").Duels; len(duels) != 0 {
+		t.Errorf("rejected challenges recorded %d duels", len(duels))
+	}
+}
@@ -0,0 +1,84 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Encoding is the wire format of the messages the server sends a WebSocket client.
+type Encoding string
+
+// The supported encodings. Messages are built as JSON; a msgpack client gets
+// each one converted to MessagePack and sent as a binary frame.
+const (
+	EncodingJSON    Encoding = "json"    // JSON in text frames, the default
+	EncodingMsgpack Encoding = "msgpack" // MessagePack in binary frames
+)
+
+// Encodings lists every supported encoding, which are also the WebSocket
+// subprotocols a client may request one with.
+var Encodings = []string{string(EncodingJSON), string(EncodingMsgpack)}
+
+// ErrUnknownEncoding is returned for an encoding that is not supported.
+var ErrUnknownEncoding = errors.New("unknown encoding")
+
+// ParseEncoding returns the encoding with the given name. An empty name means
+// EncodingJSON.
+func ParseEncoding(name string) (Encoding, error) {
+	switch Encoding(name) {
+	case "", EncodingJSON:
+		return EncodingJSON, nil
+	case EncodingMsgpack:
+		return EncodingMsgpack, nil
+	}
+	return "", ErrUnknownEncoding
+}
+
+// encodeMessage converts a JSON message to the given encoding and returns the
+// WebSocket frame type to send it in.
+func encodeMessage(encoding Encoding, message []byte) (int, []byte, error) {
+	if encoding != EncodingMsgpack {
+		return websocket.TextMessage, message, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(message))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return 0, nil, err
+	}
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	encoder.UseCompactInts(true)
+	if err := encoder.Encode(compactNumbers(value)); err != nil {
+		return 0, nil, err
+	}
+	return websocket.BinaryMessage, buf.Bytes(), nil
+}
+
+// compactNumbers replaces every JSON number in a decoded value with an int64
+// when it is a whole number that fits, and a float64 otherwise, so MessagePack
+// can store counts and levels in as few bytes as they need.
+func compactNumbers(value interface{}) interface{} {
+	switch value := value.(type) {
+	case json.Number:
+		if n, err := value.Int64(); err == nil {
+			return n
+		}
+		f, _ := value.Float64()
+		return f
+	case map[string]interface{}:
+		for key, field := range value {
+			value[key] = compactNumbers(field)
+		}
+	case []interface{}:
+		for i, element := range value {
+			value[i] = compactNumbers(element)
+		}
+	}
+	return value
+}
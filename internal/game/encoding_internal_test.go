@@ -0,0 +1,44 @@
+package game
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestEncodeMessageMsgpack(t *testing.T) {
+	message := []byte(`{"type":"update","player":{"gold":7,"multiplier":1.5,"name":"Ann"}}`)
+	frame, encoded, err := encodeMessage(EncodingMsgpack, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame != websocket.BinaryMessage {
+		t.Errorf("msgpack message sent in frame type %d, want binary", frame)
+	}
+
+	var decoded struct {
+		Type   string `msgpack:"type"`
+		Player struct {
+			Gold       int     `msgpack:"gold"`
+			Multiplier float64 `msgpack:"multiplier"`
+			Name       string  `msgpack:"name"`
+		} `msgpack:"player"`
+	}
+	if err := msgpack.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Type != "update" || decoded.Player.Gold != 7 || decoded.Player.Multiplier != 1.5 || decoded.Player.Name != "Ann" {
+		t.Errorf("decoded %+v, want the fields of %s", decoded, message)
+	}
+	// A small count takes a single byte rather than a full int64
+	if !bytes.Contains(encoded, []byte("\xa4gold\x07")) {
+		t.Errorf("gold 7 not encoded as a single byte in % x", encoded)
+	}
+
+	frame, encoded, _ = encodeMessage(EncodingJSON, message)
+	if frame != websocket.TextMessage || string(encoded) != string(message) {
+		t.Errorf("JSON message sent as frame %d %q, want the original text", frame, encoded)
+	}
+}
@@ -0,0 +1,37 @@
+package game
+
+import (
+	"math/rand/v2"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// enemyProfile is how an enemy archetype changes the regular enemy of a
+// dungeon level and what it pays for beating it.
+type enemyProfile struct {
+	hp       float64 // Multiplier on enemy HP
+	attack   float64 // Multiplier on enemy attack, shared between its hits
+	hits     int     // Attacks the enemy makes each turn, each reduced by armor on its own
+	armorPen float64 // Extra share of hero armor ignored, on top of the level's armor penetration
+	reward   float64 // Multiplier on gold and experience rewards
+	dodge    float64 // Chance from 0 to 1 that the enemy avoids each hero attack
+}
+
+// enemyProfiles holds the profile of each enemy archetype. Archetypes that are
+// harder for most builds pay a little more.
+var enemyProfiles = map[models.EnemyType]enemyProfile{
+	models.EnemyStandard:    {hp: 1, attack: 1, hits: 1, reward: 1, dodge: 0.05},
+	models.EnemyTank:        {hp: 2, attack: 0.6, hits: 1, reward: 1.3},
+	models.EnemyGlassCannon: {hp: 0.5, attack: 1.5, hits: 1, armorPen: 0.5, reward: 1.2, dodge: 0.15},
+	models.EnemySwarm:       {hp: 1, attack: 1.2, hits: 3, reward: 1.1, dodge: 0.05},
+}
+
+// enemyType returns the archetype of the enemy on the given dungeon level.
+// Levels cycle through models.EnemyTypes, so a level always fields the same
+// archetype, unless RandomEnemyTypes is set, in which case it is rolled from rng.
+func (s *Server) enemyType(dungeonLevel int, rng *rand.Rand) models.EnemyType {
+	if s.config.RandomEnemyTypes {
+		return models.EnemyTypes[rng.IntN(len(models.EnemyTypes))]
+	}
+	return models.EnemyTypes[(max(1, dungeonLevel)-1)%len(models.EnemyTypes)]
+}
@@ -0,0 +1,38 @@
+package game
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// Events returns a copy of the player's activity feed, oldest first.
+func (s *Server) Events(player *models.Player) []models.Event {
+	var events []models.Event
+	s.gameState.View(func() {
+		events = append([]models.Event{}, player.Events...)
+	})
+	return events
+}
+
+// recordBattleEvents adds the notable outcomes of a battle fought on the given
+// dungeon level at the given time to the player's activity feed: a boss
+// defeated, and a dropped item rarer than common.
+// The caller holds the game-state write lock.
+func recordBattleEvents(player *models.Player, dungeonLevel int, result models.BattleResult, at time.Time) {
+	if result.IsBoss && result.Victory {
+		player.AddEvent(models.Event{
+			Type:    models.EventBossKill,
+			Message: fmt.Sprintf("Defeated the boss of dungeon level %d (+%d gems)", dungeonLevel, result.Gems),
+			Time:    at,
+		})
+	}
+	if item := result.Item; item != nil && item.Rarity != models.RarityCommon {
+		player.AddEvent(models.Event{
+			Type:    models.EventRareLoot,
+			Message: fmt.Sprintf("Found a %s (+%d %s)", item.Name, item.Bonus, item.Stat),
+			Time:    at,
+		})
+	}
+}
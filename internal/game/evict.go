@@ -0,0 +1,122 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// evictSweepInterval is how often idle players are looked for when EvictAfter is set.
+const evictSweepInterval = 5 * time.Minute
+
+// evictLoop evicts idle players on every evictSweepInterval until ctx is cancelled.
+// Failed sweeps are logged and retried on the next interval.
+func (s *Server) evictLoop(ctx context.Context) {
+	ticker := time.NewTicker(evictSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.EvictIdlePlayers(); err != nil {
+				s.logger.Error("failed to evict idle players", "event", "evict", "error", err)
+			}
+		}
+	}
+}
+
+// EvictIdlePlayers saves every player who has not been seen for EvictAfter
+// and has no open connection, then drops them from memory, and returns how
+// many were evicted. The game loop is paused while the idle players are
+// picked and evicted, but not during the save, so a stuck persister cannot
+// hold up ticks; a player who changes between the save and the eviction is
+// kept. It is a no-op when eviction is disabled.
+func (s *Server) EvictIdlePlayers() (int, error) {
+	if s.loader == nil {
+		return 0, nil
+	}
+	cutoff := s.clock.Now().Add(-s.config.EvictAfter)
+
+	// Pause the game loop and admin operations, which change players without
+	// marking them seen, while the idle players are copied
+	s.loopMutex.Lock()
+	connected := make(map[string]bool)
+	for _, player := range s.connectedPlayers() {
+		connected[player.ID] = true
+	}
+	idle := make(map[string]*models.Player)
+	s.gameState.View(func() {
+		for id, player := range s.gameState.Players {
+			if !connected[id] && player.LastSeen.Before(cutoff) {
+				idle[id] = player.Clone()
+			}
+		}
+	})
+	restores := s.restores
+	s.loopMutex.Unlock()
+	if len(idle) == 0 {
+		return 0, nil
+	}
+
+	// Saved without any game-state lock held, from copies
+	saved := models.NewGameState()
+	saved.ReplacePlayers(idle)
+	if err := s.persist(saved, restores); err != nil {
+		return 0, fmt.Errorf("save idle players: %w", err)
+	}
+
+	s.loopMutex.Lock()
+	defer s.loopMutex.Unlock()
+	evicted := 0
+	s.gameState.Update(func() {
+		for id, snapshot := range idle {
+			// A player who returned since the snapshot was changed by it, at
+			// least in LastSeen, so their latest state stays in memory
+			if player, exists := s.gameState.Players[id]; exists && reflect.DeepEqual(player, snapshot) {
+				delete(s.gameState.Players, id)
+				evicted++
+			}
+		}
+	})
+	s.metrics.evictions.Add(float64(evicted))
+	s.logger.Info("evicted idle players", "event", "evict", "players", evicted)
+	return evicted, nil
+}
+
+// findPlayer looks up the player with the given ID, reloading them from
+// storage if they were evicted. It reports false when the player is not in
+// memory or, with eviction enabled, in storage either.
+func (s *Server) findPlayer(playerID string) (*models.Player, bool, error) {
+	if player, exists := s.gameState.GetPlayer(playerID); exists || s.loader == nil {
+		return player, exists, nil
+	}
+
+	player, exists, err := s.loader.LoadPlayer(playerID)
+	if err != nil || !exists {
+		return nil, false, err
+	}
+	s.validatePlayer(player, "load")
+	s.gameState.Update(func() { player = s.residentPlayer(player) })
+	return player, true, nil
+}
+
+// residentPlayer returns the player in the game state with the same ID as
+// player, adding player to it (and to their guild) when there is none, as for
+// a new or reloaded player. Whoever finds a player already added by someone
+// else uses that one, so there is only ever one copy of each player in memory.
+// The caller holds the game-state write lock.
+func (s *Server) residentPlayer(player *models.Player) *models.Player {
+	if current, exists := s.gameState.Players[player.ID]; exists {
+		return current
+	}
+	s.gameState.Players[player.ID] = player
+	if player.Guild != "" {
+		s.addGuildMember(player, player.Guild)
+	}
+	return player
+}
@@ -0,0 +1,188 @@
+package game
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+var (
+	// ErrInvalidSignature is returned when an import's signature does not match its data.
+	ErrInvalidSignature = errors.New("export signature does not match")
+	// ErrExportExpired is returned, wrapped with the reason, when importing an
+	// export older than ExportTTL, than the last season reset, or than an
+	// admin gold deduction of the exported player, or of a player that no
+	// longer exists.
+	ErrExportExpired = errors.New("export has expired")
+	// ErrExportUsed is returned when importing an export that was already imported.
+	ErrExportUsed = errors.New("export was already imported")
+)
+
+// ExportPlayer returns a snapshot of the player's full state signed with the
+// server's export secret, so it can be imported later without the player
+// being able to edit their gold or levels in between. Each export carries
+// the time it was taken and a random nonce, both signed, so ImportPlayer can
+// let it through once, within ExportTTL.
+func (s *Server) ExportPlayer(player *models.Player) (*models.SignedExport, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate export nonce: %w", err)
+	}
+
+	var data []byte
+	var err error
+	s.gameState.View(func() {
+		exported := player.Clone()
+		exported.ImportedExports = nil
+		exported.ExportsVoidBefore = time.Time{}
+		data, err = json.Marshal(models.PlayerExport{
+			Version:    models.ExportVersion,
+			ExportedAt: s.clock.Now(),
+			Nonce:      hex.EncodeToString(nonce),
+			Player:     exported,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encode export: %w", err)
+	}
+
+	return &models.SignedExport{Data: data, Signature: s.signExport(data)}, nil
+}
+
+// ImportPlayer verifies a signed export and restores the player it holds,
+// replacing any player that already has the target ID. The player is stored
+// under playerID, or under the exported ID when playerID is empty. Open
+// connections for that ID are rebound to the imported player. A replaced
+// player's token stays valid; a new player takes the token of the export.
+//
+// Importing under another ID moves the player there: the exported player is
+// reset as by ResetPlayer and its earlier exports are voided, so exporting it
+// again cannot clone the progress into yet another ID. Its open connections
+// are sent the reset state.
+//
+// An export imports once: its nonce is recorded on the exported player, and
+// a second import returns ErrExportUsed. Exports older than ExportTTL, taken
+// before the last season reset or before an admin gold deduction of the
+// exported player, or of a player that no longer exists, return a wrapped
+// ErrExportExpired, so an old export can neither clone progress nor undo
+// what has happened since.
+func (s *Server) ImportPlayer(signed *models.SignedExport, playerID string) (*models.Player, error) {
+	signature, err := hex.DecodeString(signed.Signature)
+	if err != nil || !hmac.Equal(signature, s.exportMAC(signed.Data)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var export models.PlayerExport
+	if err := json.Unmarshal(signed.Data, &export); err != nil {
+		return nil, fmt.Errorf("decode export: %w", err)
+	}
+	if err := export.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid export: %w", err)
+	}
+
+	now := s.clock.Now()
+	if now.Sub(export.ExportedAt) > s.config.ExportTTL {
+		return nil, fmt.Errorf("%w: taken more than %s ago", ErrExportExpired, s.config.ExportTTL)
+	}
+	if seasons := s.HallOfFame(); len(seasons) > 0 && export.ExportedAt.Before(seasons[len(seasons)-1].EndedAt) {
+		return nil, fmt.Errorf("%w: taken before the last season reset", ErrExportExpired)
+	}
+
+	player := export.Player
+	sourceID := player.ID
+	if playerID != "" {
+		player.ID = playerID
+	}
+	player.LastSeen = now // Time before the import does not count as offline progress
+	player.LastBattle = nil
+	player.TrackMaxDungeonLevel() // Exports made before the deepest level was tracked lack it
+	s.validatePlayer(player, "import")
+
+	s.loopMutex.Lock()
+	defer s.loopMutex.Unlock()
+
+	source, exists, err := s.findPlayer(sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("load exported player: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("%w: the exported player no longer exists", ErrExportExpired)
+	}
+	s.gameState.Update(func() {
+		switch {
+		case export.ExportedAt.Before(source.ExportsVoidBefore):
+			err = fmt.Errorf("%w: the exported player has changed since", ErrExportExpired)
+		case !source.ImportedExports[export.Nonce].IsZero():
+			err = ErrExportUsed
+		default:
+			recordImport(source, export, now.Add(-s.config.ExportTTL))
+			if player.ID != sourceID {
+				s.resetInPlace(source, now)
+				source.ExportsVoidBefore = now
+			}
+		}
+		if player.ID == sourceID {
+			// The player replaces the one it was exported from, so it takes over the record of imports
+			player.ImportedExports = source.ImportedExports
+			player.ExportsVoidBefore = source.ExportsVoidBefore
+		}
+		// Importing over an existing player keeps that player's token working
+		if existing, exists := s.gameState.Players[player.ID]; exists {
+			player.TokenHash = existing.TokenHash
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.gameState.SetPlayer(player)
+	s.gameState.Update(s.rebuildGuilds) // The imported player may carry, or drop, a guild membership
+
+	s.mutex.Lock()
+	for _, client := range s.clients {
+		if client.player.ID == player.ID {
+			client.player = player
+		}
+	}
+	s.mutex.Unlock()
+	if player.ID != sourceID {
+		s.SendToPlayer(sourceID, s.MarshalPlayerMessage("gameState", source, map[string]interface{}{"reset": true}))
+	}
+
+	s.logger.Info("imported player", "event", "import", "player_id", player.ID,
+		"exported_id", export.Player.ID, "exported_at", export.ExportedAt.Format(time.RFC3339))
+	return player, nil
+}
+
+// recordImport records on the exported player that the export was imported,
+// forgetting imports of exports taken before expired, which can no longer be
+// imported anyway. The caller holds the game-state write lock.
+func recordImport(source *models.Player, export models.PlayerExport, expired time.Time) {
+	for nonce, exportedAt := range source.ImportedExports {
+		if exportedAt.Before(expired) {
+			delete(source.ImportedExports, nonce)
+		}
+	}
+	if source.ImportedExports == nil {
+		source.ImportedExports = make(map[string]time.Time)
+	}
+	source.ImportedExports[export.Nonce] = export.ExportedAt
+}
+
+// signExport returns the hex-encoded signature for encoded export data.
+func (s *Server) signExport(data []byte) string {
+	return hex.EncodeToString(s.exportMAC(data))
+}
+
+// exportMAC computes the HMAC-SHA256 of encoded export data with the export secret.
+func (s *Server) exportMAC(data []byte) []byte {
+	mac := hmac.New(sha256.New, s.exportSecret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,183 @@
+package game_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game/testutil"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// exportOf exports the player with the given ID.
+func exportOf(t *testing.T, h *testutil.Harness, playerID string) *models.SignedExport {
+	t.Helper()
+	player, exists := h.Server.GetPlayer(playerID)
+	if !exists {
+		t.Fatalf("player %q not found", playerID)
+	}
+	export, err := h.Server.ExportPlayer(player)
+	if err != nil {
+		t.Fatalf("export %q: %v", playerID, err)
+	}
+	return export
+}
+
+func TestImportPlayerMovesProgress(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	h.Connect("alice")
+	h.Advance(10)
+	want := h.Player("alice").Progress.DungeonLevel
+
+	imported, err := h.Server.ImportPlayer(exportOf(t, h, "alice"), "alice-laptop")
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if imported.ID != "alice-laptop" || h.Player("alice-laptop").Progress.DungeonLevel != want {
+		t.Errorf("imported player %q on level %d, want alice-laptop on level %d",
+			imported.ID, h.Player("alice-laptop").Progress.DungeonLevel, want)
+	}
+	if alice := h.Player("alice"); alice.Progress.DungeonLevel != 1 || alice.Progress.TotalBattles != 0 {
+		t.Errorf("exported player kept level %d and %d battles after moving, want a fresh start",
+			alice.Progress.DungeonLevel, alice.Progress.TotalBattles)
+	}
+}
+
+func TestImportPlayerLeavesOneCopy(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	h.Connect("alice")
+	h.Advance(10)
+	want := h.Player("alice").Progress.TotalBattles
+	first, second := exportOf(t, h, "alice"), exportOf(t, h, "alice")
+
+	h.Clock.Advance(time.Second)
+	if _, err := h.Server.ImportPlayer(first, "alt-1"); err != nil {
+		t.Fatalf("first import: %v", err)
+	}
+	if _, err := h.Server.ImportPlayer(second, "alt-2"); !errors.Is(err, game.ErrExportExpired) {
+		t.Errorf("import of a second export into another ID: %v, want ErrExportExpired", err)
+	}
+
+	// Exporting the player left behind only carries its fresh start
+	h.Clock.Advance(time.Second)
+	if _, err := h.Server.ImportPlayer(exportOf(t, h, "alice"), "alt-3"); err != nil {
+		t.Fatalf("import of a new export: %v", err)
+	}
+	copies := 0
+	for _, id := range []string{"alice", "alt-1", "alt-2", "alt-3"} {
+		if player, exists := h.Server.GetPlayer(id); exists && player.Progress.TotalBattles == want {
+			copies++
+		}
+	}
+	if copies != 1 {
+		t.Errorf("%d players hold the exported progress, want 1", copies)
+	}
+}
+
+func TestImportPlayerRejectsTampering(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	h.Server.GetOrCreatePlayer("alice")
+	export := exportOf(t, h, "alice")
+	export.Data = append([]byte{' '}, export.Data...)
+
+	if _, err := h.Server.ImportPlayer(export, "mallory"); !errors.Is(err, game.ErrInvalidSignature) {
+		t.Errorf("import of a tampered export: %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestImportPlayerOnlyOnce(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	h.Server.GetOrCreatePlayer("alice")
+	export := exportOf(t, h, "alice")
+
+	if _, err := h.Server.ImportPlayer(export, "clone-1"); err != nil {
+		t.Fatalf("first import: %v", err)
+	}
+	for _, target := range []string{"clone-2", "clone-1", "alice", ""} {
+		if _, err := h.Server.ImportPlayer(export, target); !errors.Is(err, game.ErrExportUsed) {
+			t.Errorf("import again under %q: %v, want ErrExportUsed", target, err)
+		}
+	}
+	if _, exists := h.Server.GetPlayer("clone-2"); exists {
+		t.Error("a rejected import created a player")
+	}
+
+	// A fresh export still imports, and the record survives importing over the exported player
+	if _, err := h.Server.ImportPlayer(exportOf(t, h, "alice"), "alice"); err != nil {
+		t.Fatalf("import of a new export: %v", err)
+	}
+	if _, err := h.Server.ImportPlayer(export, "clone-3"); !errors.Is(err, game.ErrExportUsed) {
+		t.Errorf("import after the player was replaced: %v, want ErrExportUsed", err)
+	}
+
+	// Nor does resetting forget it
+	alice, _ := h.Server.GetPlayer("alice")
+	h.Server.ResetPlayer(alice)
+	if _, err := h.Server.ImportPlayer(export, "clone-4"); !errors.Is(err, game.ErrExportUsed) {
+		t.Errorf("import after a reset: %v, want ErrExportUsed", err)
+	}
+}
+
+func TestImportPlayerExpires(t *testing.T) {
+	config := game.DefaultConfig()
+	config.ExportTTL = time.Hour
+	h := testutil.New(t, config)
+	h.Server.GetOrCreatePlayer("alice")
+
+	fresh := exportOf(t, h, "alice")
+	stale := exportOf(t, h, "alice")
+	h.Clock.Advance(59 * time.Minute)
+	if _, err := h.Server.ImportPlayer(fresh, "alice-2"); err != nil {
+		t.Errorf("import within the TTL: %v", err)
+	}
+	h.Clock.Advance(2 * time.Minute)
+	if _, err := h.Server.ImportPlayer(stale, "alice-3"); !errors.Is(err, game.ErrExportExpired) {
+		t.Errorf("import after the TTL: %v, want ErrExportExpired", err)
+	}
+}
+
+func TestImportPlayerCannotUndoSeasonReset(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	h.Connect("alice")
+	h.Advance(10)
+	export := exportOf(t, h, "alice")
+
+	h.Clock.Advance(time.Second)
+	if _, err := h.Server.ResetSeason(); err != nil {
+		t.Fatalf("reset season: %v", err)
+	}
+	if _, err := h.Server.ImportPlayer(export, ""); !errors.Is(err, game.ErrExportExpired) {
+		t.Errorf("import of an export from before the season reset: %v, want ErrExportExpired", err)
+	}
+	if _, err := h.Server.ImportPlayer(exportOf(t, h, "alice"), ""); err != nil {
+		t.Errorf("import of an export from after the season reset: %v", err)
+	}
+}
+
+func TestImportPlayerCannotUndoGoldDeduction(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	h.Server.GetOrCreatePlayer("alice")
+	beforeGrant := exportOf(t, h, "alice")
+	beforeDeduction := exportOf(t, h, "alice")
+
+	h.Clock.Advance(time.Second)
+	if _, err := h.Server.GrantGold("alice", 500); err != nil {
+		t.Fatalf("grant: %v", err)
+	}
+	if _, err := h.Server.ImportPlayer(beforeGrant, "alice-2"); err != nil {
+		t.Errorf("import of an export from before a gold grant: %v", err)
+	}
+
+	h.Clock.Advance(time.Second)
+	before := h.Player("alice").Progress.Gold
+	if _, err := h.Server.GrantGold("alice", -500); err != nil {
+		t.Fatalf("deduct: %v", err)
+	}
+	if _, err := h.Server.ImportPlayer(beforeDeduction, ""); !errors.Is(err, game.ErrExportExpired) {
+		t.Errorf("import of an export from before a gold deduction: %v, want ErrExportExpired", err)
+	}
+	if gold := h.Player("alice").Progress.Gold; gold != max(0, before-500) {
+		t.Errorf("gold after the rejected import = %d, want the deducted balance", gold)
+	}
+}
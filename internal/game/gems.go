@@ -0,0 +1,35 @@
+package game
+
+import (
+	"fmt"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// bossGems returns the gems for beating the boss on the given dungeon level:
+// one for every BossInterval levels, so deeper bosses pay more.
+func (s *Server) bossGems(dungeonLevel int) int {
+	return max(1, dungeonLevel/s.config.BossInterval)
+}
+
+// BuyGemUpgrade spends the player's gems on one more permanent gem upgrade,
+// which multiplies every hero stat but crit chance and survives prestige.
+// It returns models.ErrNotEnoughGems when the player cannot afford it. The
+// purchase is recorded in the player's activity feed.
+func (s *Server) BuyGemUpgrade(player *models.Player) error {
+	var err error
+	s.gameState.Update(func() {
+		if err = player.BuyGemUpgrade(); err != nil {
+			return
+		}
+		player.AddEvent(models.Event{
+			Type:    models.EventGemUpgrade,
+			Message: fmt.Sprintf("Bought gem upgrade %d (%.2fx hero stats)", player.GemUpgrades, player.GemMultiplier()),
+			Time:    s.clock.Now(),
+		})
+	})
+	if err == nil {
+		s.requestUpdates()
+	}
+	return err
+}
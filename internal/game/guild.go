@@ -0,0 +1,217 @@
+package game
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// Each active guild member beyond the first raises every member's hero stats
+// by guildBonusPerMember, up to a multiplier of maxGuildBonus. A member is
+// active when they have been seen within guildActiveWindow, so a guild cannot
+// be padded with accounts that never play.
+const (
+	guildBonusPerMember = 0.02
+	maxGuildBonus       = 1.5
+	guildActiveWindow   = 72 * time.Hour
+)
+
+var (
+	// ErrGuildExists is returned when creating a guild whose name is already taken.
+	ErrGuildExists = errors.New("guild already exists")
+	// ErrUnknownGuild is returned when a guild name does not match any guild.
+	ErrUnknownGuild = errors.New("unknown guild")
+	// ErrAlreadyInGuild is returned when a player who belongs to a guild tries to create or join one.
+	ErrAlreadyInGuild = errors.New("already in a guild")
+	// ErrNotInGuild is returned when a player who belongs to no guild tries to leave one.
+	ErrNotInGuild = errors.New("not in a guild")
+)
+
+// CreateGuild founds a new guild with the player as its only member. The name
+// follows the same rules as display names. A player must leave their current
+// guild before founding another.
+func (s *Server) CreateGuild(player *models.Player, name string) (*models.Guild, error) {
+	name, err := models.ValidateName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var guild *models.Guild
+	s.gameState.Update(func() {
+		switch {
+		case player.Guild != "":
+			err = ErrAlreadyInGuild
+		case s.guilds[name] != nil:
+			err = ErrGuildExists
+		default:
+			s.addGuildMember(player, name)
+			guild = s.guild(name)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("guild created", "event", "guild_create", "player_id", player.ID, "guild", name)
+	s.requestUpdates()
+	return guild, nil
+}
+
+// JoinGuild adds the player to an existing guild by name. A player must leave
+// their current guild before joining another.
+func (s *Server) JoinGuild(player *models.Player, name string) (*models.Guild, error) {
+	var guild *models.Guild
+	var err error
+	s.gameState.Update(func() {
+		switch {
+		case player.Guild != "":
+			err = ErrAlreadyInGuild
+		case s.guilds[name] == nil:
+			err = ErrUnknownGuild
+		default:
+			s.addGuildMember(player, name)
+			guild = s.guild(name)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("guild joined", "event", "guild_join", "player_id", player.ID, "guild", name)
+	s.requestUpdates()
+	return guild, nil
+}
+
+// LeaveGuild removes the player from their guild. The last member to leave
+// disbands the guild, freeing its name.
+func (s *Server) LeaveGuild(player *models.Player) error {
+	var name string
+	var disbanded bool
+	s.gameState.Update(func() {
+		name = player.Guild
+		if name == "" {
+			return
+		}
+		disbanded = s.removeGuildMember(player)
+	})
+	if name == "" {
+		return ErrNotInGuild
+	}
+
+	s.logger.Info("guild left", "event", "guild_leave", "player_id", player.ID, "guild", name, "disbanded", disbanded)
+	s.requestUpdates()
+	return nil
+}
+
+// Guild returns the named guild with its members, contribution, and bonus.
+func (s *Server) Guild(name string) (*models.Guild, error) {
+	var guild *models.Guild
+	s.gameState.View(func() {
+		guild = s.guild(name)
+	})
+	if guild == nil {
+		return nil, ErrUnknownGuild
+	}
+	return guild, nil
+}
+
+// guild describes the named guild, or returns nil when there is none.
+// The caller holds the game-state lock.
+func (s *Server) guild(name string) *models.Guild {
+	members := s.guilds[name]
+	if members == nil {
+		return nil
+	}
+
+	active := s.activeGuildMembers(name, len(members))
+	guild := &models.Guild{
+		Name:          name,
+		Members:       make([]string, 0, len(members)),
+		ActiveMembers: active,
+		Bonus:         guildBonus(active),
+	}
+	for playerID := range members {
+		guild.Members = append(guild.Members, playerID)
+		if player, exists := s.gameState.Players[playerID]; exists {
+			guild.Contribution += player.Progress.BattlesWon
+		}
+	}
+	sort.Strings(guild.Members)
+	return guild
+}
+
+// addGuildMember puts the player in the named guild, creating it if needed.
+// The caller holds the game-state write lock.
+func (s *Server) addGuildMember(player *models.Player, name string) {
+	if s.guilds[name] == nil {
+		s.guilds[name] = make(map[string]struct{})
+	}
+	s.guilds[name][player.ID] = struct{}{}
+	player.Guild = name
+}
+
+// removeGuildMember takes the player out of their guild and reports whether
+// that left the guild empty, in which case it is deleted.
+// The caller holds the game-state write lock.
+func (s *Server) removeGuildMember(player *models.Player) bool {
+	name := player.Guild
+	player.Guild = ""
+	delete(s.guilds[name], player.ID)
+	if len(s.guilds[name]) > 0 {
+		return false
+	}
+	delete(s.guilds, name)
+	return true
+}
+
+// rebuildGuilds recreates the guild index from the membership stored on each
+// player, after players have been loaded or replaced wholesale.
+// The caller holds the game-state write lock.
+func (s *Server) rebuildGuilds() {
+	s.guilds = make(map[string]map[string]struct{})
+	for _, player := range s.gameState.Players {
+		if player.Guild != "" {
+			s.addGuildMember(player, player.Guild)
+		}
+	}
+}
+
+// guildMultiplier returns the hero stat multiplier the player's guild grants,
+// or 1.0 outside a guild. The caller holds the game-state lock.
+func (s *Server) guildMultiplier(player *models.Player) float64 {
+	if player.Guild == "" {
+		return 1.0
+	}
+	// Members beyond those earning the maximum bonus need not be counted
+	maxed := int(math.Ceil((maxGuildBonus-1)/guildBonusPerMember)) + 1
+	return guildBonus(s.activeGuildMembers(player.Guild, maxed))
+}
+
+// activeGuildMembers counts the members of the named guild seen within
+// guildActiveWindow, stopping once it reaches limit. Members evicted from
+// memory have not been seen for at least EvictAfter and are not counted.
+// The caller holds the game-state lock.
+func (s *Server) activeGuildMembers(name string, limit int) int {
+	cutoff := s.clock.Now().Add(-guildActiveWindow)
+	active := 0
+	for playerID := range s.guilds[name] {
+		if active >= limit {
+			break
+		}
+		if player, exists := s.gameState.Players[playerID]; exists && player.LastSeen.After(cutoff) {
+			active++
+		}
+	}
+	return active
+}
+
+// guildBonus returns the hero stat multiplier for a guild with the given number of active members.
+func guildBonus(members int) float64 {
+	if members <= 1 {
+		return 1.0
+	}
+	return min(maxGuildBonus, 1.0+guildBonusPerMember*float64(members-1))
+}
@@ -0,0 +1,56 @@
+package game_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game/testutil"
+)
+
+func TestGuildBonusCountsOnlyActiveMembers(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	founder, _ := h.Server.GetOrCreatePlayer("founder")
+	if _, err := h.Server.CreateGuild(founder, "Delvers"); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"idle1", "idle2", "regular"} {
+		member, _ := h.Server.GetOrCreatePlayer(id)
+		if _, err := h.Server.JoinGuild(member, "Delvers"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bonus := func(wantActive int, want float64) {
+		t.Helper()
+		guild, err := h.Server.Guild("Delvers")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if guild.ActiveMembers != wantActive || math.Abs(guild.Bonus-want) > 1e-9 {
+			t.Errorf("%d of %d members active with bonus %g, want %d active with bonus %g",
+				guild.ActiveMembers, len(guild.Members), guild.Bonus, wantActive, want)
+		}
+	}
+	bonus(4, 1.06)
+
+	// Only the founder and one member keep playing
+	h.Clock.Advance(96 * time.Hour)
+	h.Server.GetOrCreatePlayer("founder")
+	regular, _ := h.Server.GetOrCreatePlayer("regular")
+	bonus(2, 1.02)
+
+	// The stats of playing members only get the active members' bonus
+	withIdle := h.Server.Hero(regular).HP
+	idle, _ := h.Server.GetPlayer("idle1")
+	if err := h.Server.LeaveGuild(idle); err != nil {
+		t.Fatal(err)
+	}
+	if hp := h.Server.Hero(regular).HP; hp != withIdle {
+		t.Errorf("hero HP went from %d to %d when an idle member left", withIdle, hp)
+	}
+
+	// An idle member who returns counts again
+	h.Server.GetOrCreatePlayer("idle2")
+	bonus(3, 1.04)
+}
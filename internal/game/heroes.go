@@ -0,0 +1,44 @@
+package game
+
+import (
+	"errors"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// ErrMaxHeroes is returned when a player who already fields MaxHeroes tries to unlock another.
+var ErrMaxHeroes = errors.New("all hero slots unlocked")
+
+// UpgradeHeroSlot is the upgrade type that unlocks another hero instead of
+// upgrading a station. It is accepted wherever a station type is.
+const UpgradeHeroSlot = "heroSlot"
+
+// heroSlotCosts holds the gold cost of unlocking the second and third heroes.
+var heroSlotCosts = []int{5000, 50000}
+
+// heroSlotCost returns the cost of the player's next hero slot, or false when
+// they already field MaxHeroes.
+func heroSlotCost(player *models.Player) (int, bool) {
+	next := len(player.Heroes)
+	if next >= len(heroSlotCosts) || player.HeroCount() >= models.MaxHeroes {
+		return 0, false
+	}
+	return heroSlotCosts[next], true
+}
+
+// unlockHeroSlot buys the player's next hero slot if they can afford it. The
+// new hero starts at dungeon level 1. The caller holds the game-state write lock.
+func (s *Server) unlockHeroSlot(player *models.Player) error {
+	cost, ok := heroSlotCost(player)
+	if !ok {
+		return ErrMaxHeroes
+	}
+	if player.Progress.Gold < cost {
+		return ErrInsufficientGold
+	}
+
+	player.Progress.Gold -= cost
+	player.Heroes = append(player.Heroes, &models.HeroSlot{DungeonLevel: 1})
+	s.metrics.upgrades.WithLabelValues(UpgradeHeroSlot).Inc()
+	return nil
+}
@@ -0,0 +1,24 @@
+package game
+
+import "github.com/evevioletrose-hash/idle-dungeon/internal/models"
+
+// goldPerTick returns the passive gold the player earns every tick from
+// their loot station's multiplier; see Config.GoldPerTick.
+// The caller holds the game-state lock.
+func (s *Server) goldPerTick(player *models.Player) int {
+	loot := 1.0
+	if station := player.Factory.Station(models.StationLoot); station != nil {
+		loot = station.Multiplier
+	}
+	return s.config.GoldPerTick(loot)
+}
+
+// applyPassiveIncome pays the player one tick of passive gold, apart from
+// any battle rewards, and records the rate paid as Progress.GoldPerTick, so
+// the player JSON shows the rate as of the latest tick.
+// The caller holds the game-state write lock.
+func (s *Server) applyPassiveIncome(player *models.Player) {
+	income := s.goldPerTick(player)
+	player.Progress.GoldPerTick = income
+	player.Progress.Gold += income
+}
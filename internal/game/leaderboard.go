@@ -0,0 +1,63 @@
+package game
+
+import (
+	"sort"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// Leaderboard returns the top players ranked by the deepest dungeon level they
+// have ever reached, so prestiging does not cost a player their rank, with ties broken
+// by experience and then by ID so the order is stable. Entries are built from
+// SnapshotPlayers, so sorting never touches live player data.
+func (s *Server) Leaderboard(limit int) []models.LeaderboardEntry {
+	players := s.gameState.SnapshotPlayers()
+
+	type ranked struct {
+		entry      models.LeaderboardEntry
+		experience int
+	}
+	rows := make([]ranked, 0, len(players))
+	for _, player := range players {
+		rows = append(rows, ranked{
+			entry: models.LeaderboardEntry{
+				ID:              player.ID,
+				Name:            s.displayName(player.Name),
+				DungeonLevel:    player.Progress.DungeonLevel,
+				MaxDungeonLevel: player.Progress.MaxDungeonLevel,
+				Gold:            player.Progress.Gold,
+
+				TotalBattles:    player.Progress.TotalBattles,
+				BattlesWon:      player.Progress.BattlesWon,
+				PlaytimeSeconds: player.Progress.PlaytimeSeconds,
+			},
+			experience: player.Progress.Experience,
+		})
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.entry.MaxDungeonLevel != b.entry.MaxDungeonLevel {
+			return a.entry.MaxDungeonLevel > b.entry.MaxDungeonLevel
+		}
+		if a.experience != b.experience {
+			return a.experience > b.experience
+		}
+		return a.entry.ID < b.entry.ID
+	})
+
+	if len(rows) > limit {
+		rows = rows[:limit]
+	}
+	entries := make([]models.LeaderboardEntry, len(rows))
+	for i, row := range rows {
+		entries[i] = row.entry
+	}
+	return entries
+}
+
+// displayName returns a player's name as shown to other players: sanitized,
+// in case a stored name predates validation, and cut to DisplayNameLength.
+func (s *Server) displayName(name string) string {
+	return models.TruncateName(models.SanitizeName(name), s.config.DisplayNameLength)
+}
@@ -0,0 +1,46 @@
+package game_test
+
+import (
+	"testing"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game/testutil"
+)
+
+func TestOtherPlayersSeeDisplayNames(t *testing.T) {
+	config := game.DefaultConfig()
+	config.DisplayNameLength = 8
+	h := testutil.New(t, config)
+	player, _ := h.Server.GetOrCreatePlayer("alice")
+	if err := h.Server.SetName(player, "Alexandria"); err != nil {
+		t.Fatalf("set name: %v", err)
+	}
+	bob, _ := h.Server.GetOrCreatePlayer("bob")
+	h.Server.View(func() { bob.Name = "Bob\x1b[2J" }) // As a save from before names were validated might hold
+
+	names := map[string]string{}
+	for _, entry := range h.Server.Leaderboard(10) {
+		names[entry.ID] = entry.Name
+	}
+	if names["alice"] != "Alexand…" || names["bob"] != "Bob[2J" {
+		t.Errorf("leaderboard names = %v, want alice cut to 8 characters and bob sanitized", names)
+	}
+	if results := h.Server.SearchPlayers("alex", 10); len(results) != 1 || results[0].Name != "Alexand…" {
+		t.Errorf("search results = %+v, want alice's display name", results)
+	}
+	if profile := h.Server.Players([]string{"alice"})["alice"]; profile.Name != "Alexand…" {
+		t.Errorf("profile name = %q, want alice's display name", profile.Name)
+	}
+
+	if _, err := h.Server.ResetSeason(); err != nil {
+		t.Fatalf("reset season: %v", err)
+	}
+	for _, entry := range h.Server.HallOfFame()[0].Top {
+		if entry.Name != names[entry.ID] {
+			t.Errorf("hall of fame shows %s as %q, want %q", entry.ID, entry.Name, names[entry.ID])
+		}
+	}
+	if name := h.Player("alice").Name; name != "Alexandria" {
+		t.Errorf("alice's own name is %q, want it whole", name)
+	}
+}
@@ -0,0 +1,59 @@
+package game
+
+import (
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// Daily login bonuses grow by dailyBonusGold for each consecutive day, up to
+// maxBonusStreak days; longer streaks keep paying the largest bonus.
+const (
+	dailyBonusGold = 100
+	maxBonusStreak = 7
+)
+
+// loginDayLayout formats the calendar day stored as a player's LastLoginDay.
+const loginDayLayout = "2006-01-02"
+
+// loginBonus returns the gold granted on the given day of a login streak.
+func loginBonus(streak int) int {
+	return dailyBonusGold * min(streak, maxBonusStreak)
+}
+
+// applyDailyLogin records that the player logged in at now and, on their first
+// login of a calendar day in their time zone, extends or restarts their streak
+// and grants the day's bonus. The streak continues when the previous counted
+// day was the day before and restarts at 1 after a missed day. It returns the
+// gold granted, which is zero for further logins on the same day.
+//
+// The start of each counted day is recorded as an instant, and a day only
+// counts once models.MinDayLength has passed since the previous one began,
+// so changing time zones cannot earn a second bonus in one day; the streak
+// only breaks when a whole day was skipped. The caller holds the game-state
+// write lock.
+func (s *Server) applyDailyLogin(player *models.Player, now time.Time) int {
+	start := models.DayStart(now, player.Location())
+	previous := player.LoginDayStart
+	if previous.IsZero() && player.LastLoginDay != "" {
+		// Players saved before day starts were recorded logged in on UTC days
+		previous, _ = time.Parse(loginDayLayout, player.LastLoginDay)
+	}
+	if !models.BeginsNewDay(previous, start) {
+		return 0
+	}
+
+	if !previous.IsZero() && start.Before(previous.Add(2*models.MinDayLength)) {
+		player.LoginStreak++
+	} else {
+		player.LoginStreak = 1
+	}
+	player.LastLoginDay = start.Format(loginDayLayout)
+	player.LoginDayStart = start
+	player.NextLoginBonus = loginBonus(player.LoginStreak + 1)
+
+	bonus := loginBonus(player.LoginStreak)
+	player.Progress.Gold += bonus
+	s.completeReservations(player)
+	return bonus
+}
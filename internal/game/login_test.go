@@ -0,0 +1,147 @@
+package game_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game/testutil"
+)
+
+// newLoginHarness returns a harness whose players earn no gold while away,
+// so their gold only changes by login bonuses.
+func newLoginHarness(t *testing.T) *testutil.Harness {
+	config := game.DefaultConfig()
+	config.MaxOfflineDuration = 0
+	return testutil.New(t, config)
+}
+
+// login sets the harness clock to at, logs the player in, and returns the
+// login bonus granted and the player's streak afterwards.
+func login(t *testing.T, h *testutil.Harness, playerID string, at time.Time) (bonus, streak int) {
+	t.Helper()
+	h.Clock.Set(at)
+	before := 0
+	if _, exists := h.Server.GetPlayer(playerID); exists {
+		before = h.Player(playerID).Progress.Gold
+	}
+	h.Server.GetOrCreatePlayer(playerID)
+	player := h.Player(playerID)
+	if before == 0 {
+		before = game.DefaultConfig().StartingGold
+	}
+	return player.Progress.Gold - before, player.LoginStreak
+}
+
+// loginStep is one login of a scenario and what it should grant.
+type loginStep struct {
+	at     time.Time // When the player logs in
+	bonus  int       // Login bonus it should grant
+	streak int       // Streak the player should have afterwards
+}
+
+// runLogins logs the player in at each step, checking the bonus and streak.
+func runLogins(t *testing.T, h *testutil.Harness, playerID string, steps []loginStep) {
+	t.Helper()
+	for i, step := range steps {
+		bonus, streak := login(t, h, playerID, step.at)
+		if bonus != step.bonus || streak != step.streak {
+			t.Errorf("login %d at %s: bonus %d, streak %d; want bonus %d, streak %d",
+				i, step.at.UTC().Format(time.RFC3339), bonus, streak, step.bonus, step.streak)
+		}
+	}
+}
+
+func TestDailyLoginUTC(t *testing.T) {
+	h := newLoginHarness(t)
+	day := func(d, hour int) time.Time { return time.Date(2024, time.January, d, hour, 0, 0, 0, time.UTC) }
+	runLogins(t, h, "alice", []loginStep{
+		{day(1, 10), 100, 1},
+		{day(1, 23), 0, 1}, // Same day
+		{day(2, 0), 200, 2},
+		{day(3, 12), 300, 3},
+		{day(5, 12), 100, 1}, // Missed the 4th
+	})
+}
+
+func TestDailyLoginFollowsPlayerTimeZone(t *testing.T) {
+	h := newLoginHarness(t)
+	la := mustLoadLocation(t, "America/Los_Angeles")
+	login(t, h, "alice", time.Date(2023, time.December, 30, 12, 0, 0, 0, time.UTC))
+	setTimeZone(t, h, "alice", "America/Los_Angeles")
+
+	runLogins(t, h, "alice", []loginStep{
+		{time.Date(2024, time.January, 2, 9, 0, 0, 0, la), 100, 1},
+		// Past midnight UTC, but still the 2nd in Los Angeles
+		{time.Date(2024, time.January, 2, 17, 0, 0, 0, la), 0, 1},
+		{time.Date(2024, time.January, 3, 0, 30, 0, 0, la), 200, 2},
+	})
+	if day := h.Player("alice").LastLoginDay; day != "2024-01-03" {
+		t.Errorf("lastLoginDay = %s, want the Los Angeles date 2024-01-03", day)
+	}
+}
+
+func TestDailyLoginAcrossDST(t *testing.T) {
+	h := newLoginHarness(t)
+	ny := mustLoadLocation(t, "America/New_York")
+	login(t, h, "alice", time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC))
+	setTimeZone(t, h, "alice", "America/New_York")
+
+	runLogins(t, h, "alice", []loginStep{
+		{time.Date(2024, time.March, 9, 0, 5, 0, 0, ny), 100, 1},
+		// March 10 is only 23 hours long
+		{time.Date(2024, time.March, 10, 0, 5, 0, 0, ny), 200, 2},
+		{time.Date(2024, time.March, 10, 23, 55, 0, 0, ny), 0, 2},
+		{time.Date(2024, time.March, 11, 0, 5, 0, 0, ny), 300, 3},
+		// November 3 is 25 hours long; its last hour is still the 3rd
+		{time.Date(2024, time.November, 2, 0, 5, 0, 0, ny), 100, 1},
+		{time.Date(2024, time.November, 3, 0, 5, 0, 0, ny), 200, 2},
+		{time.Date(2024, time.November, 3, 23, 55, 0, 0, ny), 0, 2},
+		{time.Date(2024, time.November, 4, 0, 5, 0, 0, ny), 300, 3},
+	})
+	if start := h.Player("alice").LoginDayStart; !start.Equal(time.Date(2024, time.November, 4, 0, 0, 0, 0, ny)) {
+		t.Errorf("loginDayStart = %s, want midnight of November 4 in New York", start)
+	}
+}
+
+func TestDailyLoginTimeZoneChangeGrantsNoExtraBonus(t *testing.T) {
+	h := newLoginHarness(t)
+	setTimeZoneAt := func(timeZone string) {
+		setTimeZone(t, h, "alice", timeZone)
+	}
+	at := func(hour int) time.Time { return time.Date(2024, time.January, 10, hour, 0, 0, 0, time.UTC) }
+
+	login(t, h, "alice", at(0).AddDate(0, 0, -2))
+	setTimeZoneAt("Pacific/Kiritimati") // UTC+14: the 11th began at 10:00 UTC on the 10th
+	if bonus, _ := login(t, h, "alice", at(11)); bonus == 0 {
+		t.Fatal("first login of a new day granted nothing")
+	}
+	for _, timeZone := range []string{"Etc/GMT+12", "UTC", "Asia/Kolkata", "Pacific/Kiritimati"} {
+		setTimeZoneAt(timeZone)
+		for hour := 11; hour < 24; hour++ {
+			if bonus, _ := login(t, h, "alice", at(hour)); bonus != 0 {
+				t.Errorf("login at %02d:00 UTC after switching to %s granted %d gold", hour, timeZone, bonus)
+			}
+		}
+	}
+
+	// Once a whole day has passed the streak carries on in the new time zone
+	setTimeZoneAt("Etc/GMT+12") // UTC-12: the 11th begins at 12:00 UTC on the 11th
+	if bonus, streak := login(t, h, "alice", at(12).AddDate(0, 0, 1)); bonus != 200 || streak != 2 {
+		t.Errorf("next day's login: bonus %d, streak %d; want 200, 2", bonus, streak)
+	}
+}
+
+func TestDailyLoginContinuesLegacyUTCStreak(t *testing.T) {
+	h := newLoginHarness(t)
+	login(t, h, "alice", time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC))
+	player, _ := h.Server.GetPlayer("alice")
+	h.Server.View(func() {
+		player.LoginDayStart = time.Time{} // As saved before day starts were recorded
+	})
+
+	runLogins(t, h, "alice", []loginStep{
+		{time.Date(2024, time.January, 1, 20, 0, 0, 0, time.UTC), 0, 1},
+		{time.Date(2024, time.January, 2, 8, 0, 0, 0, time.UTC), 200, 2},
+	})
+}
@@ -0,0 +1,174 @@
+package game
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// Item drop chance on victory: baseDropChance per point of hero loot, growing
+// by dropLevelScaling for each dungeon level and capped at maxDropChance.
+const (
+	baseDropChance   = 0.02
+	dropLevelScaling = 0.02
+	maxDropChance    = 0.25
+)
+
+// defaultZoneSize is how many dungeon levels make up a zone when
+// Config.ZoneSize is not set.
+const defaultZoneSize = 10
+
+// itemRarity describes one entry of itemRarities.
+type itemRarity struct {
+	rarity models.Rarity
+	chance float64
+	title  string
+	factor int
+}
+
+// itemRarities lists the rarities in the order they are rolled, with the
+// chance of each, its name prefix, and how much it scales the item's bonus.
+var itemRarities = []itemRarity{
+	{models.RarityLegendary, 0.01, "Legendary", 8},
+	{models.RarityEpic, 0.07, "Epic", 4},
+	{models.RarityRare, 0.22, "Rare", 2},
+	{models.RarityCommon, 1, "Common", 1},
+}
+
+// itemKind describes one entry of itemKinds.
+type itemKind struct {
+	stat  models.StationType
+	noun  string
+	scale int
+}
+
+// itemKinds lists the stats an item can boost, the item name for each, and
+// how many stat points a single bonus point is worth for it.
+var itemKinds = []itemKind{
+	{models.StationHP, "Amulet", 5},
+	{models.StationArmor, "Shield", 1},
+	{models.StationAttack, "Sword", 1},
+}
+
+// dropChance returns the chance that a victory drops an item.
+func dropChance(loot, dungeonLevel int) float64 {
+	chance := baseDropChance * float64(loot) * (1 + dropLevelScaling*float64(dungeonLevel))
+	return min(chance, maxDropChance)
+}
+
+// rollItemDrop decides whether a victory on the given dungeon level drops an
+// item and generates it. Deeper levels drop stronger items. It returns nil
+// when nothing drops.
+func rollItemDrop(rng *rand.Rand, loot, dungeonLevel int) *models.Item {
+	if rng.Float64() >= dropChance(loot, dungeonLevel) {
+		return nil
+	}
+
+	roll := rng.Float64()
+	rarity := itemRarities[len(itemRarities)-1]
+	for _, candidate := range itemRarities {
+		if roll < candidate.chance {
+			rarity = candidate
+			break
+		}
+		roll -= candidate.chance
+	}
+
+	return newItem(rng, rarity.rarity, itemKinds[rng.IntN(len(itemKinds))].stat, dungeonLevel)
+}
+
+// LootEntry is one drop a zone's loot table offers: an item of Rarity
+// boosting Stat, picked with a chance proportional to Weight.
+type LootEntry struct {
+	Stat   models.StationType `json:"stat"`
+	Rarity models.Rarity      `json:"rarity"`
+	Weight float64            `json:"weight"`
+}
+
+// rollZoneItemDrop decides like rollItemDrop whether a victory drops an item,
+// but picks the item's stat and rarity from the zone's loot table by weight.
+// An empty table never drops anything.
+func rollZoneItemDrop(rng *rand.Rand, loot, dungeonLevel int, table []LootEntry) *models.Item {
+	if len(table) == 0 || rng.Float64() >= dropChance(loot, dungeonLevel) {
+		return nil
+	}
+
+	total := 0.0
+	for _, entry := range table {
+		total += entry.Weight
+	}
+	roll := rng.Float64() * total
+	entry := table[len(table)-1]
+	for _, candidate := range table {
+		if roll < candidate.Weight {
+			entry = candidate
+			break
+		}
+		roll -= candidate.Weight
+	}
+	return newItem(rng, entry.Rarity, entry.Stat, dungeonLevel)
+}
+
+// newItem generates an item of the given rarity and stat found on the given
+// dungeon level. Deeper levels and rarer items give bigger bonuses.
+func newItem(rng *rand.Rand, rarity models.Rarity, stat models.StationType, dungeonLevel int) *models.Item {
+	grade := itemRarities[len(itemRarities)-1]
+	for _, candidate := range itemRarities {
+		if candidate.rarity == rarity {
+			grade = candidate
+		}
+	}
+	kind := itemKinds[0]
+	for _, candidate := range itemKinds {
+		if candidate.stat == stat {
+			kind = candidate
+		}
+	}
+
+	return &models.Item{
+		ID:     strconv.FormatUint(rng.Uint64(), 36),
+		Name:   grade.title + " " + kind.noun,
+		Rarity: grade.rarity,
+		Stat:   kind.stat,
+		Bonus:  (1 + dungeonLevel/5) * grade.factor * kind.scale,
+	}
+}
+
+// validateLootTables checks that every zone number is positive and every
+// entry names an item stat and rarity with a positive, finite weight.
+func validateLootTables(tables map[int][]LootEntry) error {
+	for zone, table := range tables {
+		if zone < 1 {
+			return fmt.Errorf("zone %d: zones are numbered from 1", zone)
+		}
+		for i, entry := range table {
+			switch {
+			case !slices.ContainsFunc(itemKinds, func(kind itemKind) bool { return kind.stat == entry.Stat }):
+				return fmt.Errorf("zone %d entry %d: no items boost %q", zone, i, entry.Stat)
+			case !slices.ContainsFunc(itemRarities, func(grade itemRarity) bool { return grade.rarity == entry.Rarity }):
+				return fmt.Errorf("zone %d entry %d: unknown rarity %q", zone, i, entry.Rarity)
+			case !(entry.Weight > 0) || math.IsInf(entry.Weight, 1):
+				return fmt.Errorf("zone %d entry %d: weight must be positive", zone, i)
+			}
+		}
+	}
+	return nil
+}
+
+// dropItem rolls a victory's item drop from the loot table of the zone the
+// dungeon level belongs to, or at the default odds when no tables are set.
+func (s *Server) dropItem(rng *rand.Rand, loot, dungeonLevel int) *models.Item {
+	if s.config.LootTables == nil {
+		return rollItemDrop(rng, loot, dungeonLevel)
+	}
+	return rollZoneItemDrop(rng, loot, dungeonLevel, s.config.LootTables[s.config.Zone(dungeonLevel)])
+}
+
+// rollBuffDrop picks the buff item every defeated boss drops.
+func rollBuffDrop(rng *rand.Rand) models.BuffType {
+	return models.BuffTypes[rng.IntN(len(models.BuffTypes))]
+}
@@ -0,0 +1,109 @@
+package game_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game/testutil"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+func TestConfigZone(t *testing.T) {
+	config := game.DefaultConfig()
+	for level, want := range map[int]int{0: 1, 1: 1, 10: 1, 11: 2, 20: 2, 21: 3} {
+		if zone := config.Zone(level); zone != want {
+			t.Errorf("level %d is in zone %d, want %d", level, zone, want)
+		}
+	}
+	config.ZoneSize = 3
+	if zone := config.Zone(7); zone != 3 {
+		t.Errorf("level 7 is in zone %d with zones of 3, want 3", zone)
+	}
+}
+
+func TestLootTablesDropFromTheCurrentZone(t *testing.T) {
+	config := game.DefaultConfig()
+	config.LootTables = map[int][]game.LootEntry{
+		1: {
+			{Stat: models.StationAttack, Rarity: models.RarityCommon, Weight: 3},
+			{Stat: models.StationHP, Rarity: models.RarityRare, Weight: 1},
+		},
+		2: {{Stat: models.StationArmor, Rarity: models.RarityEpic, Weight: 1}},
+	}
+	h := testutil.New(t, config)
+	// Strong enough to win every battle, with the drop chance at its cap
+	hero := &models.Hero{HP: 1_000_000, Armor: 1000, Attack: 1_000_000, Loot: 100}
+
+	drops := func(level int) map[models.Rarity]map[models.StationType]int {
+		found := make(map[models.Rarity]map[models.StationType]int)
+		for seed := int64(1); seed <= 4000; seed++ {
+			result, _ := h.Server.ReplayBattle(hero, level, models.DifficultyNormal, seed)
+			if !result.Victory {
+				t.Fatalf("hero lost on level %d", level)
+			}
+			if item := result.Item; item != nil {
+				if found[item.Rarity] == nil {
+					found[item.Rarity] = make(map[models.StationType]int)
+				}
+				found[item.Rarity][item.Stat]++
+			}
+		}
+		return found
+	}
+
+	zone1 := drops(5)
+	commons, rares := zone1[models.RarityCommon][models.StationAttack], zone1[models.RarityRare][models.StationHP]
+	if len(zone1) != 2 || len(zone1[models.RarityCommon]) != 1 || len(zone1[models.RarityRare]) != 1 {
+		t.Fatalf("zone 1 dropped %v, want only common attack and rare hp items", zone1)
+	}
+	if share := float64(commons) / float64(commons+rares); math.Abs(share-0.75) > 0.05 {
+		t.Errorf("common attack items were %.2f of %d zone 1 drops, want about 0.75", share, commons+rares)
+	}
+
+	zone2 := drops(15)
+	if len(zone2) != 1 || len(zone2[models.RarityEpic]) != 1 || zone2[models.RarityEpic][models.StationArmor] == 0 {
+		t.Errorf("zone 2 dropped %v, want only epic armor items", zone2)
+	}
+
+	if zone3 := drops(25); len(zone3) != 0 {
+		t.Errorf("zone 3 has no loot table but dropped %v", zone3)
+	}
+}
+
+func TestDefaultLootDropsEveryStat(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	hero := &models.Hero{HP: 1_000_000, Armor: 1000, Attack: 1_000_000, Loot: 100}
+
+	stats := make(map[models.StationType]bool)
+	for seed := int64(1); seed <= 500; seed++ {
+		if result, _ := h.Server.ReplayBattle(hero, 25, models.DifficultyNormal, seed); result.Item != nil {
+			stats[result.Item.Stat] = true
+		}
+	}
+	if len(stats) != 3 {
+		t.Errorf("default loot dropped items for %v, want hp, armor, and attack", stats)
+	}
+}
+
+func TestNewServerRejectsInvalidLootTables(t *testing.T) {
+	for name, table := range map[string][]game.LootEntry{
+		"unknown stat":    {{Stat: models.StationLoot, Rarity: models.RarityCommon, Weight: 1}},
+		"unknown rarity":  {{Stat: models.StationHP, Rarity: "mythic", Weight: 1}},
+		"zero weight":     {{Stat: models.StationHP, Rarity: models.RarityCommon, Weight: 0}},
+		"NaN weight":      {{Stat: models.StationHP, Rarity: models.RarityCommon, Weight: math.NaN()}},
+		"infinite weight": {{Stat: models.StationHP, Rarity: models.RarityCommon, Weight: math.Inf(1)}},
+	} {
+		config := game.DefaultConfig()
+		config.LootTables = map[int][]game.LootEntry{1: table}
+		if _, err := game.NewServer(config, nil); err == nil {
+			t.Errorf("NewServer accepted a loot table with %s", name)
+		}
+	}
+
+	config := game.DefaultConfig()
+	config.LootTables = map[int][]game.LootEntry{0: {{Stat: models.StationHP, Rarity: models.RarityCommon, Weight: 1}}}
+	if _, err := game.NewServer(config, nil); err == nil {
+		t.Error("NewServer accepted a loot table for zone 0")
+	}
+}
@@ -0,0 +1,86 @@
+package game
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metricsNamespace prefixes the names of all game metrics.
+const metricsNamespace = "idle_dungeon"
+
+// metrics holds the Prometheus collectors describing the live game.
+// They are created with the server and registered by the caller through Collectors.
+type metrics struct {
+	battles     *prometheus.CounterVec // Battles simulated, labelled by result
+	upgrades    *prometheus.CounterVec // Station levels purchased, labelled by station
+	connections prometheus.Gauge       // Open WebSocket connections
+	players     prometheus.GaugeFunc   // Players in the game state
+	evictions   prometheus.Counter     // Idle players dropped from memory
+
+	saveFailures            prometheus.Counter // Saves of the game state that failed
+	consecutiveSaveFailures prometheus.Gauge   // Saves failed in a row since the last success
+}
+
+// newMetrics creates the game's collectors. The player gauge is read from the
+// server's game state whenever it is scraped.
+func newMetrics(s *Server) *metrics {
+	return &metrics{
+		battles: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "battles_total",
+			Help:      "Dungeon battles simulated, including offline fast-forwards, by result.",
+		}, []string{"result"}),
+		upgrades: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "upgrades_total",
+			Help:      "Factory station levels purchased, by station type.",
+		}, []string{"station"}),
+		connections: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "websocket_connections",
+			Help:      "Open WebSocket connections.",
+		}),
+		players: prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "players",
+			Help:      "Players held in memory, connected or not.",
+		}, func() float64 {
+			return float64(s.PlayerCount())
+		}),
+		evictions: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "players_evicted_total",
+			Help:      "Idle players saved and dropped from memory.",
+		}),
+		saveFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "save_failures_total",
+			Help:      "Saves of the game state that failed, periodic and eviction saves alike.",
+		}),
+		consecutiveSaveFailures: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "save_failures_consecutive",
+			Help:      "Saves failed in a row since the last success; above zero, progress is only kept in memory.",
+		}),
+	}
+}
+
+// Collectors returns the server's Prometheus collectors for registration.
+func (s *Server) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		s.metrics.battles,
+		s.metrics.upgrades,
+		s.metrics.connections,
+		s.metrics.players,
+		s.metrics.evictions,
+		s.metrics.saveFailures,
+		s.metrics.consecutiveSaveFailures,
+	}
+}
+
+// battleResultLabel returns the result label a battle is counted under.
+func battleResultLabel(victory bool) string {
+	if victory {
+		return "victory"
+	}
+	return "defeat"
+}
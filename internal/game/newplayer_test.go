@@ -0,0 +1,95 @@
+package game_test
+
+import (
+	"testing"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game/testutil"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// newPlayerUnder returns a copy of a player created on a fresh server built
+// from config. Its gold includes the first day's login bonus.
+func newPlayerUnder(t *testing.T, config game.Config) models.Player {
+	t.Helper()
+	h := testutil.New(t, config)
+	h.Server.GetOrCreatePlayer("alice")
+	return h.Player("alice")
+}
+
+func TestNewPlayersStartWithDefaults(t *testing.T) {
+	player := newPlayerUnder(t, game.DefaultConfig())
+	if player.Progress.DungeonLevel != 1 {
+		t.Errorf("new player starts on level %d, want 1", player.Progress.DungeonLevel)
+	}
+	for _, stationType := range models.StationTypes {
+		if station := player.Factory.Station(stationType); *station != *models.NewStation() {
+			t.Errorf("%s station starts as %+v, want %+v", stationType, *station, *models.NewStation())
+		}
+	}
+}
+
+func TestNewPlayersStartFromConfig(t *testing.T) {
+	defaults := game.DefaultConfig()
+	defaults.UpgradeRateLimit = 0
+	bonus := newPlayerUnder(t, defaults).Progress.Gold
+	config := game.DefaultConfig()
+	config.StartingGold = 5000
+	config.StartingDungeonLevel = 8
+	config.StartingStationLevels = map[models.StationType]int{models.StationLoot: 4, models.StationHP: 1}
+
+	player := newPlayerUnder(t, config)
+	if player.Progress.Gold != 5000+bonus || player.Progress.DungeonLevel != 8 || player.Progress.MaxDungeonLevel != 8 {
+		t.Errorf("new player starts with %d gold on level %d (deepest %d), want %d on level 8",
+			player.Progress.Gold, player.Progress.DungeonLevel, player.Progress.MaxDungeonLevel, 5000+bonus)
+	}
+
+	// A station starting at level 4 is the same as one upgraded three times
+	h := testutil.New(t, defaults)
+	bob, _ := h.Server.GetOrCreatePlayer("bob")
+	setGold(t, h, "bob", 10_000)
+	for i := 0; i < 3; i++ {
+		if err := h.Server.UpgradeStation(bob, string(models.StationLoot)); err != nil {
+			t.Fatalf("upgrade loot station: %v", err)
+		}
+	}
+	if started, upgraded := *player.Factory.Station(models.StationLoot), *h.Player("bob").Factory.Station(models.StationLoot); started != upgraded {
+		t.Errorf("loot station starting at level 4 is %+v, want %+v as if upgraded", started, upgraded)
+	}
+	if station := player.Factory.Station(models.StationHP); *station != *models.NewStation() {
+		t.Errorf("hp station starting at level 1 is %+v", *station)
+	}
+	if want := config.GoldPerTick(player.Factory.Station(models.StationLoot).Multiplier); player.Progress.GoldPerTick != want {
+		t.Errorf("new player shows %d passive gold, want %d from their loot station", player.Progress.GoldPerTick, want)
+	}
+}
+
+func TestStartingStateOnlyAppliesToNewPlayers(t *testing.T) {
+	config := game.DefaultConfig()
+	config.StartingGold = 5000
+	h := testutil.New(t, config)
+	h.Server.GetOrCreatePlayer("alice")
+	setGold(t, h, "alice", 10)
+
+	h.Server.GetOrCreatePlayer("alice")
+	if gold := h.Player("alice").Progress.Gold; gold != 10 {
+		t.Errorf("returning player has %d gold, want their own 10", gold)
+	}
+}
+
+func TestInvalidStartingStateIsCorrected(t *testing.T) {
+	bonus := newPlayerUnder(t, game.DefaultConfig()).Progress.Gold
+	config := game.DefaultConfig()
+	config.StartingGold = -50
+	config.StartingDungeonLevel = 0
+	config.MaxStationLevel = 5
+	config.StartingStationLevels = map[models.StationType]int{models.StationAttack: 40}
+
+	player := newPlayerUnder(t, config)
+	if player.Progress.Gold != bonus || player.Progress.DungeonLevel != 1 {
+		t.Errorf("new player starts with %d gold on level %d, want only the %d login bonus on level 1", player.Progress.Gold, player.Progress.DungeonLevel, bonus)
+	}
+	if level := player.Factory.Station(models.StationAttack).Level; level != 5 {
+		t.Errorf("attack station starts at level %d, want the maximum 5", level)
+	}
+}
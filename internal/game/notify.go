@@ -0,0 +1,76 @@
+package game
+
+import (
+	"encoding/json"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// notify queues a notification for a player. With batching enabled it is held
+// until the end of the current tick and delivered together with the player's
+// other notifications; otherwise it is sent right away as its own message.
+// The caller must not hold the game-state lock; see notifyUnlocked.
+func (s *Server) notify(playerID string, notification models.Notification) {
+	if !s.config.BatchNotifications {
+		message, _ := json.Marshal(notification)
+		s.SendToPlayer(playerID, message)
+		return
+	}
+
+	s.notifyMutex.Lock()
+	defer s.notifyMutex.Unlock()
+	s.pendingNotifications[playerID] = append(s.pendingNotifications[playerID], notification)
+}
+
+// deferredNotification is a notification raised under the game-state lock,
+// waiting for sendDeferredNotifications.
+type deferredNotification struct {
+	playerID     string
+	notification models.Notification
+}
+
+// notifyUnlocked queues a notification raised while the game-state lock is
+// held. Sending it then could deadlock: SendToPlayer takes the client lock,
+// under which sendUpdates reads the game state. With batching enabled
+// it is queued for the end of the tick like any other; otherwise it waits for
+// sendDeferredNotifications, which the caller runs once the lock is released.
+func (s *Server) notifyUnlocked(playerID string, notification models.Notification) {
+	s.notifyMutex.Lock()
+	defer s.notifyMutex.Unlock()
+	if s.config.BatchNotifications {
+		s.pendingNotifications[playerID] = append(s.pendingNotifications[playerID], notification)
+		return
+	}
+	s.deferredNotifications = append(s.deferredNotifications, deferredNotification{playerID, notification})
+}
+
+// sendDeferredNotifications sends every notification notifyUnlocked is still
+// holding, in the order they were raised. The caller must not hold the
+// game-state lock.
+func (s *Server) sendDeferredNotifications() {
+	s.notifyMutex.Lock()
+	deferred := s.deferredNotifications
+	s.deferredNotifications = nil
+	s.notifyMutex.Unlock()
+
+	for _, d := range deferred {
+		s.notify(d.playerID, d.notification)
+	}
+}
+
+// flushNotifications sends each player one "events" message holding every
+// notification queued for them since the last flush, in the order they were queued.
+func (s *Server) flushNotifications() {
+	s.notifyMutex.Lock()
+	pending := s.pendingNotifications
+	s.pendingNotifications = make(map[string][]models.Notification)
+	s.notifyMutex.Unlock()
+
+	for playerID, notifications := range pending {
+		message, _ := json.Marshal(map[string]interface{}{
+			"type":   "events",
+			"events": notifications,
+		})
+		s.SendToPlayer(playerID, message)
+	}
+}
@@ -0,0 +1,136 @@
+package game_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game/testutil"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// notificationsOf returns the type and data of each notification in an events message.
+func notificationsOf(t *testing.T, message map[string]interface{}) (types []string, data []map[string]interface{}) {
+	t.Helper()
+	events, ok := message["events"].([]interface{})
+	if !ok {
+		t.Fatalf("events message without events: %v", message)
+	}
+	for _, event := range events {
+		notification, _ := event.(map[string]interface{})
+		fields, _ := notification["data"].(map[string]interface{})
+		types = append(types, notification["type"].(string))
+		data = append(data, fields)
+	}
+	return types, data
+}
+
+func TestNotificationsOfATickArriveInOneMessage(t *testing.T) {
+	config := game.DefaultConfig()
+	config.LayawayEnabled = true
+	h := testutil.New(t, config)
+	client := h.Dial("alice")
+	h.Advance(1) // Deliver the login bonus first
+	client.Next("events")
+
+	player, _ := h.Server.GetPlayer("alice")
+	setGold(t, h, "alice", 0)
+	stations := []models.StationType{models.StationLoot, models.StationHP, models.StationAttack}
+	for _, stationType := range stations {
+		if _, err := h.Server.UpgradeOrReserve(player, string(stationType)); err != nil {
+			t.Fatalf("reserve %s: %v", stationType, err)
+		}
+	}
+	setGold(t, h, "alice", 1_000_000)
+	h.Advance(1)
+
+	// Every reservation completes in the same tick, in the order they were made
+	types, data := notificationsOf(t, client.Next("events"))
+	var completed []string
+	for i, kind := range types {
+		if kind == "upgradeCompleted" {
+			completed = append(completed, data[i]["station"].(string))
+		}
+	}
+	if len(completed) != len(stations) {
+		t.Fatalf("events message holds %v, want an upgradeCompleted for each of %v", types, stations)
+	}
+	for i, stationType := range stations {
+		if completed[i] != string(stationType) {
+			t.Errorf("completions arrived in order %v, want %v", completed, stations)
+			break
+		}
+	}
+}
+
+func TestUnbatchedCompletionsArriveOnTheirOwn(t *testing.T) {
+	config := game.DefaultConfig()
+	config.LayawayEnabled = true
+	config.BatchNotifications = false
+	h := testutil.New(t, config)
+	client := h.Dial("alice")
+	player, _ := h.Server.GetPlayer("alice")
+
+	setGold(t, h, "alice", 0)
+	if _, err := h.Server.UpgradeOrReserve(player, string(models.StationArmor)); err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+	setGold(t, h, "alice", 1_000_000)
+	h.Advance(1)
+
+	completed := client.Next("upgradeCompleted")
+	if data, _ := completed["data"].(map[string]interface{}); data["station"] != string(models.StationArmor) {
+		t.Errorf("completion notification = %v, want the armor station", completed)
+	}
+}
+
+func TestUnbatchedCompletionsDoNotDeadlockConnections(t *testing.T) {
+	config := game.DefaultConfig()
+	config.LayawayEnabled = true
+	config.BatchNotifications = false
+	config.UpgradeRateLimit = 0
+	h := testutil.New(t, config)
+	var players []*models.Player
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("player-%d", i)
+		h.Connect(id)
+		player, _ := h.Server.GetPlayer(id)
+		players = append(players, player)
+	}
+
+	// Every tick completes reservations under the game-state lock while other players connect
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for tick := 0; tick < 100; tick++ {
+			for _, player := range players {
+				h.Server.View(func() { player.Progress.Gold = 0 })
+				for _, stationType := range models.StationTypes {
+					h.Server.UpgradeOrReserve(player, string(stationType))
+				}
+				h.Server.View(func() { player.Progress.Gold = 1_000_000_000 })
+			}
+			h.Server.Tick()
+		}
+	}()
+	connecting := make(chan struct{})
+	go func() {
+		defer close(connecting)
+		for i := 0; i < 500; i++ { // Well under MaxClients
+			select {
+			case <-done:
+				return
+			default:
+				h.Connect(fmt.Sprintf("newcomer-%d", i))
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+		<-connecting
+	case <-time.After(20 * time.Second):
+		t.Fatal("ticks completing reservations deadlocked with connecting clients")
+	}
+}
@@ -0,0 +1,72 @@
+package game
+
+import (
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// totalDungeonLevels sums the dungeon levels of all of a player's heroes.
+func totalDungeonLevels(player *models.Player) int {
+	total := 0
+	for _, level := range player.DungeonLevels() {
+		total += *level
+	}
+	return total
+}
+
+// applyOfflineProgress fast-forwards a player through the ticks that elapsed
+// since they were last seen, capped at MaxOfflineDuration, and advances LastSeen.
+// It returns nil when no full tick has passed. The caller holds the game-state write lock.
+func (s *Server) applyOfflineProgress(player *models.Player, now time.Time) *models.OfflineGains {
+	elapsed := now.Sub(player.LastSeen)
+	if elapsed > s.config.MaxOfflineDuration {
+		// Time beyond the cap is forfeited
+		elapsed = s.config.MaxOfflineDuration
+		player.LastSeen = now.Add(-elapsed)
+	}
+
+	ticks := int(elapsed / s.config.TickInterval)
+	if ticks <= 0 {
+		return nil
+	}
+
+	// Keep the partial tick so frequent API polling still accumulates progress
+	start := player.LastSeen
+	player.LastSeen = player.LastSeen.Add(time.Duration(ticks) * s.config.TickInterval)
+	elapsed = time.Duration(ticks) * s.config.TickInterval
+
+	gains := &models.OfflineGains{Seconds: int(elapsed / time.Second)}
+	player.Progress.PlaytimeSeconds += elapsed.Seconds()
+	startLevels := totalDungeonLevels(player)
+	startGold := player.Progress.Gold
+	startExperience := player.Progress.Experience
+	startGems := player.Progress.Gems
+
+	for i := 0; i < ticks; i++ {
+		tickTime := start.Add(time.Duration(i+1) * s.config.TickInterval)
+		hero := s.createHeroAt(player, tickTime) // Buffs only help while they were active
+		combo := player.Progress.Combo           // Every hero fights with the combo the tick started with, as online
+		s.applyPassiveIncome(player)
+		for heroIndex, level := range player.DungeonLevels() {
+			dungeonLevel := *level
+			result := s.simulateBattle(hero, dungeonLevel, player.Difficulty, combo)
+			s.applyBattleResult(player, heroIndex, result)
+			recordBattleEvents(player, dungeonLevel, result, tickTime)
+			s.trackQuests(player, result, tickTime)
+
+			gains.Battles++
+			if result.Victory {
+				gains.Victories++
+			}
+		}
+	}
+
+	player.PruneBuffs(now)
+
+	gains.Levels = totalDungeonLevels(player) - startLevels
+	gains.Gold = player.Progress.Gold - startGold
+	gains.Experience = player.Progress.Experience - startExperience
+	gains.Gems = player.Progress.Gems - startGems
+	return gains
+}
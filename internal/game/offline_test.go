@@ -0,0 +1,75 @@
+package game_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game/testutil"
+)
+
+func TestNewPlayerSeenAtClockTime(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	joined := testutil.StartTime.Add(36 * time.Hour)
+	h.Clock.Set(joined)
+
+	h.Server.GetOrCreatePlayer("bob")
+	if seen := h.Player("bob").LastSeen; !seen.Equal(joined) {
+		t.Errorf("new player last seen at %v, want the clock's %v", seen, joined)
+	}
+}
+
+func TestOfflineProgressFollowsClock(t *testing.T) {
+	config := game.DefaultConfig()
+	h := testutil.New(t, config)
+	h.Server.GetOrCreatePlayer("bob")
+
+	// Ten and a half ticks away simulate ten, keeping the half for next time
+	h.Clock.Advance(10*config.TickInterval + config.TickInterval/2)
+	_, gains := h.Server.GetOrCreatePlayer("bob")
+	if gains == nil || gains.Battles != 10 || gains.Seconds != int(10*config.TickInterval/time.Second) {
+		t.Fatalf("offline gains after ten ticks away = %+v, want 10 battles", gains)
+	}
+	if seen, want := h.Player("bob").LastSeen, testutil.StartTime.Add(10*config.TickInterval); !seen.Equal(want) {
+		t.Errorf("last seen at %v after offline progress, want %v", seen, want)
+	}
+
+	if _, gains := h.Server.GetOrCreatePlayer("bob"); gains != nil {
+		t.Errorf("returning again with the clock stopped gained %+v", gains)
+	}
+	h.Clock.Advance(config.TickInterval / 2)
+	if _, gains := h.Server.GetOrCreatePlayer("bob"); gains == nil || gains.Battles != 1 {
+		t.Errorf("kept half tick plus another half gained %+v, want 1 battle", gains)
+	}
+}
+
+func TestOfflineProgressCapped(t *testing.T) {
+	config := game.DefaultConfig()
+	h := testutil.New(t, config)
+	h.Server.GetOrCreatePlayer("bob")
+
+	h.Clock.Advance(3 * config.MaxOfflineDuration)
+	_, gains := h.Server.GetOrCreatePlayer("bob")
+	if want := int(config.MaxOfflineDuration / time.Second); gains == nil || gains.Seconds != want {
+		t.Fatalf("offline gains after three times the cap = %+v, want %d seconds", gains, want)
+	}
+	if seen := h.Player("bob").LastSeen; !seen.Equal(h.Clock.Now()) {
+		t.Errorf("last seen at %v after capped progress, want %v", seen, h.Clock.Now())
+	}
+}
+
+func TestTicksMarkConnectedPlayersSeen(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	h.Connect("alice")
+
+	h.Clock.Advance(time.Hour)
+	h.Advance(1)
+	if seen := h.Player("alice").LastSeen; !seen.Equal(h.Clock.Now()) {
+		t.Errorf("connected player last seen at %v after a tick, want %v", seen, h.Clock.Now())
+	}
+
+	// Already seen by the game loop, so reconnecting simulates nothing
+	if _, gains := h.Server.GetOrCreatePlayer("alice"); gains != nil {
+		t.Errorf("connected player gained %+v offline", gains)
+	}
+}
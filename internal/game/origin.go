@@ -0,0 +1,35 @@
+package game
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// OriginAllowed reports whether a request may be served under the
+// AllowedOrigins policy. Requests without an Origin header, such as those from
+// non-browser clients, and requests from pages served by this server are
+// always allowed.
+func (s *Server) OriginAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true // Same origin
+	}
+
+	origin = normalizeOrigin(origin)
+	for _, allowed := range s.config.AllowedOrigins {
+		if allowed == "*" || normalizeOrigin(allowed) == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeOrigin lowercases an origin and drops any trailing slash, so
+// configured origins match the Origin header browsers send.
+func normalizeOrigin(origin string) string {
+	return strings.ToLower(strings.TrimSuffix(origin, "/"))
+}
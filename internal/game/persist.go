@@ -0,0 +1,158 @@
+package game
+
+import (
+	"context"
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// maxSaveBackoff bounds how long the persist loop waits before retrying after
+// failed saves. Each failure in a row doubles the wait, starting from SaveInterval.
+const maxSaveBackoff = 5 * time.Minute
+
+// persistLoop saves the game state on every SaveInterval until ctx is cancelled.
+// Failed saves are logged and retried with exponential backoff while the game
+// keeps running from memory; the regular interval resumes after a success.
+func (s *Server) persistLoop(ctx context.Context) {
+	timer := time.NewTimer(s.config.SaveInterval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		delay := s.config.SaveInterval
+		if err := s.Save(); err != nil {
+			failures := s.Persistence().ConsecutiveFailures
+			delay = saveBackoff(s.config.SaveInterval, failures)
+			s.logger.Error("failed to save game state, keeping it in memory", "event", "save",
+				"error", err, "consecutive_failures", failures, "retry_in", delay.String())
+		}
+		timer.Reset(delay)
+	}
+}
+
+// saveBackoff returns how long to wait before the next save after the given
+// number of failed saves in a row: interval doubled for each failure after
+// the first, up to maxSaveBackoff.
+func saveBackoff(interval time.Duration, failures int) time.Duration {
+	if interval >= maxSaveBackoff {
+		return interval
+	}
+	delay := interval
+	for i := 1; i < failures && delay < maxSaveBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxSaveBackoff)
+}
+
+// Save writes the current game state to the persister.
+// The game loop is paused only while a copy of the players is taken, so no
+// player is saved mid-tick and a slow or stuck persister never holds up a
+// tick. Saves are serialized, so an older copy never overwrites a newer one.
+// It is a no-op when the server has no persister.
+func (s *Server) Save() error {
+	if s.persister == nil {
+		return nil
+	}
+
+	s.loopMutex.Lock()
+	players := s.gameState.SnapshotPlayers()
+	hallOfFame := s.HallOfFame()
+	bans := s.Bans()
+	restores := s.restores
+	s.loopMutex.Unlock()
+
+	snapshot := models.NewGameState()
+	byID := make(map[string]*models.Player, len(players))
+	for _, player := range players {
+		byID[player.ID] = player
+	}
+	snapshot.ReplacePlayers(byID)
+	snapshot.HallOfFame = hallOfFame
+	snapshot.Bans = bans
+	return s.persist(snapshot, restores)
+}
+
+// persist saves state with the persister and records the outcome for
+// Persistence and the save metrics. The caller holds no lock that a tick needs.
+// State copied before the latest Restore, whose restores count is behind, is
+// stale and is dropped without saving, so it cannot write back players the
+// restore removed.
+func (s *Server) persist(state *models.GameState, restores int) error {
+	s.saveMutex.Lock()
+	if restores != s.restores {
+		s.saveMutex.Unlock()
+		s.logger.Debug("dropped save of state copied before a restore", "event", "save")
+		return nil
+	}
+	err := s.persister.Save(state)
+	s.saveMutex.Unlock()
+	return s.recordSave(err)
+}
+
+// replaceStored makes state everything the persister holds, so players,
+// seasons, and bans missing from it are not loaded back later, and drops any
+// save of state copied before it. Persisters that are not a
+// models.StateReplacer rewrite everything on each save, so for them it is a
+// plain save. The caller holds loopMutex.
+func (s *Server) replaceStored(state *models.GameState) error {
+	s.saveMutex.Lock()
+	var err error
+	if replacer, ok := s.persister.(models.StateReplacer); ok {
+		err = replacer.Replace(state)
+	} else {
+		err = s.persister.Save(state)
+	}
+	if err == nil {
+		s.restores++
+	}
+	s.saveMutex.Unlock()
+	return s.recordSave(err)
+}
+
+// recordSave records the outcome of a save for Persistence and the save
+// metrics, and returns err.
+func (s *Server) recordSave(err error) error {
+	s.saveStatusMutex.Lock()
+	defer s.saveStatusMutex.Unlock()
+	if err != nil {
+		s.saveFailures++
+		s.lastSaveError = err
+		s.metrics.saveFailures.Inc()
+		s.metrics.consecutiveSaveFailures.Set(float64(s.saveFailures))
+		return err
+	}
+	if s.saveFailures > 0 {
+		s.logger.Info("saving game state works again", "event", "save", "failed_saves", s.saveFailures)
+	}
+	s.saveFailures = 0
+	s.lastSaveError = nil
+	s.lastSaved = s.clock.Now()
+	s.metrics.consecutiveSaveFailures.Set(0)
+	return nil
+}
+
+// Persistence reports whether recent saves succeeded. A server without a
+// persister is never degraded.
+func (s *Server) Persistence() models.PersistenceStatus {
+	s.saveStatusMutex.Lock()
+	defer s.saveStatusMutex.Unlock()
+
+	status := models.PersistenceStatus{
+		Degraded:            s.saveFailures > 0,
+		ConsecutiveFailures: s.saveFailures,
+	}
+	if s.lastSaveError != nil {
+		status.LastError = s.lastSaveError.Error()
+	}
+	if !s.lastSaved.IsZero() {
+		lastSaved := s.lastSaved
+		status.LastSaved = &lastSaved
+	}
+	return status
+}
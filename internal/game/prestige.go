@@ -0,0 +1,51 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// prestigeBonus is the permanent hero multiplier gained for each prestige level.
+const prestigeBonus = 0.1
+
+// ErrPrestigeTooEarly is returned when a player prestiges before passing the configured dungeon level.
+var ErrPrestigeTooEarly = errors.New("dungeon level too low to prestige")
+
+// Prestige resets a player's gold, dungeon level, and factory stations in
+// exchange for a permanent multiplier on every hero stat. It is rejected until
+// the player's dungeon level exceeds PrestigeThreshold. Every hero returns to
+// dungeon level 1, but unlocked hero slots, experience, and duel history are
+// kept; pending reservations are dropped with the stations they referred to.
+// The prestige is recorded in the player's activity feed.
+func (s *Server) Prestige(player *models.Player) error {
+	var err error
+	s.gameState.Update(func() {
+		if player.Progress.DungeonLevel <= s.config.PrestigeThreshold {
+			err = ErrPrestigeTooEarly
+			return
+		}
+
+		player.PrestigeLevel++
+		player.PrestigeMultiplier = prestigeMultiplier(player.PrestigeLevel)
+		for _, level := range player.DungeonLevels() {
+			*level = 1
+		}
+		player.Progress.Gold = 0
+		player.Factory = models.NewFactory()
+		player.Reservations = nil
+		player.AddEvent(models.Event{
+			Type:    models.EventPrestige,
+			Message: fmt.Sprintf("Prestiged to level %d (%.1fx hero stats)", player.PrestigeLevel, player.PrestigeMultiplier),
+			Time:    s.clock.Now(),
+		})
+	})
+	return err
+}
+
+// prestigeMultiplier returns the canonical hero multiplier for a prestige level.
+// Each prestige adds prestigeBonus to the base multiplier of 1.0.
+func prestigeMultiplier(level int) float64 {
+	return 1.0 + prestigeBonus*float64(level)
+}
@@ -0,0 +1,208 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// dailyQuestCount is how many quests a player is given each day, each with a
+// different type.
+const dailyQuestCount = 3
+
+// questTypes lists every quest type in the order they are shuffled from.
+var questTypes = []models.QuestType{
+	models.QuestWinBattles,
+	models.QuestReachLevel,
+	models.QuestKillBosses,
+	models.QuestCombo,
+}
+
+var (
+	// ErrUnknownQuest is returned when claiming a quest type the player was not given today.
+	ErrUnknownQuest = errors.New("no such quest today")
+	// ErrQuestIncomplete is returned when claiming a quest whose target has not been reached.
+	ErrQuestIncomplete = errors.New("quest is not complete")
+	// ErrQuestClaimed is returned when claiming a quest whose reward was already paid.
+	ErrQuestClaimed = errors.New("quest already claimed")
+)
+
+// questSeed returns the seed a player's quests for a calendar day are drawn from,
+// so the same player is always dealt the same quests on the same day.
+func questSeed(playerID, day string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(playerID))
+	hash.Write([]byte{0})
+	hash.Write([]byte(day))
+	return int64(hash.Sum64())
+}
+
+// deepestDungeonLevel returns the highest current dungeon level of any of the player's heroes.
+func deepestDungeonLevel(player *models.Player) int {
+	deepest := 0
+	for _, level := range player.DungeonLevels() {
+		deepest = max(deepest, *level)
+	}
+	return deepest
+}
+
+// drawQuests deals the player's quests for a calendar day. Which quests are drawn
+// and how hard they are depends only on the player's ID and the day; the
+// targets of reachLevel quests and every reward then scale with the player's
+// deepest dungeon level, so deeper players get further goals for more gold.
+// Boss quests are left out when bosses are disabled.
+// The caller holds the game-state lock.
+func (s *Server) drawQuests(player *models.Player, day string) *models.DailyQuests {
+	rng := newBattleRand(questSeed(player.ID, day))
+	level := deepestDungeonLevel(player)
+	gold := 10 + level*2 // A victory's gold on the level, before loot
+
+	var candidates []models.QuestType
+	for _, questType := range questTypes {
+		if questType != models.QuestKillBosses || s.config.BossInterval > 0 {
+			candidates = append(candidates, questType)
+		}
+	}
+	rng.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+
+	quests := &models.DailyQuests{Day: day}
+	for _, questType := range candidates[:min(dailyQuestCount, len(candidates))] {
+		quest := models.Quest{Type: questType}
+		switch questType {
+		case models.QuestWinBattles:
+			quest.Target = 25 * (1 + rng.IntN(4))
+			quest.RewardGold = quest.Target * gold
+		case models.QuestReachLevel:
+			levels := 5 + rng.IntN(11)
+			quest.Target = level + levels
+			quest.Progress = level
+			quest.RewardGold = levels * 10 * gold
+			quest.RewardGems = 1
+		case models.QuestKillBosses:
+			quest.Target = 1 + rng.IntN(3)
+			quest.RewardGold = quest.Target * bossGoldMultiplier * 10 * gold
+			quest.RewardGems = quest.Target
+		case models.QuestCombo:
+			quest.Target = 10 + rng.IntN(16)
+			quest.RewardGold = quest.Target * 4 * gold
+		}
+		quests.Quests = append(quests.Quests, quest)
+	}
+	return quests
+}
+
+// currentQuests returns the player's quests for the day containing now in
+// their time zone, drawing them when the player has none for that day yet.
+// Quests left over, and rewards left unclaimed, from an earlier day are
+// dropped. As with login bonuses, a new day only begins once
+// models.MinDayLength has passed since the current quests' day began, so
+// changing time zones cannot redraw them early.
+// The caller holds the game-state write lock.
+func (s *Server) currentQuests(player *models.Player, now time.Time) *models.DailyQuests {
+	start := models.DayStart(now, player.Location())
+	if player.Quests == nil || models.BeginsNewDay(questDayStart(player.Quests), start) {
+		player.Quests = s.drawQuests(player, start.Format(loginDayLayout))
+		player.Quests.Start = start
+	}
+	return player.Quests
+}
+
+// questDayStart returns when the day of the quests began. Quests drawn
+// before day starts were recorded were drawn for UTC days.
+func questDayStart(quests *models.DailyQuests) time.Time {
+	if quests.Start.IsZero() {
+		start, _ := time.Parse(loginDayLayout, quests.Day)
+		return start
+	}
+	return quests.Start
+}
+
+// trackQuests counts a battle result that applyBattleResult has already
+// applied toward the player's quests for the day of now, and returns the
+// quests it completed.
+// The caller holds the game-state write lock.
+func (s *Server) trackQuests(player *models.Player, battleResult models.BattleResult, now time.Time) []models.Quest {
+	var completed []models.Quest
+	quests := s.currentQuests(player, now)
+	for i := range quests.Quests {
+		quest := &quests.Quests[i]
+		if quest.Complete() {
+			continue
+		}
+		switch quest.Type {
+		case models.QuestWinBattles:
+			if battleResult.Victory {
+				quest.Progress++
+			}
+		case models.QuestReachLevel:
+			quest.Progress = max(quest.Progress, deepestDungeonLevel(player))
+		case models.QuestKillBosses:
+			if battleResult.Victory && battleResult.IsBoss {
+				quest.Progress++
+			}
+		case models.QuestCombo:
+			quest.Progress = max(quest.Progress, player.Progress.Combo)
+		}
+		if quest.Complete() {
+			quest.Progress = quest.Target
+			completed = append(completed, *quest)
+		}
+	}
+	return completed
+}
+
+// Quests returns a copy of the player's quests for today, drawing them on the
+// first call of each day in the player's time zone.
+func (s *Server) Quests(player *models.Player) models.DailyQuests {
+	var quests models.DailyQuests
+	s.gameState.Update(func() {
+		quests = *s.currentQuests(player, s.clock.Now()).Clone()
+	})
+	return quests
+}
+
+// ClaimQuest pays out the reward of today's completed quest of the given type
+// and returns the player's quests with it claimed. It returns ErrUnknownQuest,
+// ErrQuestIncomplete, or ErrQuestClaimed, and changes nothing in those cases.
+// Each claim is recorded in the player's activity feed.
+func (s *Server) ClaimQuest(player *models.Player, questType string) (models.DailyQuests, error) {
+	var quests models.DailyQuests
+	var err error
+	s.gameState.Update(func() {
+		now := s.clock.Now()
+		current := s.currentQuests(player, now)
+		quest := current.Quest(models.QuestType(questType))
+		switch {
+		case quest == nil:
+			err = ErrUnknownQuest
+			return
+		case quest.Claimed:
+			err = ErrQuestClaimed
+			return
+		case !quest.Complete():
+			err = ErrQuestIncomplete
+			return
+		}
+
+		quest.Claimed = true
+		player.Progress.Gold += quest.RewardGold
+		player.Progress.Gems += quest.RewardGems
+		player.AddEvent(models.Event{
+			Type:    models.EventQuest,
+			Message: fmt.Sprintf("Completed the %s quest (+%d gold, +%d gems)", quest.Type, quest.RewardGold, quest.RewardGems),
+			Time:    now,
+		})
+		s.completeReservations(player)
+		quests = *current.Clone()
+	})
+	s.sendDeferredNotifications()
+	if err == nil {
+		s.requestUpdates()
+	}
+	return quests, err
+}
@@ -0,0 +1,92 @@
+package game_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game/testutil"
+)
+
+// setTimeZone moves the player to the named IANA time zone.
+func setTimeZone(t *testing.T, h *testutil.Harness, playerID, timeZone string) {
+	t.Helper()
+	player, _ := h.Server.GetPlayer(playerID)
+	if err := h.Server.SetTimeZone(player, timeZone); err != nil {
+		t.Fatalf("set time zone %s: %v", timeZone, err)
+	}
+}
+
+// mustLoadLocation loads the named time zone, failing the test when it is unknown.
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("load time zone %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestQuestsFollowPlayerTimeZone(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	tokyo := mustLoadLocation(t, "Asia/Tokyo")
+	h.Clock.Set(time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC))
+	player, _ := h.Server.GetOrCreatePlayer("alice")
+	setTimeZone(t, h, "alice", "Asia/Tokyo")
+
+	h.Clock.Set(time.Date(2024, time.January, 3, 8, 0, 0, 0, tokyo))
+	quests := h.Server.Quests(player)
+	if quests.Day != "2024-01-03" || !quests.Start.Equal(time.Date(2024, time.January, 3, 0, 0, 0, 0, tokyo)) {
+		t.Fatalf("quests drawn for %s starting %s, want the Tokyo day 2024-01-03", quests.Day, quests.Start)
+	}
+
+	// Still the 3rd in Tokyo, though the UTC date changed
+	h.Clock.Set(time.Date(2024, time.January, 3, 23, 0, 0, 0, tokyo))
+	if again := h.Server.Quests(player); again.Day != quests.Day {
+		t.Errorf("quests redrawn for %s within the same Tokyo day", again.Day)
+	}
+
+	// Moving the boundary does not deal a new set before a whole day has passed
+	setTimeZone(t, h, "alice", "America/Los_Angeles")
+	h.Clock.Set(time.Date(2024, time.January, 4, 1, 0, 0, 0, tokyo))
+	if again := h.Server.Quests(player); again.Day != quests.Day {
+		t.Errorf("changing time zones redrew the quests for %s", again.Day)
+	}
+
+	h.Clock.Set(time.Date(2024, time.January, 4, 12, 0, 0, 0, tokyo))
+	if next := h.Server.Quests(player); next.Day != "2024-01-03" {
+		// January 4 12:00 in Tokyo is still January 3 in Los Angeles
+		t.Errorf("quests redrawn for %s before the Los Angeles day ended", next.Day)
+	}
+	h.Clock.Set(time.Date(2024, time.January, 4, 0, 30, 0, 0, mustLoadLocation(t, "America/Los_Angeles")))
+	if next := h.Server.Quests(player); next.Day != "2024-01-04" {
+		t.Errorf("quests for the next Los Angeles day drawn for %s, want 2024-01-04", next.Day)
+	}
+}
+
+func TestQuestsAcrossDST(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+	h.Clock.Set(time.Date(2024, time.March, 30, 12, 0, 0, 0, berlin))
+	player, _ := h.Server.GetOrCreatePlayer("alice")
+	setTimeZone(t, h, "alice", "Europe/Berlin")
+
+	// March 31 is only 23 hours long in Berlin
+	for _, step := range []struct {
+		at  time.Time
+		day string
+	}{
+		{time.Date(2024, time.March, 31, 0, 10, 0, 0, berlin), "2024-03-31"},
+		{time.Date(2024, time.March, 31, 23, 50, 0, 0, berlin), "2024-03-31"},
+		{time.Date(2024, time.April, 1, 0, 10, 0, 0, berlin), "2024-04-01"},
+	} {
+		h.Clock.Set(step.at)
+		quests := h.Server.Quests(player)
+		if quests.Day != step.day {
+			t.Errorf("quests at %s drawn for %s, want %s", step.at, quests.Day, step.day)
+		}
+		if start := quests.Start; start.In(berlin).Hour() != 0 || start.In(berlin).Minute() != 0 {
+			t.Errorf("quests at %s start at %s, want local midnight", step.at, start.In(berlin))
+		}
+	}
+}
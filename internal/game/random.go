@@ -0,0 +1,33 @@
+package game
+
+import (
+	"math/rand/v2"
+	"sync"
+)
+
+// lockedRand is a random number generator that is safe for concurrent use.
+// The server keeps one, seeded at startup, and draws a fresh seed from it for
+// every battle; the battle then rolls from its own small generator, so the
+// simulation itself never contends on the lock.
+type lockedRand struct {
+	mutex sync.Mutex // Mutex for thread-safe access to rng
+	rng   *rand.Rand // Underlying generator
+}
+
+// newLockedRand creates a concurrency-safe generator from a seed.
+func newLockedRand(seed int64) *lockedRand {
+	return &lockedRand{rng: newBattleRand(seed)}
+}
+
+// Int64 returns a non-negative pseudo-random 63-bit integer.
+func (l *lockedRand) Int64() int64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.rng.Int64()
+}
+
+// newBattleRand creates the generator a single battle rolls from.
+// The same seed always yields the same sequence of rolls.
+func newBattleRand(seed int64) *rand.Rand {
+	return rand.New(rand.NewPCG(uint64(seed), 0))
+}
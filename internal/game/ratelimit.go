@@ -0,0 +1,98 @@
+package game
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiterIdle is how long a key's bucket may go unused before it is evicted.
+// By then the bucket has long refilled, so dropping it changes nothing.
+const rateLimiterIdle = time.Minute
+
+// tokenBucket tracks the tokens left for one key.
+type tokenBucket struct {
+	tokens float64   // Tokens currently available, up to the limiter's burst
+	last   time.Time // When tokens was last refilled
+}
+
+// rateLimiter is a per-key token bucket limiter that is safe for concurrent use.
+// Each key may spend up to burst tokens at once, refilled at rate tokens per second.
+type rateLimiter struct {
+	rate    float64                 // Tokens added per second
+	burst   float64                 // Maximum tokens a bucket holds
+	buckets map[string]*tokenBucket // Buckets keyed by player ID
+	mutex   sync.Mutex              // Mutex for thread-safe access to buckets
+}
+
+// newRateLimiter creates a limiter allowing rate events per second per key,
+// with bursts of up to one second's worth.
+func newRateLimiter(rate float64) *rateLimiter {
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(max(1, int(rate))),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow reports whether key may perform one more event at now, spending a token if so.
+func (l *rateLimiter) allow(key string, now time.Time) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	bucket, exists := l.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = bucket
+	}
+
+	// Refill for the time since the bucket was last used
+	bucket.tokens += now.Sub(bucket.last).Seconds() * l.rate
+	if bucket.tokens > l.burst {
+		bucket.tokens = l.burst
+	}
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// evict discards the buckets of keys not seen since before cutoff.
+func (l *rateLimiter) evict(cutoff time.Time) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for key, bucket := range l.buckets {
+		if bucket.last.Before(cutoff) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// evictLoop periodically evicts buckets idle by clock's time until ctx is cancelled.
+func (l *rateLimiter) evictLoop(ctx context.Context, clock Clock) {
+	ticker := time.NewTicker(rateLimiterIdle)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.evict(clock.Now().Add(-rateLimiterIdle))
+		}
+	}
+}
+
+// AllowUpgrade reports whether the player may make another upgrade request
+// right now. The HTTP and WebSocket upgrade paths share the same per-player
+// limit, set by UpgradeRateLimit; it always allows when rate limiting is disabled.
+func (s *Server) AllowUpgrade(playerID string) bool {
+	if s.upgradeLimiter == nil {
+		return true
+	}
+	return s.upgradeLimiter.allow(playerID, s.clock.Now())
+}
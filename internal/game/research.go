@@ -0,0 +1,177 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// ResearchNode is one upgrade of the research tree. It can be unlocked once
+// every prerequisite node is unlocked and the player has reached
+// MinDungeonLevel, and then permanently adds Bonus to the multiplier on Stat.
+type ResearchNode struct {
+	ID              string             `json:"id"`                        // Unique name the node is unlocked by
+	Name            string             `json:"name"`                      // Display name
+	Cost            int                `json:"cost"`                      // Gold spent to unlock the node
+	MinDungeonLevel int                `json:"minDungeonLevel,omitempty"` // Deepest dungeon level the player must have reached
+	Prerequisites   []string           `json:"prerequisites,omitempty"`   // IDs of the nodes that must be unlocked first
+	Stat            models.StationType `json:"stat"`                      // Hero stat the node improves
+	Bonus           float64            `json:"bonus"`                     // Added to the stat's research multiplier, 0.1 for +10%
+}
+
+var (
+	// ErrUnknownResearch is returned when unlocking a research node that does not exist.
+	ErrUnknownResearch = errors.New("unknown research")
+	// ErrResearchUnlocked is returned when unlocking a research node the player already has.
+	ErrResearchUnlocked = errors.New("research already unlocked")
+	// ErrResearchLocked is returned, wrapped in a *ResearchLockedError, when
+	// unlocking a research node whose requirements the player has not met.
+	ErrResearchLocked = errors.New("research is locked")
+)
+
+// ResearchLockedError explains which requirements of a research node the player is missing.
+type ResearchLockedError struct {
+	Node            string   // ID of the node that could not be unlocked
+	Missing         []string // Prerequisite node IDs the player has not unlocked, in tree order
+	MinDungeonLevel int      // Dungeon level still to be reached, or 0 when it was
+}
+
+// Error names the node and every requirement the player is missing.
+func (e *ResearchLockedError) Error() string {
+	var requirements []string
+	if len(e.Missing) > 0 {
+		requirements = append(requirements, "research "+strings.Join(e.Missing, ", "))
+	}
+	if e.MinDungeonLevel > 0 {
+		requirements = append(requirements, fmt.Sprintf("dungeon level %d", e.MinDungeonLevel))
+	}
+	return fmt.Sprintf("research %s is locked: requires %s", e.Node, strings.Join(requirements, " and "))
+}
+
+// Unwrap lets errors.Is match ErrResearchLocked.
+func (e *ResearchLockedError) Unwrap() error {
+	return ErrResearchLocked
+}
+
+// DefaultResearchTree returns the research tree the game uses unless
+// configured otherwise: a cheap first node for every stat, each leading to
+// stronger nodes gated by dungeon level.
+func DefaultResearchTree() []ResearchNode {
+	return []ResearchNode{
+		{ID: "vitality", Name: "Vitality", Cost: 500, Stat: models.StationHP, Bonus: 0.1},
+		{ID: "plating", Name: "Iron Plating", Cost: 500, Stat: models.StationArmor, Bonus: 0.1},
+		{ID: "sharpening", Name: "Sharpening", Cost: 500, Stat: models.StationAttack, Bonus: 0.1},
+		{ID: "scavenging", Name: "Scavenging", Cost: 500, Stat: models.StationLoot, Bonus: 0.1},
+		{ID: "fortress", Name: "Fortress", Cost: 5000, MinDungeonLevel: 10, Prerequisites: []string{"plating"}, Stat: models.StationArmor, Bonus: 0.25},
+		{ID: "temperedSteel", Name: "Tempered Steel", Cost: 5000, MinDungeonLevel: 10, Prerequisites: []string{"sharpening"}, Stat: models.StationAttack, Bonus: 0.25},
+		{ID: "precision", Name: "Precision", Cost: 8000, MinDungeonLevel: 15, Prerequisites: []string{"sharpening"}, Stat: models.StationCrit, Bonus: 0.25},
+		{ID: "treasureHunter", Name: "Treasure Hunter", Cost: 20000, MinDungeonLevel: 20, Prerequisites: []string{"scavenging"}, Stat: models.StationLoot, Bonus: 0.5},
+		{ID: "heroicVigor", Name: "Heroic Vigor", Cost: 25000, MinDungeonLevel: 25, Prerequisites: []string{"vitality", "fortress"}, Stat: models.StationHP, Bonus: 0.5},
+	}
+}
+
+// validateResearchTree checks that node IDs are unique, stats exist, costs
+// are positive, and every prerequisite names a node listed earlier, which
+// also rules out cycles.
+func validateResearchTree(tree []ResearchNode) error {
+	seen := make(map[string]bool, len(tree))
+	for _, node := range tree {
+		switch {
+		case node.ID == "" || seen[node.ID]:
+			return fmt.Errorf("research node %q: missing or duplicate ID", node.ID)
+		case !slices.Contains(models.StationTypes, node.Stat):
+			return fmt.Errorf("research node %q: unknown stat %q", node.ID, node.Stat)
+		case node.Cost <= 0:
+			return fmt.Errorf("research node %q: cost must be positive", node.ID)
+		}
+		for _, prerequisite := range node.Prerequisites {
+			if !seen[prerequisite] {
+				return fmt.Errorf("research node %q: prerequisite %q is not listed before it", node.ID, prerequisite)
+			}
+		}
+		seen[node.ID] = true
+	}
+	return nil
+}
+
+// ResearchTree returns the research nodes of this server, in tree order.
+func (s *Server) ResearchTree() []ResearchNode {
+	return s.config.ResearchTree
+}
+
+// researchNode returns the configured research node with the given ID.
+func (s *Server) researchNode(id string) (ResearchNode, bool) {
+	for _, node := range s.config.ResearchTree {
+		if node.ID == id {
+			return node, true
+		}
+	}
+	return ResearchNode{}, false
+}
+
+// researchMultiplier returns the multiplier the player's unlocked research
+// nodes apply to a stat: 1 plus the bonus of each node on it. Unlocked IDs
+// no longer in the tree contribute nothing.
+// The caller holds the game-state lock.
+func (s *Server) researchMultiplier(player *models.Player, stat models.StationType) float64 {
+	multiplier := 1.0
+	for _, id := range player.Research {
+		if node, ok := s.researchNode(id); ok && node.Stat == stat {
+			multiplier += node.Bonus
+		}
+	}
+	return multiplier
+}
+
+// UnlockResearch spends the player's gold on the research node with the
+// given ID. It returns ErrUnknownResearch, ErrResearchUnlocked, a
+// *ResearchLockedError listing the prerequisites and dungeon level still
+// missing, or ErrInsufficientGold, and changes nothing in those cases.
+// Unlocked research is kept through prestige, and each unlock is recorded in
+// the player's activity feed.
+func (s *Server) UnlockResearch(player *models.Player, id string) error {
+	node, ok := s.researchNode(id)
+	if !ok {
+		return ErrUnknownResearch
+	}
+
+	var err error
+	s.gameState.Update(func() {
+		if slices.Contains(player.Research, id) {
+			err = ErrResearchUnlocked
+			return
+		}
+		locked := &ResearchLockedError{Node: id}
+		for _, prerequisite := range node.Prerequisites {
+			if !slices.Contains(player.Research, prerequisite) {
+				locked.Missing = append(locked.Missing, prerequisite)
+			}
+		}
+		if player.Progress.MaxDungeonLevel < node.MinDungeonLevel {
+			locked.MinDungeonLevel = node.MinDungeonLevel
+		}
+		if len(locked.Missing) > 0 || locked.MinDungeonLevel > 0 {
+			err = locked
+			return
+		}
+		if player.Progress.Gold < node.Cost {
+			err = ErrInsufficientGold
+			return
+		}
+
+		player.Progress.Gold -= node.Cost
+		player.Research = append(player.Research, id)
+		player.AddEvent(models.Event{
+			Type:    models.EventResearch,
+			Message: fmt.Sprintf("Researched %s (+%.0f%% %s)", node.Name, node.Bonus*100, node.Stat),
+			Time:    s.clock.Now(),
+		})
+	})
+	if err == nil {
+		s.requestUpdates()
+	}
+	return err
+}
@@ -0,0 +1,106 @@
+package game_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game/testutil"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// newLayawayHarness returns a harness with layaway enabled.
+func newLayawayHarness(t *testing.T) *testutil.Harness {
+	config := game.DefaultConfig()
+	config.LayawayEnabled = true
+	return testutil.New(t, config)
+}
+
+func TestUnaffordableUpgradeRejectedWithoutLayaway(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	player, _ := h.Server.GetOrCreatePlayer("alice")
+	setGold(t, h, "alice", 0)
+
+	reserved, err := h.Server.UpgradeOrReserve(player, string(models.StationAttack))
+	if reserved || !errors.Is(err, game.ErrInsufficientGold) {
+		t.Errorf("unaffordable upgrade: reserved %v, %v; want ErrInsufficientGold", reserved, err)
+	}
+	if reservations := h.Player("alice").Reservations; len(reservations) != 0 {
+		t.Errorf("reservations = %+v, want none", reservations)
+	}
+}
+
+func TestUnaffordableUpgradeReserved(t *testing.T) {
+	h := newLayawayHarness(t)
+	player, _ := h.Server.GetOrCreatePlayer("alice")
+	setGold(t, h, "alice", 0)
+
+	reserved, err := h.Server.UpgradeOrReserve(player, string(models.StationAttack))
+	if !reserved || err != nil {
+		t.Fatalf("unaffordable upgrade: reserved %v, %v; want reserved", reserved, err)
+	}
+	reservations := h.Player("alice").Reservations
+	if len(reservations) != 1 || reservations[0].Station != string(models.StationAttack) {
+		t.Fatalf("reservations = %+v, want one for attack", reservations)
+	}
+
+	var encoded []byte
+	h.Server.View(func() {
+		encoded, _ = json.Marshal(player)
+	})
+	if !strings.Contains(string(encoded), `"reservations":[{"station":"attack"`) {
+		t.Errorf("player JSON does not report the reservation: %s", encoded)
+	}
+
+	// Unknown stations are still rejected outright
+	if reserved, err := h.Server.UpgradeOrReserve(player, "wisdom"); reserved || !errors.Is(err, game.ErrUnknownStation) {
+		t.Errorf("upgrade of an unknown station: reserved %v, %v; want ErrUnknownStation", reserved, err)
+	}
+}
+
+func TestReservationCompletesWhenAffordable(t *testing.T) {
+	h := newLayawayHarness(t)
+	h.Connect("alice")
+	player, _ := h.Server.GetPlayer("alice")
+	setGold(t, h, "alice", 0)
+	if _, err := h.Server.UpgradeOrReserve(player, string(models.StationAttack)); err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+
+	cost := h.Player("alice").Factory.Station(models.StationAttack).Cost
+	setGold(t, h, "alice", int(cost))
+	h.Advance(1)
+
+	after := h.Player("alice")
+	if level := after.Factory.Station(models.StationAttack).Level; level != 2 {
+		t.Errorf("attack level = %d after the gold arrived, want 2", level)
+	}
+	if len(after.Reservations) != 0 {
+		t.Errorf("reservations = %+v after completion, want none", after.Reservations)
+	}
+}
+
+func TestReservationReplacedByNewRequest(t *testing.T) {
+	h := newLayawayHarness(t)
+	player, _ := h.Server.GetOrCreatePlayer("alice")
+	setGold(t, h, "alice", 0)
+
+	h.Server.UpgradeOrReserve(player, string(models.StationAttack))
+	h.Server.UpgradeOrReserve(player, string(models.StationHP))
+	h.Clock.Advance(time.Minute)
+	h.Server.UpgradeOrReserve(player, string(models.StationAttack))
+
+	reservations := h.Player("alice").Reservations
+	if len(reservations) != 2 {
+		t.Fatalf("reservations = %+v, want one each for hp and attack", reservations)
+	}
+	if reservations[0].Station != string(models.StationHP) || reservations[1].Station != string(models.StationAttack) {
+		t.Errorf("reservations = %+v, want hp before the replaced attack", reservations)
+	}
+	if !reservations[1].ReservedAt.Equal(testutil.StartTime.Add(time.Minute)) {
+		t.Errorf("attack reserved at %s, want the time of the second request", reservations[1].ReservedAt)
+	}
+}
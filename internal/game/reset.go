@@ -0,0 +1,49 @@
+package game
+
+import (
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// ResetPlayer gives a player a fresh start: everything earned is replaced
+// with what a new player starts with, including prestige, heroes, items,
+// buffs, and activity. The player's ID, name, token, guild, time zone, daily
+// login streak, daily quests, and record of imported exports are kept, so
+// resetting cannot re-earn a login bonus or a quest reward, or let an export
+// import twice.
+//
+// The game loop is paused for the reset, so no battle fought from the old
+// state lands on the new one. The player is reset in place under the
+// game-state write lock rather than replaced, so the connections and guild
+// index that refer to it stay valid and no stale copy is left behind. Each open connection for the player is
+// then sent a gameState message with the reset state and reset set to true.
+func (s *Server) ResetPlayer(player *models.Player) {
+	s.loopMutex.Lock()
+	defer s.loopMutex.Unlock()
+
+	s.gameState.Update(func() { s.resetInPlace(player, s.clock.Now()) })
+
+	s.logger.Info("player reset", "event", "reset", "player_id", player.ID)
+	s.SendToPlayer(player.ID, s.MarshalPlayerMessage("gameState", player, map[string]interface{}{"reset": true}))
+}
+
+// resetInPlace overwrites the player with a new player's defaults, last seen
+// at now, keeping the ID, name, token, guild, time zone, daily login streak,
+// daily quests, and imported exports that ResetPlayer documents.
+// The caller holds the game-state write lock.
+func (s *Server) resetInPlace(player *models.Player, now time.Time) {
+	fresh := s.newPlayer(player.ID, now)
+	fresh.Name = player.Name
+	fresh.TokenHash = player.TokenHash
+	fresh.Guild = player.Guild
+	fresh.TimeZone = player.TimeZone
+	fresh.LastLoginDay = player.LastLoginDay
+	fresh.LoginDayStart = player.LoginDayStart
+	fresh.LoginStreak = player.LoginStreak
+	fresh.NextLoginBonus = player.NextLoginBonus
+	fresh.Quests = player.Quests
+	fresh.ImportedExports = player.ImportedExports
+	fresh.ExportsVoidBefore = player.ExportsVoidBefore
+	*player = *fresh
+}
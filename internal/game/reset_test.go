@@ -0,0 +1,70 @@
+package game_test
+
+import (
+	"testing"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game/testutil"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+func TestResetPlayerRestoresDefaults(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	player, token, err := h.Server.AuthenticatePlayer("alice", "")
+	if err != nil {
+		t.Fatalf("create player: %v", err)
+	}
+	if err := h.Server.SetTimeZone(player, "Europe/Paris"); err != nil {
+		t.Fatalf("set time zone: %v", err)
+	}
+	h.Server.View(func() {
+		player.Name = "Alice"
+		player.Progress.Gold = 1_000_000
+		player.Progress.DungeonLevel = 40
+		player.PrestigeLevel, player.PrestigeMultiplier = 2, 1.5
+		player.Factory.Station(models.StationAttack).Level = 12
+	})
+	before := h.Player("alice")
+
+	h.Server.ResetPlayer(player)
+	after := h.Player("alice")
+	if after.Progress.Gold != 0 || after.Progress.DungeonLevel != 1 || after.PrestigeLevel != 0 || after.PrestigeMultiplier != 1 {
+		t.Errorf("reset player has %d gold on level %d at prestige %d (%gx), want a fresh start",
+			after.Progress.Gold, after.Progress.DungeonLevel, after.PrestigeLevel, after.PrestigeMultiplier)
+	}
+	if station := after.Factory.Station(models.StationAttack); *station != *models.NewStation() {
+		t.Errorf("reset attack station is %+v, want a new station", *station)
+	}
+	if after.ID != "alice" || after.Name != "Alice" || after.TimeZone != "Europe/Paris" || after.LoginStreak != before.LoginStreak {
+		t.Errorf("reset lost the player's identity: %q %q %q streak %d", after.ID, after.Name, after.TimeZone, after.LoginStreak)
+	}
+	if _, _, err := h.Server.AuthenticatePlayer("alice", token); err != nil {
+		t.Errorf("token stopped working after the reset: %v", err)
+	}
+}
+
+func TestResetPlayerKeepsConnectionsPointingAtPlayer(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	client := h.Dial("alice")
+	player, _ := h.Server.GetPlayer("alice")
+	h.Advance(5)
+
+	h.Server.ResetPlayer(player)
+	message := client.Next("gameState")
+	if message["reset"] != true {
+		t.Errorf("connection was sent %v, want a gameState with reset set", message)
+	}
+	sent, _ := message["player"].(map[string]interface{})
+	if progress, _ := sent["progress"].(map[string]interface{}); progress["totalBattles"] != float64(0) {
+		t.Errorf("reset state sent %v, want no battles fought", progress)
+	}
+
+	// The player is reset in place, so the game loop keeps battling the same one
+	if live, _ := h.Server.GetPlayer("alice"); live != player {
+		t.Fatal("reset replaced the player rather than resetting it in place")
+	}
+	h.Advance(3)
+	if battles := h.Player("alice").Progress.TotalBattles; battles != 3 {
+		t.Errorf("reset player fought %d battles in 3 ticks, want 3", battles)
+	}
+}
@@ -0,0 +1,56 @@
+package game
+
+import "github.com/evevioletrose-hash/idle-dungeon/internal/models"
+
+// RewardCalculator decides the gold and experience a battle pays, so reward
+// rules such as a double-experience weekend can be swapped in without
+// touching combat. It is called once a battle is over and must be safe to
+// call from several goroutines at once.
+type RewardCalculator interface {
+	Rewards(battle BattleOutcome) (gold, experience int)
+}
+
+// BattleOutcome describes a finished battle to a RewardCalculator.
+type BattleOutcome struct {
+	Hero         *models.Hero          // Hero that fought; it must not be changed
+	DungeonLevel int                   // Dungeon level fought on
+	Difficulty   models.DifficultyTier // Difficulty tier fought on
+	EnemyType    models.EnemyType      // Archetype of the enemy
+	IsBoss       bool                  // Whether the enemy was a boss
+	Victory      bool                  // Whether the hero won
+	DamageShare  float64               // Share of the enemy's HP the hero wore down, 1 on a victory
+}
+
+// DefaultRewards is the RewardCalculator the game uses unless configured
+// otherwise. Gold scales with dungeon level and the hero's loot, bosses pay
+// more, and so do harder difficulty tiers and enemy archetypes; experience
+// scales with level, tier, and archetype. A defeat pays part of the victory
+// gold for the share of the enemy worn down, so a near miss earns more than a
+// rout. A defeat's experience is reported but never granted.
+type DefaultRewards struct{}
+
+// defeatGoldShare is the fraction of the victory gold a defeat pays when the
+// hero nearly won; it shrinks with the share of the enemy's HP left standing.
+const defeatGoldShare = 0.5
+
+// Rewards returns the gold and experience the battle pays under the default rules.
+func (DefaultRewards) Rewards(battle BattleOutcome) (int, int) {
+	scale := difficulty(battle.Difficulty)
+	profile := enemyProfiles[battle.EnemyType]
+	if profile.reward == 0 {
+		profile = enemyProfiles[models.EnemyStandard]
+	}
+
+	gold := (10 + battle.DungeonLevel*2) * battle.Hero.Loot
+	if battle.IsBoss {
+		gold *= bossGoldMultiplier
+	}
+	gold = int(float64(gold) * scale.reward)
+	gold = int(float64(gold) * profile.reward)
+	experience := int(float64(5+battle.DungeonLevel) * scale.reward * profile.reward)
+
+	if !battle.Victory {
+		gold = int(float64(gold) * defeatGoldShare * battle.DamageShare)
+	}
+	return gold, experience
+}
@@ -0,0 +1,169 @@
+package game_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game/testutil"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// battleSeeds is how many seeded battles each comparison fights.
+const battleSeeds = 100
+
+var (
+	weakHero   = &models.Hero{HP: 1, Attack: 1, Loot: 5}                 // Loses every battle
+	strongHero = &models.Hero{HP: 1_000_000, Attack: 1_000_000, Loot: 5} // Wins every battle, with the same loot
+)
+
+// generousDefeats pays ten times a victory's default gold for a defeat,
+// however badly the hero lost, which the server must cap.
+type generousDefeats struct{}
+
+func (generousDefeats) Rewards(battle game.BattleOutcome) (int, int) {
+	won := battle
+	won.Victory, won.DamageShare = true, 1
+	gold, experience := game.DefaultRewards{}.Rewards(won)
+	if !battle.Victory {
+		gold *= 10
+	}
+	return gold, experience
+}
+
+// battleGold fights battleSeeds battles with the hero on the given level and
+// returns the gold they paid, failing the test when any ends other than won.
+func battleGold(t *testing.T, h *testutil.Harness, hero *models.Hero, dungeonLevel int, won bool) int {
+	t.Helper()
+	total := 0
+	for seed := int64(1); seed <= battleSeeds; seed++ {
+		result, _ := h.Server.ReplayBattle(hero, dungeonLevel, models.DifficultyNormal, seed)
+		if result.Victory != won {
+			t.Fatalf("battle on level %d with seed %d: victory %v, want %v", dungeonLevel, seed, result.Victory, won)
+		}
+		total += result.GoldReward
+	}
+	return total
+}
+
+func TestDefeatPaysNoMoreThanPreviousLevel(t *testing.T) {
+	config := game.DefaultConfig()
+	config.Rewards = generousDefeats{}
+	h := testutil.New(t, config)
+
+	// Levels 11 and 21 follow boss levels, whose bigger rewards must not raise the cap
+	for _, level := range []int{2, 5, 11, 21, 50} {
+		limit, _ := generousDefeats{}.Rewards(game.BattleOutcome{
+			Hero:         weakHero,
+			DungeonLevel: level - 1,
+			Difficulty:   models.DifficultyNormal,
+			EnemyType:    models.EnemyStandard,
+			Victory:      true,
+			DamageShare:  1,
+		})
+		if losing := battleGold(t, h, weakHero, level, false); losing > limit*battleSeeds {
+			t.Errorf("losing on level %d paid %d gold, more than %d battles against level %d's regular enemy", level, losing, battleSeeds, level-1)
+		}
+	}
+
+	// Level 1 has no level before it, so a defeat pays less than winning there
+	if losing, winning := battleGold(t, h, weakHero, 1, false), battleGold(t, h, strongHero, 1, true); losing >= winning {
+		t.Errorf("losing on level 1 paid %d gold, winning there paid %d; want less", losing, winning)
+	}
+}
+
+func TestSustainedLosingFallsBehindWinning(t *testing.T) {
+	for name, rewards := range map[string]game.RewardCalculator{
+		"default rewards":  nil,
+		"generous defeats": generousDefeats{},
+	} {
+		t.Run(name, func(t *testing.T) {
+			config := game.DefaultConfig()
+			config.Rewards = rewards
+			h := testutil.New(t, config)
+
+			// A hero stuck losing on level 20 against one winning its way up from level 19
+			losing, winning := 0, 0
+			for level := 19; level < 19+battleSeeds; level++ {
+				losing += battleGold(t, h, weakHero, 20, false)
+				winning += battleGold(t, h, strongHero, level, true)
+			}
+			if losing >= winning {
+				t.Errorf("sustained losing paid %d gold, winning paid %d; want less", losing, winning)
+			}
+		})
+	}
+}
+
+func TestDefaultRewards(t *testing.T) {
+	hero := &models.Hero{Loot: 2}
+	for _, test := range []struct {
+		name             string
+		battle           game.BattleOutcome
+		gold, experience int
+	}{
+		{"victory", game.BattleOutcome{DungeonLevel: 5, Difficulty: models.DifficultyNormal, EnemyType: models.EnemyStandard, Victory: true, DamageShare: 1}, 40, 10},
+		{"boss", game.BattleOutcome{DungeonLevel: 10, Difficulty: models.DifficultyNormal, EnemyType: models.EnemyStandard, IsBoss: true, Victory: true, DamageShare: 1}, 300, 15},
+		{"hard tier", game.BattleOutcome{DungeonLevel: 5, Difficulty: models.DifficultyHard, EnemyType: models.EnemyStandard, Victory: true, DamageShare: 1}, 120, 30},
+		{"tank", game.BattleOutcome{DungeonLevel: 5, Difficulty: models.DifficultyNormal, EnemyType: models.EnemyTank, Victory: true, DamageShare: 1}, 52, 13},
+		{"unknown archetype", game.BattleOutcome{DungeonLevel: 5, EnemyType: "dragon", Victory: true, DamageShare: 1}, 40, 10},
+		{"defeat halfway", game.BattleOutcome{DungeonLevel: 5, Difficulty: models.DifficultyNormal, EnemyType: models.EnemyStandard, DamageShare: 0.5}, 10, 10},
+		{"rout", game.BattleOutcome{DungeonLevel: 5, Difficulty: models.DifficultyNormal, EnemyType: models.EnemyStandard}, 0, 10},
+	} {
+		test.battle.Hero = hero
+		if gold, experience := (game.DefaultRewards{}).Rewards(test.battle); gold != test.gold || experience != test.experience {
+			t.Errorf("%s: rewards = %d gold and %d experience, want %d and %d", test.name, gold, experience, test.gold, test.experience)
+		}
+	}
+}
+
+// doubleExperience pays twice the default experience, as for an event weekend.
+type doubleExperience struct {
+	mutex    sync.Mutex
+	outcomes []game.BattleOutcome // Every battle asked about, in order
+}
+
+func (d *doubleExperience) Rewards(battle game.BattleOutcome) (int, int) {
+	d.mutex.Lock()
+	d.outcomes = append(d.outcomes, battle)
+	d.mutex.Unlock()
+	gold, experience := game.DefaultRewards{}.Rewards(battle)
+	return gold, 2 * experience
+}
+
+func TestServerUsesConfiguredRewards(t *testing.T) {
+	standard := testutil.New(t, game.DefaultConfig())
+	rewards := &doubleExperience{}
+	config := game.DefaultConfig()
+	config.Rewards = rewards
+	event := testutil.New(t, config)
+
+	want, _ := standard.Server.ReplayBattle(strongHero, 10, models.DifficultyNormal, 1)
+	got, _ := event.Server.ReplayBattle(strongHero, 10, models.DifficultyNormal, 1)
+	if got.ExpReward != 2*want.ExpReward || got.GoldReward != want.GoldReward {
+		t.Errorf("event battle paid %d gold and %d experience, want %d and %d", got.GoldReward, got.ExpReward, want.GoldReward, 2*want.ExpReward)
+	}
+
+	if len(rewards.outcomes) != 1 {
+		t.Fatalf("calculator asked about %d battles, want 1", len(rewards.outcomes))
+	}
+	outcome := rewards.outcomes[0]
+	if outcome.Hero != strongHero || outcome.DungeonLevel != 10 || !outcome.IsBoss || !outcome.Victory || outcome.DamageShare != 1 || outcome.EnemyType != want.EnemyType {
+		t.Errorf("calculator was told %+v about a won boss battle on level 10", outcome)
+	}
+}
+
+func TestConfiguredRewardsApplyToPlayers(t *testing.T) {
+	experience := func(rewards game.RewardCalculator) int {
+		config := game.DefaultConfig()
+		config.Rewards = rewards
+		h := testutil.New(t, config)
+		h.Connect("alice")
+		h.Advance(20)
+		return h.Player("alice").Progress.Experience
+	}
+	standard, event := experience(nil), experience(&doubleExperience{})
+	if standard == 0 || event != 2*standard {
+		t.Errorf("20 event ticks earned %d experience, want twice the standard %d", event, standard)
+	}
+}
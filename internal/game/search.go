@@ -0,0 +1,44 @@
+package game
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// SearchPlayers returns up to limit players whose name starts with prefix,
+// ignoring case, ordered by name and then by ID. Matches are copied out in a
+// single scan under the game-state read lock and sorted afterwards. Only
+// players in memory are searched, so evicted players are not found until
+// they return. The result is empty, never nil, when nothing matches.
+func (s *Server) SearchPlayers(prefix string, limit int) []models.SearchResult {
+	prefix = strings.ToLower(prefix)
+	results := []models.SearchResult{}
+	s.gameState.View(func() {
+		for _, player := range s.gameState.Players {
+			if !strings.HasPrefix(strings.ToLower(player.Name), prefix) {
+				continue
+			}
+			results = append(results, models.SearchResult{
+				ID:              player.ID,
+				Name:            s.displayName(player.Name),
+				MaxDungeonLevel: player.Progress.MaxDungeonLevel,
+				PrestigeLevel:   player.PrestigeLevel,
+				Guild:           player.Guild,
+			})
+		}
+	})
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if nameA, nameB := strings.ToLower(a.Name), strings.ToLower(b.Name); nameA != nameB {
+			return nameA < nameB
+		}
+		return a.ID < b.ID
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
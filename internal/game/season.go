@@ -0,0 +1,89 @@
+package game
+
+import (
+	"fmt"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// HallOfFame returns every archived season, oldest first. The result is
+// never nil, so it encodes as an empty array before the first season ends.
+func (s *Server) HallOfFame() []models.Season {
+	hallOfFame := []models.Season{}
+	s.gameState.View(func() {
+		for _, season := range s.gameState.HallOfFame {
+			// Archived names are shown as current ones are, even if stored before validation
+			season.Top = append([]models.LeaderboardEntry(nil), season.Top...)
+			for i := range season.Top {
+				season.Top[i].Name = s.displayName(season.Top[i].Name)
+			}
+			hallOfFame = append(hallOfFame, season)
+		}
+	})
+	return hallOfFame
+}
+
+// ResetSeason ends the current season: the leaderboard's top
+// models.HallOfFameSize players are archived in the hall of fame under the
+// next season number, and then every player is reset as by ResetPlayer,
+// except that their prestige level and multiplier are kept. With eviction
+// enabled, players evicted from memory are loaded back first so they are
+// reset too.
+//
+// The game loop is paused for the reset, and all players are reset in a
+// single pass under the game-state write lock, so no battle lands on a
+// half-reset world. Each open connection is then sent a gameState message
+// with its reset player, reset set to true, and the new season number. It
+// returns the archived season.
+func (s *Server) ResetSeason() (models.Season, error) {
+	var stored map[string]*models.Player
+	if s.loader != nil {
+		state, err := s.persister.Load()
+		if err != nil {
+			return models.Season{}, fmt.Errorf("load evicted players: %w", err)
+		}
+		stored = state.GetAllPlayers()
+	}
+
+	s.loopMutex.Lock()
+	defer s.loopMutex.Unlock()
+
+	if len(stored) > 0 {
+		s.gameState.Update(func() {
+			for _, player := range stored {
+				if _, resident := s.gameState.Players[player.ID]; !resident {
+					s.validatePlayer(player, "load")
+					s.residentPlayer(player)
+				}
+			}
+		})
+	}
+
+	season := models.Season{
+		EndedAt: s.clock.Now(),
+		Top:     s.Leaderboard(models.HallOfFameSize),
+	}
+	s.gameState.Update(func() {
+		season.Number = 1
+		if count := len(s.gameState.HallOfFame); count > 0 {
+			season.Number = s.gameState.HallOfFame[count-1].Number + 1
+		}
+		s.gameState.HallOfFame = append(s.gameState.HallOfFame, season)
+
+		for _, player := range s.gameState.Players {
+			prestigeLevel, prestige := player.PrestigeLevel, player.PrestigeMultiplier
+			s.resetInPlace(player, season.EndedAt)
+			player.PrestigeLevel, player.PrestigeMultiplier = prestigeLevel, prestige
+		}
+	})
+
+	s.logger.Info("season reset", "event", "season", "season", season.Number,
+		"players", s.PlayerCount(), "hall_of_fame", len(season.Top))
+	for _, player := range s.connectedPlayers() {
+		s.SendToPlayer(player.ID, s.MarshalPlayerMessage("gameState", player, map[string]interface{}{
+			"reset":  true,
+			"season": season.Number + 1,
+		}))
+	}
+	return season, nil
+}
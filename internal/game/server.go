@@ -2,125 +2,752 @@
 package game
 
 import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/rand"
 	"encoding/json"
-	"net/http"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
 	"github.com/gorilla/websocket"
 )
 
+var (
+	// errUnknownClient is returned when writing to a connection that is not registered.
+	errUnknownClient = errors.New("connection is not a registered client")
+	// errSendQueueFull is returned when a message is dropped because the client has fallen too far behind.
+	errSendQueueFull = errors.New("client send queue full")
+	// ErrServerFull is returned when a connection would exceed MaxClients.
+	ErrServerFull = errors.New("server full")
+)
+
 // Server manages the game state and handles multiplayer connections.
-// It processes the game loop, manages WebSocket connections, and broadcasts updates.
+// It processes the game loop, manages WebSocket connections, and sends each client its player's updates.
 type Server struct {
-	gameState *models.GameState                   // Central game state containing all players
-	clients   map[*websocket.Conn]*models.Player // Map of WebSocket connections to players
-	broadcast chan []byte                         // Channel for broadcasting messages to all clients
-	register  chan *websocket.Conn               // Channel for registering new client connections
-	upgrader  websocket.Upgrader                 // WebSocket upgrader for HTTP connections
-	mutex     sync.RWMutex                       // Mutex for thread-safe access to clients map
-}
-
-// NewServer creates and initializes a new game server.
-func NewServer() *Server {
-	return &Server{
-		gameState: models.NewGameState(),
-		clients:   make(map[*websocket.Conn]*models.Player),
-		broadcast: make(chan []byte),
+	config    Config                         // Tunable game settings
+	logger    *slog.Logger                   // Structured logger for server events
+	persister models.Persister               // Storage for the game state (nil keeps state in memory only)
+	loader    models.PlayerLoader            // Reloads evicted players from the persister (nil when eviction is disabled)
+	gameState *models.GameState              // Central game state containing all players
+	guilds    map[string]map[string]struct{} // Member IDs of each guild by name, guarded by the game-state lock
+	rng       *lockedRand                    // Random source for battles, seeded at startup
+	clock     Clock                          // Source of the current time for game logic
+	rewards   RewardCalculator               // Gold and experience rules for battles
+	clients   map[*websocket.Conn]*client    // Map of WebSocket connections to registered clients
+	updates   chan struct{}                  // Signals the sender to push each client its player's state
+	register  chan *websocket.Conn           // Channel for registering new client connections
+	upgrader  websocket.Upgrader             // WebSocket upgrader for HTTP connections
+	mutex     sync.RWMutex                   // Mutex for thread-safe access to clients map
+	loopMutex sync.Mutex                     // Held while a tick is processed; admin operations take it to pause the loop
+	running   sync.WaitGroup                 // Background goroutines started by Start
+	started   atomic.Bool                    // Set once the game loop has completed a tick, cleared when it stops
+	ticks     int                            // Ticks run so far, guarded by loopMutex
+	sends     uint64                         // Calls to sendUpdates so far; touched only by sendUpdates
+
+	upgradeLimiter *rateLimiter // Per-player limit on upgrade requests (nil when disabled)
+	metrics        *metrics     // Prometheus collectors for the live game
+	exportSecret   []byte       // Key player exports are signed with
+
+	saveMutex       sync.Mutex // Held while the persister saves, so saves never overlap
+	restores        int        // Backups restored so far, guarded by both loopMutex and saveMutex
+	saveFailures    int        // Saves failed in a row since the last success, guarded by saveStatusMutex
+	lastSaveError   error      // Error of the latest failed save, guarded by saveStatusMutex
+	lastSaved       time.Time  // When a save last succeeded, guarded by saveStatusMutex
+	saveStatusMutex sync.Mutex // Mutex for thread-safe access to the save outcome
+
+	stats      *models.ServerStats // Aggregate statistics last computed by Stats (nil until the first call)
+	statsMutex sync.Mutex          // Mutex for thread-safe access to stats
+
+	pendingNotifications  map[string][]models.Notification // Notifications queued per player for the end of the tick
+	deferredNotifications []deferredNotification           // Unbatched notifications raised under the game-state lock, waiting for it to be released
+	notifyMutex           sync.Mutex                       // Mutex for thread-safe access to pendingNotifications and deferredNotifications
+}
+
+// NewServer creates and initializes a new game server with the given settings.
+// When a persister is provided, the saved game state is loaded from it; a nil
+// persister starts from an empty state that is kept in memory only.
+func NewServer(config Config, persister models.Persister) (*Server, error) {
+	gameState := models.NewGameState()
+	if persister != nil {
+		loaded, err := persister.Load()
+		if err != nil {
+			return nil, fmt.Errorf("load game state: %w", err)
+		}
+		gameState = loaded
+	}
+
+	seed := config.RandomSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano() // Seeded from the real time even under a test clock
+	}
+
+	clock := config.Clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+
+	rewards := config.Rewards
+	if rewards == nil {
+		rewards = DefaultRewards{}
+	}
+
+	logger := config.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	exportSecret := config.ExportSecret
+	if len(exportSecret) == 0 {
+		exportSecret = make([]byte, 32)
+		if _, err := rand.Read(exportSecret); err != nil {
+			return nil, fmt.Errorf("generate export secret: %w", err)
+		}
+		logger.Warn("EXPORT_SECRET is not set, player exports will not import after a restart")
+	}
+
+	if config.TickInterval <= 0 {
+		config.TickInterval = DefaultConfig().TickInterval
+	}
+	if config.ExportTTL <= 0 {
+		config.ExportTTL = DefaultConfig().ExportTTL
+	}
+	config.BroadcastEveryNTicks = max(1, config.BroadcastEveryNTicks)
+	if config.BattleWorkers <= 0 {
+		config.BattleWorkers = runtime.GOMAXPROCS(0)
+	}
+	if config.StartingDungeonLevel < 1 {
+		config.StartingDungeonLevel = 1
+	}
+	config.StartingGold = max(0, config.StartingGold)
+	if config.DisplayNameLength <= 0 {
+		config.DisplayNameLength = models.MaxNameLength
+	}
+	if config.ResearchTree == nil {
+		config.ResearchTree = DefaultResearchTree()
+	}
+	if err := validateResearchTree(config.ResearchTree); err != nil {
+		return nil, fmt.Errorf("research tree: %w", err)
+	}
+	if config.ZoneSize <= 0 {
+		config.ZoneSize = defaultZoneSize
+	}
+	if err := validateLootTables(config.LootTables); err != nil {
+		return nil, fmt.Errorf("loot tables: %w", err)
+	}
+	if config.CompressionLevel < flate.HuffmanOnly || config.CompressionLevel > flate.BestCompression {
+		config.CompressionLevel = flate.BestSpeed
+	}
+	config.StationCurves = validStationCurves(config.StationCurves, logger)
+
+	var loader models.PlayerLoader
+	if config.EvictAfter > 0 {
+		if loader, _ = persister.(models.PlayerLoader); loader == nil {
+			logger.Warn("EVICT_AFTER needs a persister that can load single players, idle players will stay in memory")
+		}
+	}
+
+	var upgradeLimiter *rateLimiter
+	if config.UpgradeRateLimit > 0 {
+		upgradeLimiter = newRateLimiter(config.UpgradeRateLimit)
+	}
+
+	s := &Server{
+		config:    config,
+		logger:    logger,
+		persister: persister,
+		loader:    loader,
+		gameState: gameState,
+		rng:       newLockedRand(seed),
+		clock:     clock,
+		rewards:   rewards,
+		clients:   make(map[*websocket.Conn]*client),
+		updates:   make(chan struct{}),
 		register:  make(chan *websocket.Conn),
-		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true // Allow all origins for development
-			},
-		},
+
+		upgradeLimiter:       upgradeLimiter,
+		exportSecret:         exportSecret,
+		pendingNotifications: make(map[string][]models.Notification),
+	}
+	s.upgrader.CheckOrigin = s.OriginAllowed
+	s.upgrader.EnableCompression = config.CompressMessages
+	s.upgrader.Subprotocols = Encodings
+	s.metrics = newMetrics(s)
+	for _, player := range gameState.Players {
+		s.validatePlayer(player, "load")
+	}
+	s.gameState.Update(s.rebuildGuilds)
+	return s, nil
+}
+
+// validatePlayer repairs any stats of a player read from storage, a backup, or
+// an export that game logic could never have produced, logging the invalid
+// fields so corrupt data is noticed. source names where the player came from.
+func (s *Server) validatePlayer(player *models.Player, source string) {
+	if err := player.Validate(); err != nil {
+		s.logger.Warn("repaired invalid player state", "event", "validate", "player_id", player.ID, "source", source, "error", err)
 	}
 }
 
 // Start begins the game server operations including the game loop and message handling.
-func (s *Server) Start() {
-	go s.gameLoop()
-	go s.handleMessages()
+// The background goroutines run until ctx is cancelled; Wait blocks until they have exited.
+func (s *Server) Start(ctx context.Context) {
+	s.run(func() { s.gameLoop(ctx) })
+	s.run(func() { s.handleMessages(ctx) })
+	if s.persister != nil {
+		s.run(func() { s.persistLoop(ctx) })
+	}
+	if s.loader != nil {
+		s.run(func() { s.evictLoop(ctx) })
+	}
+	if s.upgradeLimiter != nil {
+		s.run(func() { s.upgradeLimiter.evictLoop(ctx, s.clock) })
+	}
 }
 
-// gameLoop runs continuously to process all players and broadcast updates.
-// It ticks every second to simulate the idle game progression.
-func (s *Server) gameLoop() {
-	ticker := time.NewTicker(1 * time.Second)
+// run starts fn in a goroutine tracked by Wait.
+func (s *Server) run(fn func()) {
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		fn()
+	}()
+}
+
+// Wait blocks until every goroutine started by Start has exited after its
+// context was cancelled. A tick in progress is always finished first, so the
+// state is consistent for a final Save once Wait returns.
+func (s *Server) Wait() {
+	s.running.Wait()
+}
+
+// gameLoop runs continuously to process connected players and broadcast updates.
+// It calls Tick every TickInterval to simulate the idle game progression. It
+// stops when ctx is cancelled, never in the middle of a tick.
+func (s *Server) gameLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.config.TickInterval)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		players := s.gameState.GetAllPlayers()
-		
-		// Process each player's battle
+	defer s.started.Store(false)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.Tick()
+		s.started.Store(true)
+	}
+}
+
+// Tick runs a single iteration of the game loop: every connected player fights
+// one battle per hero, their notifications are delivered, and clients are sent
+// their updated state. Players without a connection are not battled here; they
+// catch up through offline progress when they return. The game loop calls it on
+// every tick; tests can call it directly to advance the game synchronously.
+// With BroadcastEveryNTicks above 1, only every Nth tick delivers
+// notifications and updates, covering the ticks since the last broadcast.
+func (s *Server) Tick() {
+	s.loopMutex.Lock()
+
+	// Process each connected player's battle
+	s.processPlayers(s.connectedPlayers())
+	s.ticks++
+	broadcast := s.ticks%s.config.BroadcastEveryNTicks == 0
+	s.loopMutex.Unlock()
+
+	if !broadcast {
+		return // Queued notifications and changed state wait for the next broadcast
+	}
+
+	// Deliver each player's notifications from this tick as one message
+	s.flushNotifications()
+
+	// Send each connected client its own player's new state
+	s.requestUpdates()
+}
+
+// processPlayers runs processPlayer for every given player, spread across up
+// to BattleWorkers goroutines that take players from a shared channel, and
+// returns once all of them are done. Workers can run side by side because
+// processPlayer only reads a player under the game-state read lock and writes
+// it under the write lock, and every other shared structure it touches (the
+// random source, notifications, metrics) has its own lock.
+func (s *Server) processPlayers(players []*models.Player) {
+	workers := min(s.config.BattleWorkers, len(players))
+	if workers <= 1 {
 		for _, player := range players {
 			s.processPlayer(player)
 		}
+		return
+	}
 
-		// Broadcast updates to all connected clients
-		gameUpdate, _ := json.Marshal(map[string]interface{}{
-			"type":    "update",
-			"players": players,
-		})
-		
-		select {
-		case s.broadcast <- gameUpdate:
-		default:
-		}
+	jobs := make(chan *models.Player)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for player := range jobs {
+				s.processPlayer(player)
+			}
+		}()
+	}
+	for _, player := range players {
+		jobs <- player
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// requestUpdates asks the sender to push each client its player's state.
+// The request is dropped if the sender is still busy with the previous one.
+func (s *Server) requestUpdates() {
+	select {
+	case s.updates <- struct{}{}:
+	default:
 	}
 }
 
-// handleMessages manages the broadcasting of messages to all connected clients.
-func (s *Server) handleMessages() {
+// handleMessages sends player updates to connected clients whenever they are
+// requested. When ctx is cancelled it sends every client a close frame and
+// closes its connection.
+func (s *Server) handleMessages(ctx context.Context) {
 	for {
 		select {
-		case message := <-s.broadcast:
-			s.mutex.RLock()
-			for client := range s.clients {
-				err := client.WriteMessage(websocket.TextMessage, message)
-				if err != nil {
-					client.Close()
-					delete(s.clients, client)
-				}
-			}
-			s.mutex.RUnlock()
+		case <-ctx.Done():
+			s.closeClients()
+			return
+		case <-s.updates:
+			s.sendUpdates()
+		}
+	}
+}
+
+// sendUpdates sends each client an update carrying only its own player, and
+// only when that player has changed since the last update the client was sent.
+// Other players' state is never sent, so bandwidth grows with the number of
+// clients rather than its square, and each update carries only the fields
+// ticks change; see MarshalUpdate. Updates are only queued, so a slow client
+// delays no one else; an update dropped from a full queue is retried on the
+// next request. With MaxUpdatesPerBroadcast set, the clients left over past
+// the cap are sent theirs by later calls, round-robin: those updated longest
+// ago go first.
+func (s *Server) sendUpdates() {
+	type pendingUpdate struct {
+		conn   *websocket.Conn
+		client *client
+		update []byte
+	}
+
+	s.sends++
+	updates := make(map[*models.Player][]byte)
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	var pending []pendingUpdate
+	for conn, client := range s.clients {
+		update, encoded := updates[client.player]
+		if !encoded {
+			update = s.MarshalUpdate(client.player)
+			updates[client.player] = update
+		}
+		if !bytes.Equal(update, client.lastUpdate) {
+			pending = append(pending, pendingUpdate{conn, client, update})
+		}
+	}
+
+	if limit := s.config.MaxUpdatesPerBroadcast; limit > 0 && len(pending) > limit {
+		sort.Slice(pending, func(i, j int) bool {
+			return pending[i].client.updatedAt < pending[j].client.updatedAt
+		})
+		pending = pending[:limit]
+	}
+	for _, p := range pending {
+		if s.enqueue(p.conn, p.client, p.update) {
+			p.client.lastUpdate = p.update
+			p.client.updatedAt = s.sends
+		}
+	}
+}
+
+// enqueue queues a message for a client and reports whether it was queued.
+// After maxQueueOverflows dropped messages in a row the connection is closed,
+// which makes its read loop fail and unregister the client.
+func (s *Server) enqueue(conn *websocket.Conn, client *client, message []byte) bool {
+	if client.enqueue(message) {
+		return true
+	}
+	if client.overflows.Add(1) == maxQueueOverflows {
+		s.logger.Warn("disconnecting client, send queue full", "event", "disconnect",
+			"player_id", client.player.ID, "remote_addr", conn.RemoteAddr().String())
+		conn.Close()
+	}
+	return false
+}
+
+// closeClients tells every connected client the server is going away and closes its connection.
+// Each connection's read loop then fails and unregisters the client as usual.
+func (s *Server) closeClients() {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	closeMessage := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	for conn := range s.clients {
+		conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second))
+		conn.Close()
+	}
+}
+
+// isConnected reports whether the player has at least one open connection.
+func (s *Server) isConnected(playerID string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, client := range s.clients {
+		if client.player.ID == playerID {
+			return true
 		}
 	}
+	return false
+}
+
+// connectedPlayers returns each player with at least one open connection, once.
+func (s *Server) connectedPlayers() []*models.Player {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	seen := make(map[*models.Player]bool, len(s.clients))
+	players := make([]*models.Player, 0, len(s.clients))
+	for _, client := range s.clients {
+		if !seen[client.player] {
+			seen[client.player] = true
+			players = append(players, client.player)
+		}
+	}
+	return players
 }
 
 // GetOrCreatePlayer retrieves an existing player or creates a new one if not found.
 // This method is thread-safe and handles player initialization.
-func (s *Server) GetOrCreatePlayer(playerID string) *models.Player {
-	if player, exists := s.gameState.GetPlayer(playerID); exists {
-		player.LastSeen = time.Now()
-		return player
+// For an existing player it also simulates the battles missed since they were
+// last seen and returns the resulting gains, or nil if none were missed. A
+// player who is already connected is battled by the game loop instead, so no
+// time is simulated for them twice.
+// Either way the access counts as a daily login, granting the day's login
+// bonus the first time each day; the player is notified of the bonus.
+// An evicted player is reloaded from storage. If that fails, the returned
+// player is a new one kept out of the game state, so it is never saved over
+// the stored progress.
+func (s *Server) GetOrCreatePlayer(playerID string) (*models.Player, *models.OfflineGains) {
+	now := s.clock.Now()
+	player, exists, err := s.findPlayer(playerID)
+	if err != nil {
+		s.logger.Error("failed to reload evicted player", "event", "evict", "player_id", playerID, "error", err)
+	}
+	if !exists {
+		// Create new player with default values
+		player = s.newPlayer(playerID, now)
+	}
+
+	offline := exists && !s.isConnected(playerID)
+	var gains *models.OfflineGains
+	var bonus, streak int
+	s.gameState.Update(func() {
+		if err == nil {
+			player = s.residentPlayer(player)
+		}
+		if player.Difficulty == "" {
+			player.Difficulty = models.DifficultyNormal // Players saved before difficulty tiers existed
+		}
+		if offline {
+			gains = s.applyOfflineProgress(player, now)
+		}
+		bonus = s.applyDailyLogin(player, now)
+		streak = player.LoginStreak
+	})
+	s.sendDeferredNotifications()
+
+	if bonus > 0 {
+		s.logger.Info("granted daily login bonus", "event", "login", "player_id", playerID, "streak", streak, "gold", bonus)
+		s.notify(playerID, models.Notification{
+			Type: "dailyLogin",
+			Data: map[string]interface{}{"streak": streak, "gold": bonus},
+		})
 	}
+	return player, gains
+}
 
-	// Create new player with default values
+// newPlayer creates a player with the configured starting gold, dungeon level,
+// and station levels, last seen at now, showing the passive income those
+// stations pay.
+func (s *Server) newPlayer(playerID string, now time.Time) *models.Player {
 	player := models.NewPlayer(playerID)
-	s.gameState.SetPlayer(player)
+	player.LastSeen = now
+	player.Progress.Gold = s.config.StartingGold
+	player.Progress.DungeonLevel = s.config.StartingDungeonLevel
+	player.Progress.MaxDungeonLevel = s.config.StartingDungeonLevel
+
+	for stationType, level := range s.config.StartingStationLevels {
+		station := player.Factory.Station(stationType)
+		if s.config.MaxStationLevel > 0 {
+			level = min(level, s.config.MaxStationLevel)
+		}
+		if station == nil || level <= 1 {
+			continue
+		}
+		curve := s.stationCurve(stationType)
+		station.Level = level
+		station.Multiplier = stationMultiplier(curve, level)
+		station.Cost = stationCost(curve, level)
+	}
+	player.Progress.GoldPerTick = s.goldPerTick(player)
 	return player
 }
 
-// AddClient registers a new WebSocket client connection with the server.
-func (s *Server) AddClient(conn *websocket.Conn, player *models.Player) {
+// View runs fn while holding the game-state read lock. Handlers use it to
+// read or encode players without racing the game loop.
+func (s *Server) View(fn func()) {
+	s.gameState.View(fn)
+}
+
+// Hero builds the player's current hero from their factory, level, and items.
+func (s *Server) Hero(player *models.Player) *models.Hero {
+	var hero *models.Hero
+	s.gameState.View(func() {
+		hero = s.createHero(player)
+	})
+	return hero
+}
+
+// HeroBreakdown builds the player's current hero like Hero and explains how
+// each of its stats follows from the player's stations, level, prestige,
+// guild, buffs, and items.
+func (s *Server) HeroBreakdown(player *models.Player) *models.HeroBreakdown {
+	var breakdown *models.HeroBreakdown
+	s.gameState.View(func() {
+		breakdown = s.heroBreakdownAt(player, s.clock.Now())
+	})
+	return breakdown
+}
+
+// MarshalUpdate encodes the routine update message for a player, reading the
+// player under the game-state lock. It carries the player's models.PlayerUpdate
+// rather than the full player, which gameState messages carry, so the
+// player's histories are not resent every tick.
+func (s *Server) MarshalUpdate(player *models.Player) []byte {
+	var message []byte
+	s.gameState.View(func() {
+		message, _ = json.Marshal(map[string]interface{}{"type": "update", "player": player.Update()})
+	})
+	return message
+}
+
+// MarshalPlayerMessage encodes a message of the given type carrying a player,
+// reading the player under the game-state lock. Extra fields are added to the
+// message alongside the player.
+func (s *Server) MarshalPlayerMessage(messageType string, player *models.Player, extra map[string]interface{}) []byte {
+	msg := map[string]interface{}{
+		"type":   messageType,
+		"player": player,
+	}
+	for key, value := range extra {
+		msg[key] = value
+	}
+
+	var message []byte
+	s.gameState.View(func() {
+		message, _ = json.Marshal(msg)
+	})
+	return message
+}
+
+// SetTimeZone validates and stores the IANA time zone used for a player's daily resets.
+func (s *Server) SetTimeZone(player *models.Player, timeZone string) error {
+	if err := models.ValidateTimeZone(timeZone); err != nil {
+		return err
+	}
+	s.gameState.Update(func() {
+		player.TimeZone = timeZone
+	})
+	return nil
+}
+
+// SetName validates and applies a player's chosen display name, then pushes an
+// update so the player's clients show it right away.
+func (s *Server) SetName(player *models.Player, name string) error {
+	name, err := models.ValidateName(name)
+	if err != nil {
+		return err
+	}
+	s.gameState.Update(func() {
+		player.Name = name
+	})
+
+	s.requestUpdates()
+	return nil
+}
+
+// Ready reports whether the game loop is running and has completed at least one tick.
+func (s *Server) Ready() bool {
+	return s.started.Load()
+}
+
+// PlayerCount returns the number of players in the game state.
+func (s *Server) PlayerCount() int {
+	var count int
+	s.gameState.View(func() {
+		count = len(s.gameState.Players)
+	})
+	return count
+}
+
+// ClientCount returns the number of open WebSocket connections.
+func (s *Server) ClientCount() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.clients)
+}
+
+// GetPlayer retrieves an existing player without creating one.
+// An evicted player is reloaded from storage; a failed reload is logged and
+// reported as not found.
+func (s *Server) GetPlayer(playerID string) (*models.Player, bool) {
+	player, exists, err := s.findPlayer(playerID)
+	if err != nil {
+		s.logger.Error("failed to reload evicted player", "event", "evict", "player_id", playerID, "error", err)
+		return nil, false
+	}
+	return player, exists
+}
+
+// Players returns the public profiles of the players with the given IDs, keyed
+// by ID, for showing to other players. Profiles of players in memory are built
+// under a single read lock; evicted ones are read from storage without being
+// reloaded. Unknown IDs are skipped.
+func (s *Server) Players(ids []string) map[string]models.PlayerProfile {
+	players := make(map[string]models.PlayerProfile, len(ids))
+	s.gameState.View(func() {
+		for _, id := range ids {
+			if player, exists := s.gameState.Players[id]; exists {
+				players[id] = s.profile(player)
+			}
+		}
+	})
+	if s.loader != nil {
+		for _, id := range ids {
+			if _, found := players[id]; found {
+				continue
+			}
+			player, exists, err := s.loader.LoadPlayer(id)
+			if err != nil {
+				s.logger.Error("failed to read evicted player", "event", "evict", "player_id", id, "error", err)
+				continue
+			}
+			if exists {
+				s.validatePlayer(player, "load")
+				players[id] = s.profile(player)
+			}
+		}
+	}
+	return players
+}
+
+// profile returns the player's public profile with their display name.
+func (s *Server) profile(player *models.Player) models.PlayerProfile {
+	profile := player.Profile()
+	profile.Name = s.displayName(profile.Name)
+	return profile
+}
+
+// AddClient registers a new WebSocket client connection with the server,
+// which sends it every message in the given encoding. The goroutine writing to
+// the connection exits once ctx, scoped to the connection, is cancelled, even
+// if RemoveClient is never called. It returns ErrServerFull, leaving the connection unregistered, when the
+// server already has MaxClients connections, and ErrBanned when the player
+// was banned since authenticating.
+//
+// The banlist is checked after the connection is registered and the client
+// lock released, so the game state is never read under the client lock. A ban
+// is recorded before KickPlayer looks for connections, so a connection
+// registering concurrently is either found and closed there or sees the ban
+// here and is unregistered again.
+func (s *Server) AddClient(ctx context.Context, conn *websocket.Conn, player *models.Player, encoding Encoding) error {
+	if err := s.registerClient(ctx, conn, player, encoding); err != nil {
+		return err
+	}
+	if s.IsBanned(player.ID) {
+		s.RemoveClient(conn)
+		return ErrBanned
+	}
+	return nil
+}
+
+// registerClient adds the connection to the clients map, returning
+// ErrServerFull instead when the server already has MaxClients connections.
+func (s *Server) registerClient(ctx context.Context, conn *websocket.Conn, player *models.Player, encoding Encoding) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	s.clients[conn] = player
+	if s.full() {
+		return ErrServerFull
+	}
+	if s.config.CompressMessages {
+		// Only takes effect when the client negotiated compression. The level is
+		// set before the client's writeLoop starts, so it never changes under a write
+		conn.EnableWriteCompression(true)
+		conn.SetCompressionLevel(s.config.CompressionLevel)
+	}
+	s.clients[conn] = newClient(ctx, conn, player, encoding)
+	s.metrics.connections.Inc()
+	return nil
+}
+
+// AtCapacity reports whether the server already has MaxClients connections.
+// It lets handlers turn clients away before upgrading; AddClient still makes
+// the final check, since other connections may arrive in between.
+func (s *Server) AtCapacity() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.full()
+}
+
+// full reports whether the clients map has reached MaxClients. The caller holds mutex.
+func (s *Server) full() bool {
+	return s.config.MaxClients > 0 && len(s.clients) >= s.config.MaxClients
 }
 
 // RemoveClient unregisters a WebSocket client connection from the server.
 func (s *Server) RemoveClient(conn *websocket.Conn) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	delete(s.clients, conn)
+	if client, exists := s.clients[conn]; exists {
+		client.stop()
+		delete(s.clients, conn)
+		s.metrics.connections.Dec()
+	}
 }
 
 // GetPlayerByConnection retrieves the player associated with a WebSocket connection.
 func (s *Server) GetPlayerByConnection(conn *websocket.Conn) *models.Player {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
-	return s.clients[conn]
+	if client, exists := s.clients[conn]; exists {
+		return client.player
+	}
+	return nil
+}
+
+// Logger returns the server's structured logger, for handlers to log with.
+func (s *Server) Logger() *slog.Logger {
+	return s.logger
 }
 
 // GetUpgrader returns the WebSocket upgrader for converting HTTP connections.
@@ -128,7 +755,31 @@ func (s *Server) GetUpgrader() *websocket.Upgrader {
 	return &s.upgrader
 }
 
-// BroadcastToClient sends a message to a specific WebSocket connection.
+// BroadcastToClient queues a message for a specific WebSocket connection.
+// The connection must be registered with AddClient so the message is written
+// in order with updates to the same connection. It does not wait for the
+// write, and returns an error when the client's send queue is full.
 func (s *Server) BroadcastToClient(conn *websocket.Conn, message []byte) error {
-	return conn.WriteMessage(websocket.TextMessage, message)
-}
\ No newline at end of file
+	s.mutex.RLock()
+	client, exists := s.clients[conn]
+	s.mutex.RUnlock()
+	if !exists {
+		return errUnknownClient
+	}
+	if !s.enqueue(conn, client, message) {
+		return errSendQueueFull
+	}
+	return nil
+}
+
+// SendToPlayer queues a message for every WebSocket connection belonging to a player.
+// Players without an open connection are silently skipped.
+func (s *Server) SendToPlayer(playerID string, message []byte) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for conn, client := range s.clients {
+		if client.player.ID == playerID {
+			s.enqueue(conn, client, message)
+		}
+	}
+}
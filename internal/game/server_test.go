@@ -0,0 +1,138 @@
+package game_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game/testutil"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/handlers"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/storage"
+	"github.com/gorilla/websocket"
+)
+
+// waitForGoroutines waits for the number of goroutines to drop to at most
+// want, reporting the last count seen.
+func waitForGoroutines(want int) int {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		count := runtime.NumGoroutine()
+		if count <= want || time.Now().After(deadline) {
+			return count
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStartStopLeavesNoGoroutines(t *testing.T) {
+	persister, err := storage.NewSQLitePersister(filepath.Join(t.TempDir(), "game.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	defer persister.Close()
+
+	config := game.DefaultConfig()
+	config.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	config.ExportSecret = []byte("test")
+	config.TickInterval = 5 * time.Millisecond
+	config.SaveInterval = 5 * time.Millisecond
+	config.EvictAfter = time.Hour
+	config.UpgradeRateLimit = 10
+	server, err := game.NewServer(config, persister)
+	if err != nil {
+		t.Fatalf("create server: %v", err)
+	}
+	httpServer := httptest.NewServer(handlers.WebSocketHandler(server))
+	defer httpServer.Close()
+	baseline := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	server.Start(ctx)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http")+"?playerID=alice", nil)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+	for i := 0; i < 3; i++ { // The initial state, then updates from running ticks
+		if _, _, err := conn.ReadMessage(); err != nil {
+			t.Fatalf("read message %d: %v", i, err)
+		}
+	}
+
+	cancel()
+	stopped := make(chan struct{})
+	go func() {
+		server.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop after its context was cancelled")
+	}
+
+	// The connection is closed as the server goes away
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+				t.Errorf("connection ended with %v, want a going away close", err)
+			}
+			break
+		}
+	}
+	conn.Close()
+
+	if err := server.Save(); err != nil {
+		t.Fatalf("final save: %v", err)
+	}
+	if _, exists, err := persister.LoadPlayer("alice"); err != nil || !exists {
+		t.Errorf("player not saved on shutdown: %v, %v", exists, err)
+	}
+	if count := waitForGoroutines(baseline); count > baseline {
+		t.Errorf("%d goroutines still running after shutdown, want at most %d", count, baseline)
+	}
+}
+
+func TestConnectionChurnLeavesNoGoroutines(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	h.Dial("warmup").Close() // Let the HTTP server start what it keeps running between connections
+	waitForClients(t, h, 0)
+	baseline := runtime.NumGoroutine()
+
+	for round := 0; round < 5; round++ {
+		var clients []*testutil.Client
+		for i := 0; i < 20; i++ {
+			clients = append(clients, h.Dial(fmt.Sprintf("player-%d-%d", round, i)))
+		}
+		h.Advance(1) // Give every connection's writer something to send
+		for _, client := range clients {
+			client.Close()
+		}
+		waitForClients(t, h, 0)
+	}
+
+	if count := waitForGoroutines(baseline); count > baseline {
+		t.Errorf("%d goroutines running after 100 connections closed, want at most %d", count, baseline)
+	}
+}
+
+// waitForClients waits for the server to count want connected clients.
+func waitForClients(t *testing.T, h *testutil.Harness, want int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for h.Server.ClientCount() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("server has %d clients, want %d", h.Server.ClientCount(), want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,91 @@
+package game
+
+import (
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// simulationBattles is how many battles SimulateUpgrade fights with each hero.
+const simulationBattles = 100
+
+// SimulateUpgrade predicts whether one more level of the given station would
+// let the player beat their current dungeon level. It upgrades the station on
+// a copy of the player, whether or not they can afford it, and fights
+// simulationBattles battles on the first hero's dungeon level and difficulty
+// tier with both the current and the upgraded hero. Both heroes fight from the
+// same seeds, so the difference comes from the upgrade rather than luck. The
+// player is not changed. It returns ErrUnknownStation or ErrMaxStationLevel
+// when the station cannot be upgraded at all.
+func (s *Server) SimulateUpgrade(player *models.Player, stationType string) (*models.UpgradeSimulation, error) {
+	var simulation *models.UpgradeSimulation
+	var before, after *models.Hero
+	var tier models.DifficultyTier
+	var err error
+	s.gameState.View(func() {
+		upgraded := player.Clone()
+		station := s.getStationByType(upgraded.Factory, stationType)
+		if station == nil {
+			err = ErrUnknownStation
+			return
+		}
+		if s.config.MaxStationLevel > 0 && station.Level >= s.config.MaxStationLevel {
+			err = ErrMaxStationLevel
+			return
+		}
+
+		simulation = &models.UpgradeSimulation{
+			Station:      stationType,
+			DungeonLevel: player.Progress.DungeonLevel,
+			Cost:         station.Cost,
+			Affordable:   int64(player.Progress.Gold) >= station.Cost,
+		}
+		curve := s.stationCurve(models.StationType(stationType))
+		station.Level++
+		station.Multiplier = stationMultiplier(curve, station.Level)
+		station.Cost = nextStationCost(curve, station.Cost)
+
+		before = s.createHero(player)
+		after = s.createHero(upgraded)
+		tier = player.Difficulty
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	seed := s.rng.Int64()
+	simulation.Before = s.battleOdds(before, simulation.DungeonLevel, tier, seed)
+	simulation.After = s.battleOdds(after, simulation.DungeonLevel, tier, seed)
+	simulation.WouldWin = simulation.After.WinRate >= 0.5
+	return simulation, nil
+}
+
+// battleOdds fights simulationBattles battles with the hero on the given
+// dungeon level, rolling from consecutive seeds starting at seed.
+func (s *Server) battleOdds(hero *models.Hero, dungeonLevel int, tier models.DifficultyTier, seed int64) models.BattleOdds {
+	odds := models.BattleOdds{Hero: *hero, Battles: simulationBattles}
+	wins := 0
+	for i := int64(0); i < simulationBattles; i++ {
+		var turns []models.BattleTurn
+		result := s.runBattle(hero, dungeonLevel, tier, seed+i, &turns)
+		if len(turns) == 0 {
+			odds.EnemyHPLeft++ // A hero without HP loses without landing a blow
+			continue
+		}
+		last := turns[len(turns)-1]
+		if result.Victory {
+			wins++
+			odds.HeroHPLeft += float64(last.HeroHP) / float64(hero.HP)
+		} else {
+			first := turns[0] // The hero always attacks first, so this is the enemy's full HP
+			odds.EnemyHPLeft += float64(last.EnemyHP) / float64(first.EnemyHP+first.Damage)
+		}
+	}
+
+	odds.WinRate = float64(wins) / simulationBattles
+	if wins > 0 {
+		odds.HeroHPLeft /= float64(wins)
+	}
+	if losses := simulationBattles - wins; losses > 0 {
+		odds.EnemyHPLeft /= float64(losses)
+	}
+	return odds
+}
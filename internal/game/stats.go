@@ -0,0 +1,40 @@
+package game
+
+import (
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// statsTTL is how long Stats reuses the figures it last computed.
+const statsTTL = 5 * time.Second
+
+// Stats returns aggregate statistics over every player in memory. The player
+// figures are computed under a single read lock and then reused for statsTTL,
+// so dashboards polling under load cost one pass over the players every few
+// seconds at most.
+func (s *Server) Stats() models.ServerStats {
+	now := s.clock.Now()
+
+	s.statsMutex.Lock()
+	defer s.statsMutex.Unlock()
+	if s.stats != nil && now.Sub(s.stats.ComputedAt) < statsTTL {
+		return *s.stats
+	}
+
+	stats := models.ServerStats{ComputedAt: now}
+	s.gameState.View(func() {
+		stats.Players = len(s.gameState.Players)
+		for _, player := range s.gameState.Players {
+			stats.TotalDungeonLevels += int64(player.Progress.DungeonLevel)
+			stats.TotalGold += int64(player.Progress.Gold)
+			stats.HighestDungeonLevel = max(stats.HighestDungeonLevel, player.Progress.MaxDungeonLevel)
+		}
+	})
+	if stats.Players > 0 {
+		stats.AverageDungeonLevel = float64(stats.TotalDungeonLevels) / float64(stats.Players)
+	}
+	stats.ConnectedClients = s.ClientCount() // Taken after the game-state lock is released
+	s.stats = &stats
+	return stats
+}
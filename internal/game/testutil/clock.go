@@ -0,0 +1,38 @@
+package testutil
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a game.Clock that only moves when the test moves it.
+type Clock struct {
+	mutex sync.Mutex // Guards now
+	now   time.Time  // Time reported by Now
+}
+
+// NewClock returns a clock stopped at start.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// Now returns the clock's current time.
+func (c *Clock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set moves the clock to t.
+func (c *Clock) Set(t time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = t
+}
@@ -0,0 +1,169 @@
+// Package testutil drives a game server synchronously for tests.
+//
+// A Harness wraps a real game.Server that never starts its background loops:
+// players are connected over a real WebSocket, and the test advances the game
+// one tick at a time with Advance. Battles roll from a fixed seed and time only
+// moves when the test advances the harness clock, unless the config sets its
+// own, so the same test always sees the same outcomes.
+package testutil
+
+import (
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/handlers"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+	"github.com/gorilla/websocket"
+)
+
+// StartTime is where a harness clock starts.
+var StartTime = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Harness is a game server under test together with the WebSocket endpoint its players connect through.
+type Harness struct {
+	Server *game.Server // Server being driven; call its methods directly to act on players
+	Clock  *Clock       // Clock the server reads, unless the config supplied its own
+
+	tb   testing.TB       // Test the harness reports failures to
+	http *httptest.Server // Serves the WebSocket endpoint for Connect
+}
+
+// New creates a harness around a server built from config, with state kept in
+// memory only. A zero RandomSeed is replaced with a fixed seed, zero
+// BattleWorkers with a single worker so battles draw seeds in a fixed order, a nil clock
+// with a harness Clock stopped at StartTime, logs are
+// discarded unless config sets a logger, and an empty export secret gets a
+// fixed one. Everything is torn down when the test finishes.
+func New(tb testing.TB, config game.Config) *Harness {
+	tb.Helper()
+	if config.RandomSeed == 0 {
+		config.RandomSeed = 1
+	}
+	if config.BattleWorkers == 0 {
+		config.BattleWorkers = 1
+	}
+	var clock *Clock
+	if config.Clock == nil {
+		clock = NewClock(StartTime)
+		config.Clock = clock
+	}
+	if config.Logger == nil {
+		config.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	if len(config.ExportSecret) == 0 {
+		config.ExportSecret = []byte("testutil")
+	}
+
+	server, err := game.NewServer(config, nil)
+	if err != nil {
+		tb.Fatalf("create server: %v", err)
+	}
+
+	h := &Harness{
+		Server: server,
+		Clock:  clock,
+		tb:     tb,
+		http:   httptest.NewServer(handlers.WebSocketHandler(server)),
+	}
+	tb.Cleanup(h.http.Close)
+	return h
+}
+
+// Connect opens a WebSocket connection for the player with the given ID,
+// creating the player if needed, so it takes part in ticks. It returns once the
+// server has registered the connection. Messages sent to the connection are
+// read and discarded, and the connection is closed when the test finishes.
+func (h *Harness) Connect(playerID string) {
+	h.tb.Helper()
+	conn := h.dial(playerID)
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+}
+
+// Client is a player's WebSocket connection whose messages the test reads.
+type Client struct {
+	tb   testing.TB
+	conn *websocket.Conn
+}
+
+// Dial is like Connect, but leaves the messages sent to the connection,
+// after the initial game state, for the test to read from the returned Client.
+func (h *Harness) Dial(playerID string) *Client {
+	h.tb.Helper()
+	return &Client{tb: h.tb, conn: h.dial(playerID)}
+}
+
+// dial opens a connection for the player and reads the initial game state,
+// which is sent only after the connection is registered.
+func (h *Harness) dial(playerID string) *websocket.Conn {
+	h.tb.Helper()
+	wsURL := "ws" + strings.TrimPrefix(h.http.URL, "http") + "?playerID=" + url.QueryEscape(playerID)
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		h.tb.Fatalf("connect player %q: %v", playerID, err)
+	}
+	h.tb.Cleanup(func() { conn.Close() })
+
+	if _, _, err := conn.ReadMessage(); err != nil {
+		h.tb.Fatalf("read initial state for player %q: %v", playerID, err)
+	}
+	return conn
+}
+
+// readTimeout bounds how long Next waits for each message.
+const readTimeout = 5 * time.Second
+
+// Next reads messages until one of the given type arrives and returns it
+// decoded, failing the test if none arrives in time.
+func (c *Client) Next(messageType string) map[string]interface{} {
+	c.tb.Helper()
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(readTimeout))
+		var message map[string]interface{}
+		if err := c.conn.ReadJSON(&message); err != nil {
+			c.tb.Fatalf("waiting for a %q message: %v", messageType, err)
+		}
+		if message["type"] == messageType {
+			return message
+		}
+	}
+}
+
+// Close closes the connection.
+func (c *Client) Close() {
+	c.conn.Close()
+}
+
+// Advance runs the given number of ticks of the game loop, one after another.
+func (h *Harness) Advance(ticks int) {
+	for i := 0; i < ticks; i++ {
+		h.Server.Tick()
+	}
+}
+
+// Player returns a deep copy of the player with the given ID, failing the test
+// if there is none. Later ticks do not change the copy.
+func (h *Harness) Player(playerID string) models.Player {
+	h.tb.Helper()
+	player, exists := h.Server.GetPlayer(playerID)
+	if !exists {
+		h.tb.Fatalf("player %q not found", playerID)
+	}
+
+	var snapshot *models.Player
+	h.Server.View(func() {
+		snapshot = player.Clone()
+	})
+	return *snapshot
+}
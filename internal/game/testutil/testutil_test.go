@@ -0,0 +1,71 @@
+package testutil_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game/testutil"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+func TestAdvanceRunsTicks(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	h.Connect("alice")
+	before := h.Player("alice")
+
+	h.Advance(10)
+	after := h.Player("alice")
+	if battles := after.Progress.TotalBattles - before.Progress.TotalBattles; battles != 10 {
+		t.Errorf("10 ticks fought %d battles, want 10", battles)
+	}
+	if before.Progress.TotalBattles != 0 {
+		t.Errorf("copy taken before the ticks changed to %d battles", before.Progress.TotalBattles)
+	}
+}
+
+func TestAdvanceSkipsOfflinePlayers(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	h.Connect("alice")
+	h.Server.GetOrCreatePlayer("bob")
+
+	h.Advance(5)
+	if battles := h.Player("bob").Progress.TotalBattles; battles != 0 {
+		t.Errorf("player without a connection fought %d battles", battles)
+	}
+}
+
+func TestHarnessesPlayAlike(t *testing.T) {
+	play := func() (progress models.Progress, lastSeen time.Time) {
+		h := testutil.New(t, game.DefaultConfig())
+		h.Connect("alice")
+		h.Advance(50)
+		player := h.Player("alice")
+		return *player.Progress, player.LastSeen
+	}
+	firstProgress, firstSeen := play()
+	secondProgress, secondSeen := play()
+	if !reflect.DeepEqual(firstProgress, secondProgress) {
+		t.Errorf("same ticks ended at %+v and %+v", firstProgress, secondProgress)
+	}
+	if !firstSeen.Equal(secondSeen) {
+		t.Errorf("players last seen at %v and %v under stopped clocks", firstSeen, secondSeen)
+	}
+}
+
+func TestClockOnlyMovesWhenTold(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	if now := h.Clock.Now(); !now.Equal(testutil.StartTime) {
+		t.Fatalf("clock starts at %v, want %v", now, testutil.StartTime)
+	}
+	h.Advance(3)
+	if now := h.Clock.Now(); !now.Equal(testutil.StartTime) {
+		t.Errorf("ticks moved the clock to %v", now)
+	}
+
+	h.Clock.Advance(time.Hour)
+	if now := h.Clock.Now(); !now.Equal(testutil.StartTime.Add(time.Hour)) {
+		t.Errorf("clock at %v after advancing an hour", now)
+	}
+}
@@ -0,0 +1,74 @@
+package game_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game/testutil"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// playerFieldsOf returns the fields of the player carried by an encoded message.
+func playerFieldsOf(t *testing.T, message []byte) map[string]json.RawMessage {
+	t.Helper()
+	var decoded struct {
+		Player map[string]json.RawMessage `json:"player"`
+	}
+	if err := json.Unmarshal(message, &decoded); err != nil {
+		t.Fatalf("decode message: %v", err)
+	}
+	return decoded.Player
+}
+
+func TestPlayerCollectionsStayBounded(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	h.Connect("alice")
+	strengthen(t, h, "alice")
+	alice, _ := h.Server.GetPlayer("alice")
+	h.Server.GetOrCreatePlayer("bob")
+
+	h.Advance(500)
+	for i := 0; i < 2*models.MaxDuelHistory; i++ {
+		h.Server.Duel(alice, "bob")
+	}
+
+	player := h.Player("alice")
+	if len(player.Events) > models.MaxEvents || len(player.Inventory) > models.MaxInventorySize || len(player.Duels) > models.MaxDuelHistory {
+		t.Errorf("after heavy activity the player holds %d events, %d items, and %d duels; want at most %d, %d, and %d",
+			len(player.Events), len(player.Inventory), len(player.Duels), models.MaxEvents, models.MaxInventorySize, models.MaxDuelHistory)
+	}
+	if len(player.Events) == 0 || len(player.Duels) == 0 {
+		t.Fatalf("heavy activity recorded %d events and %d duels, want some of each", len(player.Events), len(player.Duels))
+	}
+}
+
+func TestRoutineUpdatesLeaveOutHistories(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	h.Connect("alice")
+	strengthen(t, h, "alice")
+	alice, _ := h.Server.GetPlayer("alice")
+	h.Server.GetOrCreatePlayer("bob")
+	h.Advance(200)
+	h.Server.Duel(alice, "bob")
+
+	update := h.Server.MarshalUpdate(alice)
+	full := h.Server.MarshalPlayerMessage("gameState", alice, nil)
+	updateFields, fullFields := playerFieldsOf(t, update), playerFieldsOf(t, full)
+	for _, field := range []string{"events", "duels", "quests"} {
+		if _, found := fullFields[field]; !found {
+			t.Errorf("full player lacks %s", field)
+		}
+		if _, found := updateFields[field]; found {
+			t.Errorf("routine update carries %s", field)
+		}
+	}
+	for _, field := range []string{"id", "factory", "progress", "prestigeMultiplier", "lastBattle"} {
+		if string(updateFields[field]) != string(fullFields[field]) {
+			t.Errorf("routine update has %s %s, the full player %s", field, updateFields[field], fullFields[field])
+		}
+	}
+	if len(update) >= len(full) {
+		t.Errorf("routine update takes %d bytes, the full player only %d", len(update), len(full))
+	}
+}
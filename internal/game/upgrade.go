@@ -1,45 +1,329 @@
 package game
 
 import (
+	"errors"
+	"math"
+
 	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
 )
 
+var (
+	// ErrUnknownStation is returned when an upgrade names a station type that does not exist.
+	ErrUnknownStation = errors.New("unknown station")
+	// ErrInsufficientGold is returned when the player cannot afford an upgrade.
+	ErrInsufficientGold = errors.New("insufficient gold")
+	// ErrMinStationLevel is returned when downgrading a station that is already at level 1.
+	ErrMinStationLevel = errors.New("station is already at level 1")
+	// ErrMaxStationLevel is returned when upgrading a station that is already at MaxStationLevel.
+	ErrMaxStationLevel = errors.New("station is already at its maximum level")
+)
+
+// downgradeRefundShare is the fraction of a level's upgrade cost refunded when it is sold back.
+const downgradeRefundShare = 0.5
+
+// maxBulkUpgradeLevels bounds how many levels one UpgradeStationMax call buys,
+// which holds the game-state write lock throughout. Even the most gold there
+// is buys fewer than 10,000 levels at maxStationCost each.
+const maxBulkUpgradeLevels = 100_000
+
 // UpgradeStation attempts to upgrade a specific factory station for a player.
 // It checks if the player has enough gold, then increases the station's level,
-// multiplier, and cost according to the game's progression rules.
-func (s *Server) UpgradeStation(player *models.Player, stationType string) bool {
+// multiplier, and cost according to the game's progression rules. It returns
+// ErrUnknownStation, ErrMaxStationLevel, or ErrInsufficientGold when the
+// upgrade is not possible.
+func (s *Server) UpgradeStation(player *models.Player, stationType string) error {
+	var err error
+	s.gameState.Update(func() {
+		err = s.upgradeStation(player, stationType)
+	})
+	return err
+}
+
+// upgradeStation performs UpgradeStation without locking; the caller holds the game-state write lock.
+func (s *Server) upgradeStation(player *models.Player, stationType string) error {
+	preview, err := s.previewUpgrade(player, stationType)
+	if err != nil {
+		return err
+	}
+
+	// Perform the upgrade
+	station := s.getStationByType(player.Factory, stationType)
+	player.Progress.Gold = preview.RemainingGold
+	station.Level = preview.NewLevel
+	station.Multiplier = preview.NewMultiplier
+	station.Cost = preview.NewCost
+	s.metrics.upgrades.WithLabelValues(stationType).Inc()
+
+	return nil // Upgrade successful
+}
+
+// UpgradeStationMax buys levels of a station one at a time until the player
+// cannot afford the next one, compounding the cost at each step exactly as
+// repeated single upgrades would. It stops early at maxBulkUpgradeLevels
+// levels, or once the next level would cost nothing, so a free upgrade can
+// never loop forever. A pending reservation for the station is cleared when
+// at least one level is bought. It returns ErrUnknownStation, or
+// ErrInsufficientGold when not even one level is affordable.
+func (s *Server) UpgradeStationMax(player *models.Player, stationType string) (*models.BulkUpgradeResult, error) {
+	result := &models.BulkUpgradeResult{Station: stationType}
+	var err error
+	s.gameState.Update(func() {
+		startGold := player.Progress.Gold
+		if err = s.upgradeStation(player, stationType); err != nil {
+			return
+		}
+		result.Levels = 1
+		station := s.getStationByType(player.Factory, stationType)
+		for result.Levels < maxBulkUpgradeLevels && station.Cost > 0 && s.upgradeStation(player, stationType) == nil {
+			result.Levels++
+		}
+
+		player.CancelReservation(stationType)
+		result.GoldSpent = startGold - player.Progress.Gold
+		result.NewLevel = s.getStationByType(player.Factory, stationType).Level
+		result.RemainingGold = player.Progress.Gold
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("station upgraded", "event", "upgrade", "player_id", player.ID,
+		"station", stationType, "levels", result.Levels, "gold_spent", result.GoldSpent)
+	return result, nil
+}
+
+// UpgradeOrReserve upgrades a station, or reserves the upgrade when layaway is
+// enabled and the player cannot afford it yet. A successful upgrade clears any
+// pending reservation for the same station. The UpgradeHeroSlot type unlocks
+// another hero instead; it is never reserved. A nil error with reserved false
+// means the upgrade was applied; otherwise the error says why it was rejected.
+func (s *Server) UpgradeOrReserve(player *models.Player, stationType string) (reserved bool, err error) {
+	s.gameState.Update(func() {
+		if stationType == UpgradeHeroSlot {
+			err = s.unlockHeroSlot(player)
+			return
+		}
+
+		if err = s.upgradeStation(player, stationType); err == nil {
+			player.CancelReservation(stationType)
+			return
+		}
+
+		if !s.config.LayawayEnabled || !errors.Is(err, ErrInsufficientGold) {
+			return
+		}
+
+		player.Reserve(stationType, s.clock.Now())
+		reserved, err = true, nil
+	})
+
+	switch {
+	case err != nil:
+		s.logger.Debug("station upgrade rejected", "event", "upgrade", "player_id", player.ID, "station", stationType, "error", err)
+	case reserved:
+		s.logger.Info("station upgrade reserved", "event", "reserve", "player_id", player.ID, "station", stationType)
+	default:
+		s.logger.Info("station upgraded", "event", "upgrade", "player_id", player.ID, "station", stationType, "levels", 1)
+	}
+	return reserved, err
+}
+
+// DowngradeStation sells back one level of a station, refunding
+// downgradeRefundShare of what that level cost. The station's multiplier and
+// cost are recomputed from its curve for the lower level, so buying the level
+// again costs exactly what it did before. It returns ErrUnknownStation, or
+// ErrMinStationLevel for a station at level 1.
+func (s *Server) DowngradeStation(player *models.Player, stationType string) (*models.DowngradeResult, error) {
+	var result *models.DowngradeResult
+	var err error
+	s.gameState.Update(func() {
+		station := s.getStationByType(player.Factory, stationType)
+		if station == nil {
+			err = ErrUnknownStation
+			return
+		}
+		if station.Level <= 1 {
+			err = ErrMinStationLevel
+			return
+		}
+
+		curve := s.stationCurve(models.StationType(stationType))
+		station.Level--
+		station.Multiplier = stationMultiplier(curve, station.Level)
+		station.Cost = stationCost(curve, station.Level) // The price paid for the level being sold
+		// Gold is a plain int, so a huge refund on a 32-bit build stops at its maximum
+		refund := int(min(int64(float64(station.Cost)*downgradeRefundShare), int64(math.MaxInt-player.Progress.Gold)))
+		player.Progress.Gold += refund
+
+		result = &models.DowngradeResult{
+			Station:       stationType,
+			NewLevel:      station.Level,
+			NewMultiplier: station.Multiplier,
+			NewCost:       station.Cost,
+			Refund:        refund,
+			RemainingGold: player.Progress.Gold,
+		}
+	})
+
+	if err != nil {
+		s.logger.Debug("station downgrade rejected", "event", "downgrade", "player_id", player.ID, "station", stationType, "error", err)
+		return nil, err
+	}
+	s.logger.Info("station downgraded", "event", "downgrade", "player_id", player.ID,
+		"station", stationType, "new_level", result.NewLevel, "refund", result.Refund)
+	return result, nil
+}
+
+// completeReservations performs every reserved upgrade the player can now afford,
+// in the order they were requested. Unaffordable reservations stay pending.
+// The caller holds the game-state write lock, and runs sendDeferredNotifications
+// once it has released it, so the player hears about each completed upgrade.
+func (s *Server) completeReservations(player *models.Player) {
+	for _, reservation := range append([]models.Reservation(nil), player.Reservations...) {
+		if s.upgradeStation(player, reservation.Station) == nil {
+			player.CancelReservation(reservation.Station)
+			s.notifyUnlocked(player.ID, models.Notification{
+				Type: "upgradeCompleted",
+				Data: map[string]interface{}{
+					"station": reservation.Station,
+					"level":   s.getStationByType(player.Factory, reservation.Station).Level,
+				},
+			})
+		}
+	}
+}
+
+// PreviewUpgrade computes the effect of upgrading a station without mutating the player.
+// It applies the same validation as UpgradeStation, so a preview succeeds exactly
+// when the real upgrade would and fails with the same error otherwise.
+func (s *Server) PreviewUpgrade(player *models.Player, stationType string) (preview *models.UpgradePreview, err error) {
+	s.gameState.View(func() {
+		preview, err = s.previewUpgrade(player, stationType)
+	})
+	return preview, err
+}
+
+// previewUpgrade performs PreviewUpgrade without locking; the caller holds the game-state lock.
+func (s *Server) previewUpgrade(player *models.Player, stationType string) (*models.UpgradePreview, error) {
 	station := s.getStationByType(player.Factory, stationType)
 	if station == nil {
-		return false // Invalid station type
+		return nil, ErrUnknownStation
 	}
 
-	// Check if player has enough gold for the upgrade
-	if player.Progress.Gold < station.Cost {
-		return false // Insufficient funds
+	if s.config.MaxStationLevel > 0 && station.Level >= s.config.MaxStationLevel {
+		return nil, ErrMaxStationLevel
 	}
 
-	// Perform the upgrade
-	player.Progress.Gold -= station.Cost          // Deduct upgrade cost
-	station.Level++                               // Increase station level
-	station.Multiplier += 0.2                    // Increase effectiveness by 20%
-	station.Cost = int(float64(station.Cost) * 1.5) // Increase next upgrade cost by 50%
+	// Check if player has enough gold for the upgrade; once it passes, the
+	// cost is known to fit in an int
+	if int64(player.Progress.Gold) < station.Cost {
+		return nil, ErrInsufficientGold
+	}
+	curve := s.stationCurve(models.StationType(stationType))
 
-	return true // Upgrade successful
+	return &models.UpgradePreview{
+		Station:       stationType,
+		NewLevel:      station.Level + 1,                         // Increase station level
+		NewMultiplier: stationMultiplier(curve, station.Level+1), // Increase effectiveness by the curve's increment
+		NewCost:       nextStationCost(curve, station.Cost),      // Grow the next upgrade cost by the curve's factor
+		GoldSpent:     int(station.Cost),                         // Deduct upgrade cost
+		RemainingGold: player.Progress.Gold - int(station.Cost),
+	}, nil
+}
+
+// UpgradeCosts returns the bulk upgrade prices for every station of the player's
+// factory. Stations the factory is missing, as in a malformed save, are left out.
+func (s *Server) UpgradeCosts(player *models.Player) map[models.StationType]models.UpgradeCosts {
+	costs := make(map[models.StationType]models.UpgradeCosts, len(models.StationTypes))
+	s.gameState.View(func() {
+		for _, stationType := range models.StationTypes {
+			if station := player.Factory.Station(stationType); station != nil {
+				costs[stationType] = upgradeCosts(s.stationCurve(stationType), station.Cost)
+			}
+		}
+	})
+	return costs
+}
+
+// upgradeCosts computes the bulk prices for a station on the given curve whose next upgrade costs cost.
+func upgradeCosts(curve StationCurve, cost int64) models.UpgradeCosts {
+	return models.UpgradeCosts{
+		Next1:   cumulativeUpgradeCost(curve, cost, 1),
+		Next10:  cumulativeUpgradeCost(curve, cost, 10),
+		Next100: cumulativeUpgradeCost(curve, cost, 100),
+	}
+}
+
+// cumulativeUpgradeCost returns the total gold needed to buy levels upgrades in a
+// row, starting from one that costs cost and growing by the same step as
+// single upgrades on the curve.
+func cumulativeUpgradeCost(curve StationCurve, cost int64, levels int) int64 {
+	total := int64(0)
+	for i := 0; i < levels; i++ {
+		total += cost
+		cost = nextStationCost(curve, cost)
+	}
+	return total
 }
 
 // getStationByType returns the appropriate station pointer based on the station type string.
 // This is a helper function to map string identifiers to actual station objects.
+// It returns nil for unknown station types, and for a missing station or a
+// nil factory, which callers reject like an unknown type rather than panic.
 func (s *Server) getStationByType(factory *models.Factory, stationType string) *models.Station {
-	switch stationType {
-	case "hp":
-		return factory.HPStation
-	case "armor":
-		return factory.ArmorStation
-	case "loot":
-		return factory.LootStation
-	case "attack":
-		return factory.AttackStation
-	default:
-		return nil // Unknown station type
+	return factory.Station(models.StationType(stationType))
+}
+
+// Stations describes every station type in display order, with the
+// progression its upgrades follow on this server's station curves.
+func (s *Server) Stations() []models.StationInfo {
+	stations := make([]models.StationInfo, 0, len(models.StationTypes))
+	for _, stationType := range models.StationTypes {
+		curve := s.stationCurve(stationType)
+		stations = append(stations, models.NewStationInfo(stationType, curve.MultiplierIncrement, curve.CostGrowth))
+	}
+	return stations
+}
+
+// stationCurve returns the configured progression for a station type.
+func (s *Server) stationCurve(stationType models.StationType) StationCurve {
+	if curve, ok := s.config.StationCurves[stationType]; ok {
+		return curve
+	}
+	return DefaultStationCurve
+}
+
+// stationMultiplier returns the canonical multiplier for a station on the given curve at the given level.
+// Each upgrade beyond level 1 adds the curve's increment to the base multiplier of 1.0.
+func stationMultiplier(curve StationCurve, level int) float64 {
+	return 1.0 + curve.MultiplierIncrement*float64(level-1)
+}
+
+// stationCost returns the canonical cost to upgrade a station on the given curve from the given level.
+// It repeats the per-upgrade growth, including its integer truncation, so the
+// result matches a station that was upgraded one level at a time.
+func stationCost(curve StationCurve, level int) int64 {
+	cost := int64(models.BaseStationCost)
+	for i := 1; i < level; i++ {
+		cost = nextStationCost(curve, cost)
+	}
+	return cost
+}
+
+// maxStationCost caps station upgrade costs. Costs grow exponentially and
+// would otherwise overflow int64 after a few hundred levels and wrap
+// negative, making every upgrade free; at the cap they stop growing, which
+// keeps every cost, and the total of a hundred of them, exactly representable
+// as a float64 and an int64 too. On a 32-bit build costs past the largest int
+// are simply never affordable.
+const maxStationCost int64 = 1_000_000_000_000_000
+
+// nextStationCost returns the cost of the upgrade after one that costs cost:
+// grown by the curve's factor, truncated to an integer, and never above maxStationCost.
+func nextStationCost(curve StationCurve, cost int64) int64 {
+	next := float64(cost) * curve.CostGrowth
+	if next >= float64(maxStationCost) {
+		return maxStationCost
 	}
-}
\ No newline at end of file
+	return int64(next)
+}
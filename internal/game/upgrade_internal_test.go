@@ -0,0 +1,175 @@
+package game
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+func TestUpgradeCosts(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		curve StationCurve
+		cost  int64
+		want  models.UpgradeCosts
+	}{
+		// 100, 150, 225, 337, 505, ... truncating each step, 100 levels reaching maxStationCost
+		{"default curve from level 1", DefaultStationCurve, 100, models.UpgradeCosts{Next1: 100, Next10: 11_293, Next100: 28_137_735_912_329_620}},
+		// 150, 187, 233, 291, 363, ...
+		{"gentler curve", StationCurve{MultiplierIncrement: 0.1, CostGrowth: 1.25}, 150, models.UpgradeCosts{Next1: 150, Next10: 4_936, Next100: 2_902_353_917_935}},
+		{"at the cost cap", DefaultStationCurve, maxStationCost, models.UpgradeCosts{Next1: maxStationCost, Next10: 10 * maxStationCost, Next100: 100 * maxStationCost}},
+	} {
+		if got := upgradeCosts(test.curve, test.cost); got != test.want {
+			t.Errorf("%s: upgradeCosts = %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestUpgradeCostsMatchSingleUpgrades(t *testing.T) {
+	cost := int64(models.BaseStationCost)
+	for levels := 1; levels <= 100; levels++ {
+		total := int64(0)
+		next := cost
+		for i := 0; i < levels; i++ {
+			total += next
+			next = nextStationCost(DefaultStationCurve, next)
+		}
+		if got := cumulativeUpgradeCost(DefaultStationCurve, cost, levels); got != total {
+			t.Fatalf("cumulativeUpgradeCost(%d levels) = %d, want %d", levels, got, total)
+		}
+		if got := stationCost(DefaultStationCurve, levels+1); got != next {
+			t.Fatalf("stationCost(level %d) = %d, want %d after %d single upgrades", levels+1, got, next, levels)
+		}
+	}
+}
+
+// newTestServer returns a server on the default config with state kept in
+// memory only and upgrades not rate limited.
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	config := DefaultConfig()
+	config.RandomSeed = 1
+	config.UpgradeRateLimit = 0
+	config.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	config.ExportSecret = []byte("test")
+	server, err := NewServer(config, nil)
+	if err != nil {
+		t.Fatalf("create server: %v", err)
+	}
+	return server
+}
+
+func TestTwoHundredUpgradesSaturateCost(t *testing.T) {
+	if strconv.IntSize < 64 {
+		t.Skip("costs at the cap are never affordable with 32-bit gold")
+	}
+	server := newTestServer(t)
+	player, _ := server.GetOrCreatePlayer("alice")
+	station := player.Factory.Station(models.StationAttack)
+	total := cumulativeUpgradeCost(DefaultStationCurve, station.Cost, 200)
+	if total <= 0 || total < 100*maxStationCost {
+		t.Fatalf("200 upgrades cost %d in total, want a positive sum past the cap", total)
+	}
+	player.Progress.Gold = int(total)
+
+	for i := 1; i <= 200; i++ {
+		gold, cost := player.Progress.Gold, station.Cost
+		if err := server.UpgradeStation(player, string(models.StationAttack)); err != nil {
+			t.Fatalf("upgrade %d costing %d with %d gold: %v", i, cost, gold, err)
+		}
+		if station.Cost < cost || station.Cost > maxStationCost || player.Progress.Gold != gold-int(cost) {
+			t.Fatalf("upgrade %d: cost went from %d to %d and gold from %d to %d", i, cost, station.Cost, gold, player.Progress.Gold)
+		}
+	}
+	if station.Level != 201 || station.Cost != maxStationCost || player.Progress.Gold != 0 {
+		t.Errorf("after 200 upgrades: level %d costing %d with %d gold left, want level 201 at the cap with none",
+			station.Level, station.Cost, player.Progress.Gold)
+	}
+	if err := server.UpgradeStation(player, string(models.StationAttack)); !errors.Is(err, ErrInsufficientGold) {
+		t.Errorf("upgrade with no gold left returned %v, want ErrInsufficientGold", err)
+	}
+}
+
+func TestUpgradeStationMaxAtCostCap(t *testing.T) {
+	if strconv.IntSize < 64 {
+		t.Skip("costs at the cap are never affordable with 32-bit gold")
+	}
+	total := cumulativeUpgradeCost(DefaultStationCurve, models.BaseStationCost, 200)
+	for _, test := range []struct {
+		name   string
+		gold   int
+		levels int // Levels bought, or 0 to derive them from the gold
+	}{
+		{"just short of a 201st level", int(total + maxStationCost - 1), 200},
+		{"the most gold there is", math.MaxInt, 0},
+	} {
+		server := newTestServer(t)
+		player, _ := server.GetOrCreatePlayer("alice")
+		player.Progress.Gold = test.gold
+
+		result, err := server.UpgradeStationMax(player, string(models.StationAttack))
+		if err != nil {
+			t.Fatalf("%s: UpgradeStationMax: %v", test.name, err)
+		}
+		station := player.Factory.Station(models.StationAttack)
+		if test.levels == 0 {
+			// Every level past the first 200 costs exactly the cap
+			test.levels = 200 + int((int64(test.gold)-total)/maxStationCost)
+		}
+		if result.Levels != test.levels || result.NewLevel != 1+test.levels || station.Cost != maxStationCost {
+			t.Errorf("%s: bought %d levels to level %d costing %d, want %d levels at the cap", test.name, result.Levels, result.NewLevel, station.Cost, test.levels)
+		}
+		if result.GoldSpent < 0 || result.RemainingGold < 0 || int64(result.RemainingGold) >= maxStationCost || result.GoldSpent+result.RemainingGold != test.gold {
+			t.Errorf("%s: spent %d of %d gold leaving %d", test.name, result.GoldSpent, test.gold, result.RemainingGold)
+		}
+	}
+}
+
+func TestNewServerReplacesInvalidStationCurves(t *testing.T) {
+	gentle := StationCurve{MultiplierIncrement: 0.1, CostGrowth: 1.2}
+	curves := map[models.StationType]StationCurve{
+		models.StationLoot:   {MultiplierIncrement: 0.2, CostGrowth: 0.5},
+		models.StationAttack: {MultiplierIncrement: -1, CostGrowth: 1.5},
+		models.StationArmor:  {MultiplierIncrement: 0.2, CostGrowth: math.NaN()},
+		models.StationHP:     gentle,
+	}
+	config := DefaultConfig()
+	config.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	config.ExportSecret = []byte("test")
+	config.StationCurves = curves
+	server, err := NewServer(config, nil)
+	if err != nil {
+		t.Fatalf("create server: %v", err)
+	}
+
+	for _, stationType := range []models.StationType{models.StationLoot, models.StationAttack, models.StationArmor} {
+		if curve := server.stationCurve(stationType); curve != DefaultStationCurve {
+			t.Errorf("%s curve = %+v, want the default in place of %+v", stationType, curve, curves[stationType])
+		}
+	}
+	if curve := server.stationCurve(models.StationHP); curve != gentle {
+		t.Errorf("valid hp curve = %+v, want it kept as %+v", curve, gentle)
+	}
+	if curves[models.StationLoot].CostGrowth != 0.5 {
+		t.Error("NewServer changed the caller's curve map")
+	}
+}
+
+func TestUpgradeStationMaxStopsAtFreeUpgrades(t *testing.T) {
+	server := newTestServer(t)
+	player, _ := server.GetOrCreatePlayer("alice")
+	player.Factory.Station(models.StationAttack).Cost = 0 // As a broken save might hold
+
+	result, err := server.UpgradeStationMax(player, string(models.StationAttack))
+	if err != nil {
+		t.Fatalf("UpgradeStationMax: %v", err)
+	}
+	if result.Levels != 1 || result.GoldSpent != 0 {
+		t.Errorf("bought %d levels for %d gold at no cost, want one free level", result.Levels, result.GoldSpent)
+	}
+}
@@ -0,0 +1,86 @@
+package game_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game/testutil"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// setGold gives the player exactly gold gold.
+func setGold(t *testing.T, h *testutil.Harness, playerID string, gold int) {
+	t.Helper()
+	player, exists := h.Server.GetPlayer(playerID)
+	if !exists {
+		t.Fatalf("player %q not found", playerID)
+	}
+	h.Server.View(func() {
+		player.Progress.Gold = gold
+	})
+}
+
+func TestPreviewUpgradeMatchesUpgrade(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	player, _ := h.Server.GetOrCreatePlayer("alice")
+	setGold(t, h, "alice", 10_000)
+
+	for _, stationType := range []models.StationType{models.StationAttack, models.StationAttack, models.StationHP} {
+		before := h.Player("alice")
+		preview, err := h.Server.PreviewUpgrade(player, string(stationType))
+		if err != nil {
+			t.Fatalf("preview %s: %v", stationType, err)
+		}
+		if after := h.Player("alice"); !reflect.DeepEqual(after, before) {
+			t.Fatalf("preview of %s changed the player", stationType)
+		}
+
+		if err := h.Server.UpgradeStation(player, string(stationType)); err != nil {
+			t.Fatalf("upgrade %s: %v", stationType, err)
+		}
+		upgraded := h.Player("alice")
+		station := upgraded.Factory.Station(stationType)
+		got := models.UpgradePreview{
+			Station:       string(stationType),
+			NewLevel:      station.Level,
+			NewMultiplier: station.Multiplier,
+			NewCost:       station.Cost,
+			GoldSpent:     before.Progress.Gold - upgraded.Progress.Gold,
+			RemainingGold: upgraded.Progress.Gold,
+		}
+		if got != *preview {
+			t.Errorf("upgrade of %s = %+v, preview promised %+v", stationType, got, *preview)
+		}
+	}
+}
+
+func TestPreviewUpgradeFailsLikeUpgrade(t *testing.T) {
+	config := game.DefaultConfig()
+	config.MaxStationLevel = 2
+	h := testutil.New(t, config)
+	player, _ := h.Server.GetOrCreatePlayer("alice")
+	setGold(t, h, "alice", 1_000)
+	if err := h.Server.UpgradeStation(player, string(models.StationHP)); err != nil {
+		t.Fatalf("upgrade: %v", err)
+	}
+	setGold(t, h, "alice", 0)
+
+	for stationType, want := range map[string]error{
+		"wisdom":                    game.ErrUnknownStation,
+		string(models.StationArmor): game.ErrInsufficientGold,
+		string(models.StationHP):    game.ErrMaxStationLevel,
+	} {
+		before := h.Player("alice")
+		if _, err := h.Server.PreviewUpgrade(player, stationType); !errors.Is(err, want) {
+			t.Errorf("preview %s: %v, want %v", stationType, err, want)
+		}
+		if err := h.Server.UpgradeStation(player, stationType); !errors.Is(err, want) {
+			t.Errorf("upgrade %s: %v, want %v", stationType, err, want)
+		}
+		if after := h.Player("alice"); !reflect.DeepEqual(after, before) {
+			t.Errorf("rejected upgrade of %s changed the player", stationType)
+		}
+	}
+}
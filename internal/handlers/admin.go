@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+)
+
+// AdminTokenHeader is the request header that carries the admin token.
+const AdminTokenHeader = "X-Admin-Token"
+
+// RequireAdmin wraps an admin handler so it only runs when the request carries
+// the configured admin token. An empty token disables admin endpoints entirely.
+func RequireAdmin(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		provided := r.Header.Get(AdminTokenHeader)
+		if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			writeError(w, http.StatusForbidden, CodeForbidden, "Forbidden")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// RecomputeHandler handles admin POST requests that recompute derived fields for all players.
+// It returns a summary of how many players were checked and repaired.
+func RecomputeHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		result := gameServer.RecomputeDerived()
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode recompute result")
+		}
+	}
+}
+
+// maxRestoreBytes caps the size of an uploaded backup document.
+const maxRestoreBytes = 256 << 20
+
+// BackupHandler handles admin GET requests that download a full game state backup.
+func BackupHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="idle-dungeon-backup.json"`)
+		if err := gameServer.WriteBackup(w); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode backup")
+		}
+	}
+}
+
+// RestoreHandler handles admin POST requests that replace the game state with an uploaded backup.
+// Invalid backups are rejected with 400 and leave the current state untouched.
+func RestoreHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		restored, err := gameServer.Restore(http.MaxBytesReader(w, r.Body, maxRestoreBytes))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Restore failed - "+err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]int{"playersRestored": restored}); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode restore result")
+		}
+	}
+}
+
+// GrantHandler handles admin POST requests that adjust a player's gold, for
+// compensating players who lost progress. The gold query parameter is the
+// change to apply and may be negative; the balance never drops below zero.
+// Unknown players are answered with 404 rather than created.
+func GrantHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		playerID := r.URL.Query().Get("playerID")
+		delta, err := strconv.Atoi(r.URL.Query().Get("gold"))
+		if playerID == "" || err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "PlayerID and integer gold required")
+			return
+		}
+
+		result, err := gameServer.GrantGold(playerID, delta)
+		if errors.Is(err, game.ErrUnknownPlayer) {
+			writeError(w, http.StatusNotFound, CodePlayerNotFound, "Player not found")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode grant result")
+		}
+	}
+}
+
+// SeasonResetHandler handles admin POST requests that end the current season,
+// archiving the leaderboard top 10 in the hall of fame and resetting every
+// player's progress but their prestige. It returns the archived season.
+func SeasonResetHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		season, err := gameServer.ResetSeason()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Season reset failed - "+err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(season); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode season")
+		}
+	}
+}
+
+// KickHandler handles admin POST requests that disconnect a player, closing
+// every open connection of the player named by the id query parameter. With
+// ban=true the player is also banned, so they cannot reconnect or use their
+// token until unbanned; an optional reason is stored with the ban and sent in
+// the close frames. It returns how many connections were closed.
+func KickHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		playerID := r.URL.Query().Get("id")
+		if playerID == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Player ID required")
+			return
+		}
+		ban := r.URL.Query().Get("ban") == "true"
+
+		result := gameServer.KickPlayer(playerID, ban, r.URL.Query().Get("reason"))
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode kick result")
+		}
+	}
+}
+
+// UnbanHandler handles admin POST requests that lift the ban on the player
+// named by the id query parameter. Players who are not banned are answered
+// with 404.
+func UnbanHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		playerID := r.URL.Query().Get("id")
+		if playerID == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Player ID required")
+			return
+		}
+		if !gameServer.UnbanPlayer(playerID) {
+			writeError(w, http.StatusNotFound, CodePlayerNotFound, "Player is not banned")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]string{"unbanned": playerID}); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode unban result")
+		}
+	}
+}
+
+// TokenHandler handles admin POST requests that issue a new token to the
+// player named by the id query parameter, for players saved before tokens
+// existed and players who lost theirs. The player's earlier token stops
+// working. Unknown players are answered with 404.
+func TokenHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		playerID := r.URL.Query().Get("id")
+		if playerID == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Player ID required")
+			return
+		}
+		token, err := gameServer.IssueToken(playerID)
+		if errors.Is(err, game.ErrUnknownPlayer) {
+			writeError(w, http.StatusNotFound, CodePlayerNotFound, "Player not found")
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Issuing token failed - "+err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]string{"playerId": playerID, "token": token}); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode token")
+		}
+	}
+}
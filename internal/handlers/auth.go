@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+)
+
+// PlayerTokenHeader is the response header that carries a newly issued player token.
+const PlayerTokenHeader = "X-Player-Token"
+
+// RequirePlayerToken wraps a handler so it only runs when the request carries
+// the bearer token of the player named by the idParam query parameter. The
+// first request for a player ID creates the player and returns its token in
+// the PlayerTokenHeader response header; later requests must present it in an
+// "Authorization: Bearer" header and are answered with 401 Unauthorized otherwise.
+// Requests for a banned player are answered with 403 Forbidden.
+// Requests without a player ID are passed through for the handler to reject.
+func RequirePlayerToken(gameServer *game.Server, idParam string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		playerID := r.URL.Query().Get(idParam)
+		if playerID == "" {
+			next(w, r)
+			return
+		}
+
+		_, issued, err := gameServer.AuthenticatePlayer(playerID, bearerToken(r))
+		if errors.Is(err, game.ErrBanned) {
+			writeError(w, http.StatusForbidden, CodeBanned, "Player is banned")
+			return
+		}
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+			return
+		}
+		if issued != "" {
+			w.Header().Set(PlayerTokenHeader, issued)
+		}
+		next(w, r)
+	}
+}
+
+// bearerToken returns the token from the request's Authorization header, or
+// an empty string when there is none.
+func bearerToken(r *http.Request) string {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+)
+
+// CORS wraps an API handler with the server's origin policy. Requests from an
+// allowed origin get an Access-Control-Allow-Origin header naming it, and
+// preflight requests are answered directly; cross-origin requests from any
+// other origin are rejected with 403 Forbidden before the handler runs.
+func CORS(gameServer *game.Server, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next(w, r)
+			return
+		}
+		if !gameServer.OriginAllowed(r) {
+			writeError(w, http.StatusForbidden, CodeForbidden, "Origin not allowed")
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+		w.Header().Set("Access-Control-Expose-Headers", PlayerTokenHeader) // Lets browsers read a newly issued token
+
+		// Preflight requests ask permission before a cross-origin call with a token
+		if r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, X-Admin-Token")
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next(w, r)
+	}
+}
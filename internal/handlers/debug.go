@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// ReplayHandler handles debug requests to replay a battle from its seed.
+// It rebuilds the player's current hero and reruns the battle at the given
+// dungeon level, returning the result, every attack made, and a count of the
+// hits and misses. The hero's stats can be overridden with hp, armor, attack,
+// loot, critChance, dodgeChance, and doubleStrikeChance to match the hero that
+// fought the original battle. The battle is fought on the player's
+// current difficulty tier unless difficulty names another.
+func ReplayHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		playerID := query.Get("playerID")
+		if playerID == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "PlayerID required")
+			return
+		}
+
+		level, err := strconv.Atoi(query.Get("level"))
+		if err != nil || level < 1 {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Level must be a positive integer")
+			return
+		}
+		seed, err := strconv.ParseInt(query.Get("seed"), 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Seed must be an integer")
+			return
+		}
+
+		player, exists := gameServer.GetPlayer(playerID)
+		if !exists {
+			writeError(w, http.StatusNotFound, CodePlayerNotFound, "Player not found")
+			return
+		}
+
+		hero := gameServer.Hero(player)
+		for name, stat := range map[string]*int{"hp": &hero.HP, "armor": &hero.Armor, "attack": &hero.Attack, "loot": &hero.Loot} {
+			if value := query.Get(name); value != "" {
+				if *stat, err = strconv.Atoi(value); err != nil {
+					writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid "+name)
+					return
+				}
+			}
+		}
+		for name, chance := range map[string]*float64{"critChance": &hero.CritChance, "dodgeChance": &hero.DodgeChance, "doubleStrikeChance": &hero.DoubleStrikeChance} {
+			if value := query.Get(name); value != "" {
+				if *chance, err = strconv.ParseFloat(value, 64); err != nil {
+					writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid "+name)
+					return
+				}
+			}
+		}
+
+		var tier models.DifficultyTier
+		gameServer.View(func() {
+			tier = player.Difficulty
+		})
+		if value := query.Get("difficulty"); value != "" {
+			if tier, err = models.ValidateDifficulty(value); err != nil {
+				writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid difficulty")
+				return
+			}
+		}
+
+		result, turns := gameServer.ReplayBattle(hero, level, tier, seed)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"hero":       hero,
+			"difficulty": tier,
+			"result":     result,
+			"turns":      turns,
+			"summary":    models.SummarizeTurns(turns),
+		}); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode battle replay")
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// Error codes identify why a request failed. HTTP error responses carry them
+// in their envelope and WebSocket error replies in their code field. They are
+// stable, so clients can branch on them, while messages may change.
+const (
+	CodePlayerNotFound    = "PLAYER_NOT_FOUND"    // No player, or no opponent, with the given ID
+	CodeInsufficientGold  = "INSUFFICIENT_GOLD"   // The player cannot afford the upgrade
+	CodeInsufficientGems  = "INSUFFICIENT_GEMS"   // The player cannot afford the gem upgrade
+	CodeInvalidStation    = "INVALID_STATION"     // The station type does not exist
+	CodeStationLevelLimit = "STATION_LEVEL_LIMIT" // The station is already at its lowest or highest level
+	CodeRateLimited       = "RATE_LIMITED"        // Too many upgrade requests
+	CodePrestigeTooEarly  = "PRESTIGE_TOO_EARLY"  // The dungeon level is too low to prestige
+	CodeUnknownResearch   = "UNKNOWN_RESEARCH"    // The research node does not exist
+	CodeResearchUnlocked  = "RESEARCH_UNLOCKED"   // The player already unlocked the research node
+	CodeResearchLocked    = "RESEARCH_LOCKED"     // The research node's prerequisites or dungeon level are not met
+	CodeUnknownQuest      = "UNKNOWN_QUEST"       // The player has no quest of that type today
+	CodeQuestIncomplete   = "QUEST_INCOMPLETE"    // The quest's target has not been reached
+	CodeQuestClaimed      = "QUEST_CLAIMED"       // The quest's reward was already paid
+	CodeExportExpired     = "EXPORT_EXPIRED"      // The export is too old, or predates a season reset or gold deduction
+	CodeExportUsed        = "EXPORT_USED"         // The export was already imported
+	CodeGuildNotFound     = "GUILD_NOT_FOUND"     // No guild with the given name
+	CodeGuildConflict     = "GUILD_CONFLICT"      // The guild exists already, or the player is already in or not in a guild
+	CodeInvalidRequest    = "INVALID_REQUEST"     // A parameter or message is missing or malformed
+	CodeMethodNotAllowed  = "METHOD_NOT_ALLOWED"  // The endpoint does not accept the request method
+	CodeUnauthorized      = "UNAUTHORIZED"        // The player token is missing or wrong
+	CodeForbidden         = "FORBIDDEN"           // The origin or admin token is not allowed
+	CodeBanned            = "BANNED"              // The player is banned
+	CodeServerFull        = "SERVER_FULL"         // The server has no room for another connection
+	CodeInternal          = "INTERNAL_ERROR"      // The server failed to produce a response
+)
+
+// errorCodes maps the errors game operations and handlers return to their error codes.
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{game.ErrUnknownPlayer, CodePlayerNotFound},
+	{game.ErrUnknownOpponent, CodePlayerNotFound},
+	{game.ErrInsufficientGold, CodeInsufficientGold},
+	{models.ErrNotEnoughGems, CodeInsufficientGems},
+	{game.ErrUnknownStation, CodeInvalidStation},
+	{game.ErrMinStationLevel, CodeStationLevelLimit},
+	{game.ErrMaxStationLevel, CodeStationLevelLimit},
+	{game.ErrPrestigeTooEarly, CodePrestigeTooEarly},
+	{game.ErrUnknownResearch, CodeUnknownResearch},
+	{game.ErrResearchUnlocked, CodeResearchUnlocked},
+	{game.ErrResearchLocked, CodeResearchLocked},
+	{game.ErrUnknownQuest, CodeUnknownQuest},
+	{game.ErrQuestIncomplete, CodeQuestIncomplete},
+	{game.ErrQuestClaimed, CodeQuestClaimed},
+	{game.ErrExportExpired, CodeExportExpired},
+	{game.ErrExportUsed, CodeExportUsed},
+	{game.ErrUnknownGuild, CodeGuildNotFound},
+	{game.ErrGuildExists, CodeGuildConflict},
+	{game.ErrAlreadyInGuild, CodeGuildConflict},
+	{game.ErrNotInGuild, CodeGuildConflict},
+	{game.ErrServerFull, CodeServerFull},
+	{game.ErrBanned, CodeBanned},
+	{errRateLimited, CodeRateLimited},
+}
+
+// errorCode returns the error code for err, or fallback when err is not one
+// of the game errors with a code of its own.
+func errorCode(err error, fallback string) string {
+	for _, known := range errorCodes {
+		if errors.Is(err, known.err) {
+			return known.code
+		}
+	}
+	return fallback
+}
+
+// errorResponse is the JSON envelope of every HTTP error response.
+type errorResponse struct {
+	Error errorBody `json:"error"`
+}
+
+// errorBody describes a failed request.
+type errorBody struct {
+	Code    string `json:"code"`    // Stable error code, one of the Code constants
+	Message string `json:"message"` // Human-readable explanation
+}
+
+// writeError answers a request with the given status and a JSON error
+// envelope, {"error":{"code":...,"message":...}}. Like http.Error, it
+// replaces the content type and discards any cache headers set so far.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	header := w.Header()
+	header.Del("Content-Length")
+	header.Del("Cache-Control")
+	header.Set("Content-Type", "application/json")
+	header.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{Error: errorBody{Code: code, Message: message}})
+}
+
+// errorReply builds the WebSocket error reply with the given code and reason.
+func errorReply(code, reason string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":   "error",
+		"code":   code,
+		"reason": reason,
+	}
+}
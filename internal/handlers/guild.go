@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// GuildHandler handles HTTP GET requests for a guild.
+// It returns the guild given by name with its sorted member IDs, combined
+// contribution, and current bonus, or 404 when there is no such guild.
+func GuildHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Name required")
+			return
+		}
+
+		guild, err := gameServer.Guild(name)
+		if err != nil {
+			writeError(w, http.StatusNotFound, CodeGuildNotFound, "Guild not found")
+			return
+		}
+		writeGuildJSON(w, guild, http.StatusOK)
+	}
+}
+
+// CreateGuildHandler handles HTTP POST requests to found a guild.
+// The player given by playerID becomes the only member of a new guild called
+// name. It answers 201 Created with the guild, or 409 Conflict when the name
+// is taken or the player already belongs to a guild.
+func CreateGuildHandler(gameServer *game.Server) http.HandlerFunc {
+	return guildMembershipHandler(gameServer, http.StatusCreated, true, func(player *models.Player, name string) (*models.Guild, error) {
+		return gameServer.CreateGuild(player, name)
+	})
+}
+
+// JoinGuildHandler handles HTTP POST requests to join a guild by name.
+// It returns the guild with the player added, 404 when there is no such guild,
+// or 409 Conflict when the player already belongs to a guild.
+func JoinGuildHandler(gameServer *game.Server) http.HandlerFunc {
+	return guildMembershipHandler(gameServer, http.StatusOK, true, gameServer.JoinGuild)
+}
+
+// LeaveGuildHandler handles HTTP POST requests to leave the player's guild.
+// The last member to leave disbands the guild. It answers 204 No Content, or
+// 409 Conflict when the player belongs to no guild.
+func LeaveGuildHandler(gameServer *game.Server) http.HandlerFunc {
+	return guildMembershipHandler(gameServer, http.StatusNoContent, false, func(player *models.Player, _ string) (*models.Guild, error) {
+		return nil, gameServer.LeaveGuild(player)
+	})
+}
+
+// guildMembershipHandler answers POST requests that change the guild of the
+// player given by playerID, requiring a guild name when needsName is set.
+// On success it replies with status and the guild change returns, if any.
+func guildMembershipHandler(gameServer *game.Server, status int, needsName bool, change func(*models.Player, string) (*models.Guild, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		playerID := r.URL.Query().Get("playerID")
+		if playerID == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "PlayerID required")
+			return
+		}
+		name := r.URL.Query().Get("name")
+		if needsName && name == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Name required")
+			return
+		}
+
+		player, exists := gameServer.GetPlayer(playerID)
+		if !exists {
+			writeError(w, http.StatusNotFound, CodePlayerNotFound, "Player not found")
+			return
+		}
+
+		guild, err := change(player, name)
+		switch {
+		case errors.Is(err, game.ErrUnknownGuild):
+			writeError(w, http.StatusNotFound, errorCode(err, CodePlayerNotFound), "Guild failed - "+err.Error())
+		case errors.Is(err, game.ErrGuildExists), errors.Is(err, game.ErrAlreadyInGuild), errors.Is(err, game.ErrNotInGuild):
+			writeError(w, http.StatusConflict, errorCode(err, CodeGuildConflict), "Guild failed - "+err.Error())
+		case err != nil:
+			writeError(w, http.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Guild failed - "+err.Error())
+		case guild == nil:
+			w.WriteHeader(status)
+		default:
+			writeGuildJSON(w, guild, status)
+		}
+	}
+}
+
+// writeGuildJSON encodes a guild as the JSON response body with the given status.
+func writeGuildJSON(w http.ResponseWriter, guild *models.Guild, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(guild); err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode guild")
+	}
+}
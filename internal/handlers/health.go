@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+)
+
+// HealthHandler is the liveness probe. It always answers 200 OK with the
+// number of players and open connections and the outcome of recent saves,
+// and takes no locks beyond three brief reads, so it is cheap to poll.
+func HealthHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, http.StatusOK, "ok", gameServer)
+	}
+}
+
+// ReadyHandler is the readiness probe. It answers 503 Service Unavailable
+// until the game loop has completed its first tick, and again once it stops
+// during shutdown. While saves fail it reports the status "degraded" but
+// still answers 200 OK, since the game keeps serving players from memory.
+func ReadyHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !gameServer.Ready() {
+			writeHealth(w, http.StatusServiceUnavailable, "starting", gameServer)
+			return
+		}
+		if gameServer.Persistence().Degraded {
+			writeHealth(w, http.StatusOK, "degraded", gameServer)
+			return
+		}
+		writeHealth(w, http.StatusOK, "ok", gameServer)
+	}
+}
+
+// writeHealth writes a probe response with the given status.
+func writeHealth(w http.ResponseWriter, status int, state string, gameServer *game.Server) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":      state,
+		"players":     gameServer.PlayerCount(),
+		"clients":     gameServer.ClientCount(),
+		"persistence": gameServer.Persistence(),
+	})
+}
@@ -2,58 +2,799 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
 )
 
 // PlayerHandler handles HTTP requests for player data retrieval.
-// It returns player information in JSON format for API consumers.
+// It returns player information in JSON format for API consumers, along with an
+// upgradeCosts section giving each station's price for the next 1, 10, and 100 levels.
 func PlayerHandler(gameServer *game.Server) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		playerID := r.URL.Query().Get("id")
 		if playerID == "" {
-			http.Error(w, "Player ID required", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Player ID required")
 			return
 		}
 
-		player := gameServer.GetOrCreatePlayer(playerID)
-		
+		player, _ := gameServer.GetOrCreatePlayer(playerID)
+		response := struct {
+			*models.Player
+			UpgradeCosts map[models.StationType]models.UpgradeCosts `json:"upgradeCosts"`
+		}{player, gameServer.UpgradeCosts(player)}
+		writeLockedJSON(w, gameServer, response, http.StatusOK)
+	}
+}
+
+// writePlayerJSON encodes a player as the JSON response body with the given status.
+func writePlayerJSON(w http.ResponseWriter, gameServer *game.Server, player *models.Player, status int) {
+	writeLockedJSON(w, gameServer, player, status)
+}
+
+// writeLockedJSON encodes a value that refers to players as the JSON response body.
+// It is encoded under the game-state lock so the game loop cannot change a player mid-write.
+func writeLockedJSON(w http.ResponseWriter, gameServer *game.Server, value interface{}, status int) {
+	var data []byte
+	var err error
+	gameServer.View(func() {
+		data, err = json.Marshal(value)
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode player data")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
+
+// StationsHandler handles HTTP requests for the list of station types, their
+// display names, and how their upgrades progress. The list only changes when
+// the server is reconfigured, so responses may be cached for an hour.
+func StationsHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		if err := json.NewEncoder(w).Encode(gameServer.Stations()); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode stations")
+		}
+	}
+}
+
+// StatsHandler handles HTTP requests for aggregate server statistics. The
+// server recomputes them at most every few seconds, so responses may be cached
+// for as long.
+func StatsHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(player); err != nil {
-			http.Error(w, "Failed to encode player data", http.StatusInternalServerError)
+		w.Header().Set("Cache-Control", "public, max-age=5")
+		if err := json.NewEncoder(w).Encode(gameServer.Stats()); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode stats")
 		}
 	}
 }
 
 // UpgradeHandler handles HTTP POST requests for factory station upgrades.
 // It processes upgrade requests and returns updated player data.
+// When layaway is enabled, an unaffordable upgrade is reserved and answered with 202 Accepted.
+// With dryRun=true it returns the upgrade preview instead and leaves the player unchanged.
+// With max=true it buys as many levels as the player can afford and returns a summary of the purchase.
+// Upgrades other than previews are rate limited per player and answered with 429 Too Many Requests beyond the limit.
 func UpgradeHandler(gameServer *game.Server) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		playerID := r.URL.Query().Get("playerID")
+		station := r.URL.Query().Get("station")
+
+		if playerID == "" || station == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "PlayerID and station required")
+			return
+		}
+
+		player, _ := gameServer.GetOrCreatePlayer(playerID)
+
+		if r.URL.Query().Get("dryRun") == "true" {
+			preview, err := gameServer.PreviewUpgrade(player, station)
+			if err != nil {
+				writeUpgradeError(w, err)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(preview); err != nil {
+				writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode upgrade preview")
+			}
+			return
+		}
+
+		if !gameServer.AllowUpgrade(player.ID) {
+			writeError(w, http.StatusTooManyRequests, CodeRateLimited, "Too many upgrade requests")
+			return
+		}
+
+		if r.URL.Query().Get("max") == "true" {
+			result, err := gameServer.UpgradeStationMax(player, station)
+			if err != nil {
+				writeUpgradeError(w, err)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(result); err != nil {
+				writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode upgrade result")
+			}
+			return
+		}
+
+		reserved, err := gameServer.UpgradeOrReserve(player, station)
+		if err != nil {
+			writeUpgradeError(w, err)
+			return
+		}
+
+		status := http.StatusOK
+		if reserved {
+			// The upgrade completes in the game loop once the player has enough gold
+			status = http.StatusAccepted
+		}
+		writePlayerJSON(w, gameServer, player, status)
+	}
+}
+
+// DowngradeHandler handles HTTP POST requests to sell back one level of a station.
+// It returns the refund and the station's new level, or 400 Bad Request for an
+// unknown station or one already at level 1. Downgrades share the upgrade rate limit.
+func DowngradeHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
 			return
 		}
 
 		playerID := r.URL.Query().Get("playerID")
 		station := r.URL.Query().Get("station")
-		
 		if playerID == "" || station == "" {
-			http.Error(w, "PlayerID and station required", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "PlayerID and station required")
+			return
+		}
+
+		player, exists := gameServer.GetPlayer(playerID)
+		if !exists {
+			writeError(w, http.StatusNotFound, CodePlayerNotFound, "Player not found")
+			return
+		}
+
+		if !gameServer.AllowUpgrade(player.ID) {
+			writeError(w, http.StatusTooManyRequests, CodeRateLimited, "Too many upgrade requests")
+			return
+		}
+
+		result, err := gameServer.DowngradeStation(player, station)
+		if err != nil {
+			writeStationError(w, "Downgrade failed", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode downgrade result")
+		}
+	}
+}
+
+// writeUpgradeError answers a rejected upgrade with 400 Bad Request and the
+// reason, listing the valid station names when the station was unknown.
+func writeUpgradeError(w http.ResponseWriter, err error) {
+	writeStationError(w, "Upgrade failed", err)
+}
+
+// writeStationError answers a rejected station change with 400 Bad Request,
+// the error's code, the given prefix, and the reason, listing the valid
+// station names when the station was unknown.
+func writeStationError(w http.ResponseWriter, prefix string, err error) {
+	message := prefix + " - " + err.Error()
+	if errors.Is(err, game.ErrUnknownStation) {
+		message += " (valid stations: " + strings.Join(validStations(), ", ") + ")"
+	}
+	writeError(w, http.StatusBadRequest, errorCode(err, CodeInvalidRequest), message)
+}
+
+// validStations returns the names of every station type, in display order.
+func validStations() []string {
+	names := make([]string, len(models.StationTypes))
+	for i, stationType := range models.StationTypes {
+		names[i] = string(stationType)
+	}
+	return names
+}
+
+// DuelsHandler handles HTTP requests for player duels.
+// GET returns the player's recent duel history; POST challenges the player given by opponentID.
+func DuelsHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		playerID := r.URL.Query().Get("playerID")
+		if playerID == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "PlayerID required")
+			return
+		}
+
+		player, exists := gameServer.GetPlayer(playerID)
+		if !exists {
+			writeError(w, http.StatusNotFound, CodePlayerNotFound, "Player not found")
+			return
+		}
+
+		var response interface{}
+		switch r.Method {
+		case "GET":
+			response = gameServer.DuelHistory(player)
+		case "POST":
+			opponentID := r.URL.Query().Get("opponentID")
+			if opponentID == "" {
+				writeError(w, http.StatusBadRequest, CodeInvalidRequest, "OpponentID required")
+				return
+			}
+
+			result, err := gameServer.Duel(player, opponentID)
+			if errors.Is(err, game.ErrUnknownOpponent) {
+				writeError(w, http.StatusNotFound, errorCode(err, CodePlayerNotFound), "Duel failed - "+err.Error())
+				return
+			}
+			if err != nil {
+				writeError(w, http.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Duel failed - "+err.Error())
+				return
+			}
+			response = result
+		default:
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode duel data")
+		}
+	}
+}
+
+// EventsHandler handles HTTP GET requests for a player's activity feed.
+// It returns the player's most recent notable events, oldest first.
+func EventsHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		playerID := r.URL.Query().Get("id")
+		if playerID == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Player ID required")
+			return
+		}
+
+		player, exists := gameServer.GetPlayer(playerID)
+		if !exists {
+			writeError(w, http.StatusNotFound, CodePlayerNotFound, "Player not found")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(gameServer.Events(player)); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode events")
+		}
+	}
+}
+
+// ChallengeHandler handles HTTP requests to preview a duel between two players.
+// It returns who would win if attacker challenged defender now, without changing
+// either player. Unknown players get 404 and a self-challenge gets 400.
+func ChallengeHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		attackerID := r.URL.Query().Get("attacker")
+		defenderID := r.URL.Query().Get("defender")
+		if attackerID == "" || defenderID == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Attacker and defender required")
+			return
+		}
+
+		attacker, exists := gameServer.GetPlayer(attackerID)
+		if !exists {
+			writeError(w, http.StatusNotFound, CodePlayerNotFound, "Player not found")
+			return
+		}
+
+		preview, err := gameServer.PreviewChallenge(attacker, defenderID)
+		if errors.Is(err, game.ErrUnknownOpponent) {
+			writeError(w, http.StatusNotFound, errorCode(err, CodePlayerNotFound), "Challenge failed - "+err.Error())
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Challenge failed - "+err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(preview); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode challenge preview")
+		}
+	}
+}
+
+// HeroHandler handles HTTP requests for a player's current hero with a
+// per-station breakdown of each stat, to help choose the next upgrade.
+func HeroHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		playerID := r.URL.Query().Get("id")
+		if playerID == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Player ID required")
+			return
+		}
+
+		player, exists := gameServer.GetPlayer(playerID)
+		if !exists {
+			writeError(w, http.StatusNotFound, CodePlayerNotFound, "Player not found")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(gameServer.HeroBreakdown(player)); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode hero")
+		}
+	}
+}
+
+// SimulateHandler handles HTTP requests to predict whether upgrading the
+// station given by station would let the player beat their current dungeon
+// level. It returns the battle odds before and after one hypothetical upgrade,
+// even when the player cannot afford it, without changing the player.
+func SimulateHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		playerID := r.URL.Query().Get("id")
+		if playerID == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Player ID required")
+			return
+		}
+		station := r.URL.Query().Get("station")
+		if station == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Station required")
+			return
+		}
+
+		player, exists := gameServer.GetPlayer(playerID)
+		if !exists {
+			writeError(w, http.StatusNotFound, CodePlayerNotFound, "Player not found")
+			return
+		}
+
+		simulation, err := gameServer.SimulateUpgrade(player, station)
+		if err != nil {
+			writeStationError(w, "Simulation failed", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(simulation); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode simulation")
+		}
+	}
+}
+
+// BattlePreviewHandler handles HTTP requests to preview a battle at the
+// player's current stats, combo, and difficulty tier. It fights one battle on the
+// first hero's dungeon level, or on level when given, and returns the hero,
+// the result, every attack made, and a count of the hits and misses, without
+// changing the player.
+func BattlePreviewHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		playerID := r.URL.Query().Get("playerID")
+		if playerID == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "PlayerID required")
 			return
 		}
 
-		player := gameServer.GetOrCreatePlayer(playerID)
-		success := gameServer.UpgradeStation(player, station)
-		
-		if !success {
-			http.Error(w, "Upgrade failed - insufficient funds or invalid station", http.StatusBadRequest)
+		player, exists := gameServer.GetPlayer(playerID)
+		if !exists {
+			writeError(w, http.StatusNotFound, CodePlayerNotFound, "Player not found")
 			return
 		}
-		
+
+		var level, combo int
+		var tier models.DifficultyTier
+		gameServer.View(func() {
+			level = player.Progress.DungeonLevel
+			tier = player.Difficulty
+			combo = player.Progress.Combo
+		})
+		if value := r.URL.Query().Get("level"); value != "" {
+			var err error
+			if level, err = strconv.Atoi(value); err != nil || level < 1 {
+				writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Level must be a positive integer")
+				return
+			}
+		}
+
+		hero := gameServer.Hero(player)
+		result, turns := gameServer.SimulateBattleVerbose(hero, level, tier, combo)
+
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(player); err != nil {
-			http.Error(w, "Failed to encode player data", http.StatusInternalServerError)
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"hero":         hero,
+			"dungeonLevel": level,
+			"difficulty":   tier,
+			"combo":        combo,
+			"result":       result,
+			"turns":        turns,
+			"summary":      models.SummarizeTurns(turns),
+		}); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode battle preview")
 		}
 	}
-}
\ No newline at end of file
+}
+
+// PrestigeHandler handles HTTP POST requests to prestige a player.
+// It returns the reset player data, or 400 Bad Request below the prestige threshold.
+func PrestigeHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		playerID := r.URL.Query().Get("playerID")
+		if playerID == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "PlayerID required")
+			return
+		}
+
+		player, exists := gameServer.GetPlayer(playerID)
+		if !exists {
+			writeError(w, http.StatusNotFound, CodePlayerNotFound, "Player not found")
+			return
+		}
+
+		if err := gameServer.Prestige(player); err != nil {
+			writeError(w, http.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Prestige failed - "+err.Error())
+			return
+		}
+		writePlayerJSON(w, gameServer, player, http.StatusOK)
+	}
+}
+
+// GemUpgradeHandler handles HTTP POST requests to spend a player's gems on a
+// permanent gem upgrade. It returns the updated player data, or 400 Bad Request
+// when the player has too few gems.
+func GemUpgradeHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		playerID := r.URL.Query().Get("playerID")
+		if playerID == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "PlayerID required")
+			return
+		}
+
+		player, exists := gameServer.GetPlayer(playerID)
+		if !exists {
+			writeError(w, http.StatusNotFound, CodePlayerNotFound, "Player not found")
+			return
+		}
+
+		if err := gameServer.BuyGemUpgrade(player); err != nil {
+			writeError(w, http.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Gem upgrade failed - "+err.Error())
+			return
+		}
+		writePlayerJSON(w, gameServer, player, http.StatusOK)
+	}
+}
+
+// ResearchHandler handles HTTP requests for the research tree: every node
+// with its cost, requirements, and stat bonus, in tree order. The tree only
+// changes when the server is reconfigured, so responses may be cached for an
+// hour.
+func ResearchHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		if err := json.NewEncoder(w).Encode(gameServer.ResearchTree()); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode research tree")
+		}
+	}
+}
+
+// UnlockResearchHandler handles HTTP POST requests to spend a player's gold
+// on the research node named by the node parameter. It returns the updated
+// player data, or 400 Bad Request when the node is unknown, already unlocked,
+// locked behind missing prerequisites or dungeon level, or unaffordable.
+func UnlockResearchHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		playerID := r.URL.Query().Get("playerID")
+		node := r.URL.Query().Get("node")
+		if playerID == "" || node == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "PlayerID and node required")
+			return
+		}
+
+		player, exists := gameServer.GetPlayer(playerID)
+		if !exists {
+			writeError(w, http.StatusNotFound, CodePlayerNotFound, "Player not found")
+			return
+		}
+
+		if err := gameServer.UnlockResearch(player, node); err != nil {
+			writeError(w, http.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Research failed - "+err.Error())
+			return
+		}
+		writePlayerJSON(w, gameServer, player, http.StatusOK)
+	}
+}
+
+// QuestsHandler handles HTTP GET requests for a player's daily quests: the
+// day they were drawn for in the player's time zone and each quest's target, progress, reward, and
+// whether it was claimed.
+func QuestsHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		playerID := r.URL.Query().Get("id")
+		if playerID == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Player ID required")
+			return
+		}
+
+		player, exists := gameServer.GetPlayer(playerID)
+		if !exists {
+			writeError(w, http.StatusNotFound, CodePlayerNotFound, "Player not found")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(gameServer.Quests(player)); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode quests")
+		}
+	}
+}
+
+// ClaimQuestHandler handles HTTP POST requests to claim the reward of one of
+// a player's completed daily quests, named by its type. It returns the
+// player's quests afterwards.
+func ClaimQuestHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		playerID := r.URL.Query().Get("id")
+		quest := r.URL.Query().Get("quest")
+		if playerID == "" || quest == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Player ID and quest required")
+			return
+		}
+
+		player, exists := gameServer.GetPlayer(playerID)
+		if !exists {
+			writeError(w, http.StatusNotFound, CodePlayerNotFound, "Player not found")
+			return
+		}
+
+		quests, err := gameServer.ClaimQuest(player, quest)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Claim failed - "+err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(quests); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode quests")
+		}
+	}
+}
+
+// ResetHandler handles HTTP POST requests to reset a player to a new
+// player's defaults, keeping their ID and name. It returns the reset player.
+func ResetHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		playerID := r.URL.Query().Get("id")
+		if playerID == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Player ID required")
+			return
+		}
+
+		player, exists := gameServer.GetPlayer(playerID)
+		if !exists {
+			writeError(w, http.StatusNotFound, CodePlayerNotFound, "Player not found")
+			return
+		}
+
+		gameServer.ResetPlayer(player)
+		writePlayerJSON(w, gameServer, player, http.StatusOK)
+	}
+}
+
+// maxImportBytes bounds the size of an uploaded player export.
+const maxImportBytes = 1 << 20
+
+// ExportHandler handles HTTP requests to export a player's progress.
+// It returns the player's full state signed by the server, ready to pass to /api/import.
+func ExportHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		playerID := r.URL.Query().Get("id")
+		if playerID == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Player ID required")
+			return
+		}
+
+		player, exists := gameServer.GetPlayer(playerID)
+		if !exists {
+			writeError(w, http.StatusNotFound, CodePlayerNotFound, "Player not found")
+			return
+		}
+
+		export, err := gameServer.ExportPlayer(player)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, errorCode(err, CodeInternal), "Export failed - "+err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(export); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode export")
+		}
+	}
+}
+
+// ImportHandler handles HTTP POST requests that restore a player from a signed export.
+// The player is stored under the id query parameter, or under its exported ID when
+// none is given. Exports that are malformed or whose signature does not match get 400.
+func ImportHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		var export models.SignedExport
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxImportBytes)).Decode(&export); err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Import failed - invalid export")
+			return
+		}
+
+		player, err := gameServer.ImportPlayer(&export, r.URL.Query().Get("id"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Import failed - "+err.Error())
+			return
+		}
+		writePlayerJSON(w, gameServer, player, http.StatusOK)
+	}
+}
+
+// maxBatchPlayers is the most player IDs PlayersHandler accepts in one request.
+const maxBatchPlayers = 50
+
+// PlayersHandler handles HTTP requests for several players at once, named by
+// a comma-separated ids parameter. It returns an object mapping each known ID
+// to the player's public profile, and skips unknown IDs. More than
+// maxBatchPlayers IDs get 400 Bad Request.
+func PlayersHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var ids []string
+		for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) == 0 {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "IDs required")
+			return
+		}
+		if len(ids) > maxBatchPlayers {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf("At most %d IDs per request", maxBatchPlayers))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(gameServer.Players(ids)); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode players")
+		}
+	}
+}
+
+// HallOfFameHandler handles HTTP requests for the hall of fame: every
+// archived season with its top players, oldest first, or an empty array
+// before the first season reset.
+func HallOfFameHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(gameServer.HallOfFame()); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode hall of fame")
+		}
+	}
+}
+
+// Search result limits for the limit query parameter.
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+)
+
+// SearchHandler handles HTTP requests for players whose name starts with the
+// name parameter, ignoring case. It returns 20 matches by default and at most
+// 50, and an empty array when nothing matches.
+func SearchHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		prefix := strings.TrimSpace(r.URL.Query().Get("name"))
+		if prefix == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Name required")
+			return
+		}
+
+		limit := defaultSearchLimit
+		if value := r.URL.Query().Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 {
+				writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Limit must be a positive integer")
+				return
+			}
+			limit = min(parsed, maxSearchLimit)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(gameServer.SearchPlayers(prefix, limit)); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode search results")
+		}
+	}
+}
+
+// Leaderboard size limits for the limit query parameter.
+const (
+	defaultLeaderboardLimit = 20
+	maxLeaderboardLimit     = 100
+)
+
+// LeaderboardHandler handles HTTP requests for the player leaderboard.
+// It returns the top players by deepest dungeon level reached, 20 by default and at most 100.
+func LeaderboardHandler(gameServer *game.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit := defaultLeaderboardLimit
+		if value := r.URL.Query().Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 {
+				writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Limit must be a positive integer")
+				return
+			}
+			limit = min(parsed, maxLeaderboardLimit)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(gameServer.Leaderboard(limit)); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode leaderboard")
+		}
+	}
+}
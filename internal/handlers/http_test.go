@@ -0,0 +1,212 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game/testutil"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/handlers"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// postUpgrade sends an upgrade request for the player to the upgrade handler.
+func postUpgrade(h *testutil.Harness, query string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handlers.UpgradeHandler(h.Server).ServeHTTP(recorder, httptest.NewRequest("POST", "/api/upgrade?playerID=alice&"+query, nil))
+	return recorder
+}
+
+// errorCodeOf returns the code of an error response.
+func errorCodeOf(t *testing.T, recorder *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode error response %q: %v", recorder.Body.String(), err)
+	}
+	return body.Error.Code
+}
+
+func TestUpgradeDryRunLeavesPlayerUnchanged(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	h.Server.GetOrCreatePlayer("alice")
+	before := h.Player("alice")
+
+	recorder := postUpgrade(h, "station=attack&dryRun=true")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("dry run: status %d, body %s", recorder.Code, recorder.Body)
+	}
+	var preview models.UpgradePreview
+	if err := json.Unmarshal(recorder.Body.Bytes(), &preview); err != nil {
+		t.Fatalf("decode preview: %v", err)
+	}
+	if after := h.Player("alice"); !reflect.DeepEqual(after, before) {
+		t.Fatal("dry run changed the player")
+	}
+
+	if recorder := postUpgrade(h, "station=attack"); recorder.Code != http.StatusOK {
+		t.Fatalf("upgrade: status %d, body %s", recorder.Code, recorder.Body)
+	}
+	after := h.Player("alice")
+	if station := after.Factory.Station(models.StationAttack); station.Level != preview.NewLevel || station.Cost != preview.NewCost || after.Progress.Gold != preview.RemainingGold {
+		t.Errorf("upgrade left level %d, cost %d, gold %d; dry run promised %+v", station.Level, station.Cost, after.Progress.Gold, preview)
+	}
+}
+
+func TestUpgradeDryRunFailsLikeUpgrade(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	h.Server.GetOrCreatePlayer("alice")
+	player, _ := h.Server.GetPlayer("alice")
+	h.Server.View(func() {
+		player.Progress.Gold = 0
+	})
+
+	for _, station := range []string{"armor", "wisdom"} {
+		dryRun := postUpgrade(h, "station="+station+"&dryRun=true")
+		upgrade := postUpgrade(h, "station="+station)
+		if dryRun.Code != upgrade.Code || errorCodeOf(t, dryRun) != errorCodeOf(t, upgrade) {
+			t.Errorf("%s: dry run answered %d %s, the upgrade %d %s",
+				station, dryRun.Code, errorCodeOf(t, dryRun), upgrade.Code, errorCodeOf(t, upgrade))
+		}
+	}
+}
+
+func TestPlayerIncludesUpgradeCosts(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	recorder := httptest.NewRecorder()
+	handlers.PlayerHandler(h.Server).ServeHTTP(recorder, httptest.NewRequest("GET", "/api/player?id=alice", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", recorder.Code, recorder.Body)
+	}
+
+	var body struct {
+		ID           string                                     `json:"id"`
+		UpgradeCosts map[models.StationType]models.UpgradeCosts `json:"upgradeCosts"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode player: %v", err)
+	}
+	if body.ID != "alice" {
+		t.Errorf("player ID = %q, want alice", body.ID)
+	}
+	for _, stationType := range models.StationTypes {
+		if costs := body.UpgradeCosts[stationType]; costs.Next1 != models.BaseStationCost || costs.Next10 != 11_293 {
+			t.Errorf("%s upgrade costs = %+v, want 100 for one level and 11293 for ten", stationType, costs)
+		}
+	}
+}
+
+func TestPlayerWithMissingStationGetsUpgradeCosts(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	player, _ := h.Server.GetOrCreatePlayer("alice")
+	h.Server.View(func() { delete(player.Factory.Stations, models.StationAttack) })
+
+	recorder := httptest.NewRecorder()
+	handlers.PlayerHandler(h.Server).ServeHTTP(recorder, httptest.NewRequest("GET", "/api/player?id=alice", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", recorder.Code, recorder.Body)
+	}
+	var body struct {
+		UpgradeCosts map[models.StationType]models.UpgradeCosts `json:"upgradeCosts"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode player: %v", err)
+	}
+	if _, found := body.UpgradeCosts[models.StationAttack]; found {
+		t.Errorf("upgrade costs priced the missing attack station: %v", body.UpgradeCosts)
+	}
+	if costs := body.UpgradeCosts[models.StationHP]; costs.Next1 != models.BaseStationCost {
+		t.Errorf("hp upgrade costs = %+v, want the other stations still priced", costs)
+	}
+}
+
+func TestPlayersHandlerReturnsPublicProfiles(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	player, _ := h.Server.GetOrCreatePlayer("alice")
+	h.Server.View(func() {
+		player.Progress.Gold = 12_345
+		player.AddItem(models.Item{ID: "sword", Name: "Rare Sword", Rarity: models.RarityRare, Stat: models.StationAttack, Bonus: 5})
+	})
+
+	recorder := httptest.NewRecorder()
+	handlers.PlayersHandler(h.Server).ServeHTTP(recorder, httptest.NewRequest("GET", "/api/players?ids=alice,nobody", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", recorder.Code, recorder.Body)
+	}
+	var players map[string]map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &players); err != nil {
+		t.Fatalf("decode players: %v", err)
+	}
+	if len(players) != 1 {
+		t.Fatalf("response holds %d players, want only alice: %s", len(players), recorder.Body)
+	}
+	alice := players["alice"]
+	for _, field := range []string{"gold", "progress", "factory", "inventory", "reservations", "importedExports"} {
+		if _, found := alice[field]; found {
+			t.Errorf("public profile exposes %s: %v", field, alice)
+		}
+	}
+	if strings.Contains(recorder.Body.String(), "12345") || strings.Contains(recorder.Body.String(), "Rare Sword") {
+		t.Errorf("response leaks gold or inventory: %s", recorder.Body)
+	}
+	stations, _ := alice["stations"].(map[string]interface{})
+	if alice["id"] != "alice" || alice["maxDungeonLevel"] != 1.0 || stations[string(models.StationAttack)] != 1.0 {
+		t.Errorf("profile = %v, want alice's ID, deepest level, and station levels", alice)
+	}
+}
+
+func TestResetHandler(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	alice, token, err := h.Server.AuthenticatePlayer("alice", "")
+	if err != nil {
+		t.Fatalf("create player: %v", err)
+	}
+	h.Server.View(func() { alice.Progress.Gold = 5000 })
+	handler := handlers.RequirePlayerToken(h.Server, "id", handlers.ResetHandler(h.Server))
+	reset := func(method, query, token string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, "/api/reset"+query, nil)
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	for _, test := range []struct {
+		name, method, query, token string
+		status                     int
+		code                       string
+	}{
+		{"wrong token", "POST", "?id=alice", "wrong", http.StatusUnauthorized, handlers.CodeUnauthorized},
+		{"GET", "GET", "?id=alice", token, http.StatusMethodNotAllowed, handlers.CodeMethodNotAllowed},
+		{"no ID", "POST", "", "", http.StatusBadRequest, handlers.CodeInvalidRequest},
+	} {
+		if recorder := reset(test.method, test.query, test.token); recorder.Code != test.status || errorCodeOf(t, recorder) != test.code {
+			t.Errorf("%s: status %d, body %s; want %d %s", test.name, recorder.Code, recorder.Body, test.status, test.code)
+		}
+	}
+	if gold := h.Player("alice").Progress.Gold; gold != 5000 {
+		t.Fatalf("refused resets left %d gold, want 5000", gold)
+	}
+
+	recorder := reset("POST", "?id=alice", token)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("reset: status %d, body %s", recorder.Code, recorder.Body)
+	}
+	var player models.Player
+	if err := json.Unmarshal(recorder.Body.Bytes(), &player); err != nil {
+		t.Fatalf("decode reset player: %v", err)
+	}
+	if player.ID != "alice" || player.Progress.Gold != 0 || h.Player("alice").Progress.Gold != 0 {
+		t.Errorf("reset returned %q with %d gold and left %d, want alice with none", player.ID, player.Progress.Gold, h.Player("alice").Progress.Gold)
+	}
+}
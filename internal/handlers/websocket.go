@@ -2,57 +2,310 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
 	"github.com/gorilla/websocket"
 )
 
+// Keepalive timing for WebSocket connections. The server pings every pingPeriod
+// and drops a connection that has not answered (or sent anything) within pongWait.
+const (
+	pongWait   = 60 * time.Second
+	pingPeriod = 30 * time.Second
+	pingWait   = 10 * time.Second // Deadline for writing a single ping frame
+)
+
+// maxMessageSize bounds a single message read from a client. Every client
+// message is a small JSON object; a larger frame closes the connection before
+// it is buffered, so one huge frame cannot exhaust the server's memory.
+const maxMessageSize = 4096
+
+var (
+	// errNoMessageType is returned for a client message without a string type field.
+	errNoMessageType = errors.New("message has no type")
+	// errRateLimited is returned for an upgrade or downgrade message over the
+	// per-player upgrade rate limit.
+	errRateLimited = errors.New("too many upgrade requests")
+)
+
+// Cookies the WebSocket handshake sets so a browser that lost its stored
+// player ID and token can still resume its player.
+const (
+	playerIDCookie    = "idle_dungeon_player"
+	playerTokenCookie = "idle_dungeon_token"
+	playerCookieAge   = 365 * 24 * time.Hour
+)
+
 // WebSocketHandler handles WebSocket connections for real-time multiplayer functionality.
 // It upgrades HTTP connections to WebSocket and manages client communication.
+// Browsers cannot set headers on the handshake, so the player's token is read
+// from the token query parameter; a connection for a player without a token
+// creates one and receives it in the token field of the initial gameState.
+//
+// The player is the one named by the playerID query parameter, else the one
+// in the player cookie, else a new player with a generated ID. The token is
+// likewise taken from the query before the token cookie. The handshake
+// response sets the player cookie, and the token cookie when a token is
+// issued, and the initial gameState repeats the ID in its playerID field.
+//
+// Messages are sent as JSON text frames unless the client asks for msgpack,
+// with the encoding query parameter or by requesting the msgpack subprotocol,
+// in which case they are sent as MessagePack binary frames. The query
+// parameter wins; an unknown encoding is rejected with 400 Bad Request.
+// Client messages are always JSON.
+//
+// A banned player's handshake is accepted only to close the connection
+// straight away with a policy violation code and the reason "banned".
 func WebSocketHandler(gameServer *game.Server) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		upgrader := gameServer.GetUpgrader()
-		conn, err := upgrader.Upgrade(w, r, nil)
+		logger := gameServer.Logger()
+		if !gameServer.OriginAllowed(r) {
+			logger.Warn("rejected connection, origin not allowed", "event", "connect", "remote_addr", r.RemoteAddr, "origin", r.Header.Get("Origin"))
+			writeError(w, http.StatusForbidden, CodeForbidden, "Origin not allowed")
+			return
+		}
+		if gameServer.AtCapacity() {
+			logger.Warn("rejected connection, server full", "event", "connect", "remote_addr", r.RemoteAddr)
+			writeError(w, http.StatusServiceUnavailable, CodeServerFull, "Server full")
+			return
+		}
+
+		encoding, err := game.ParseEncoding(r.URL.Query().Get("encoding"))
 		if err != nil {
-			log.Printf("WebSocket upgrade error: %v", err)
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Unknown encoding (valid encodings: "+strings.Join(game.Encodings, ", ")+")")
 			return
 		}
-		defer conn.Close()
 
 		// Get or generate player ID
 		playerID := r.URL.Query().Get("playerID")
 		if playerID == "" {
-			playerID = generatePlayerID()
+			playerID = cookieValue(r, playerIDCookie)
+		}
+		if playerID == "" {
+			playerID = generatePlayerID(gameServer.Now())
+		}
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			token = cookieValue(r, playerTokenCookie)
+		}
+
+		_, issuedToken, err := gameServer.AuthenticatePlayer(playerID, token)
+		if errors.Is(err, game.ErrBanned) {
+			logger.Warn("rejected connection, player banned", "event", "connect", "remote_addr", r.RemoteAddr, "player_id", playerID)
+			rejectBanned(w, r, gameServer)
+			return
+		}
+		if err != nil {
+			logger.Warn("rejected connection, invalid token", "event", "connect", "remote_addr", r.RemoteAddr, "player_id", playerID)
+			writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+			return
+		}
+
+		responseHeader := http.Header{}
+		responseHeader.Add("Set-Cookie", playerCookie(r, playerIDCookie, playerID).String())
+		if issuedToken != "" {
+			responseHeader.Add("Set-Cookie", playerCookie(r, playerTokenCookie, issuedToken).String())
 		}
 
-		// Get or create player and register connection
-		player := gameServer.GetOrCreatePlayer(playerID)
-		gameServer.AddClient(conn, player)
+		upgrader := gameServer.GetUpgrader()
+		conn, err := upgrader.Upgrade(w, r, responseHeader)
+		if err != nil {
+			logger.Warn("websocket upgrade failed", "event", "connect", "remote_addr", r.RemoteAddr, "error", err)
+			return
+		}
+		defer conn.Close()
+
+		// Every goroutine serving the connection watches ctx, so none outlives the handler
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		if r.URL.Query().Get("encoding") == "" && conn.Subprotocol() != "" {
+			encoding = game.Encoding(conn.Subprotocol()) // Only offered subprotocols are ever selected
+		}
+
+		// Get the player, catching up on offline progress, and register connection
+		player, offlineGains := gameServer.GetOrCreatePlayer(playerID)
+		err = gameServer.AddClient(ctx, conn, player, encoding)
+		if errors.Is(err, game.ErrBanned) {
+			// Banned while the connection was being set up
+			logger.Warn("rejected connection, player banned", "event", "connect", "remote_addr", r.RemoteAddr, "player_id", playerID)
+			conn.WriteControl(websocket.CloseMessage, bannedCloseMessage, time.Now().Add(pingWait))
+			return
+		}
+		if err != nil {
+			// Lost the race for the last slot after upgrading
+			logger.Warn("rejected connection, server full", "event", "connect", "remote_addr", r.RemoteAddr, "player_id", playerID)
+			closeMessage := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, err.Error())
+			conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(pingWait))
+			return
+		}
 		defer gameServer.RemoveClient(conn)
 
-		// Send initial game state to the newly connected client
-		initialState, _ := json.Marshal(map[string]interface{}{
-			"type":   "gameState",
-			"player": player,
-		})
+		logger = logger.With("player_id", playerID, "remote_addr", r.RemoteAddr)
+		logger.Info("client connected", "event", "connect")
+		defer logger.Info("client disconnected", "event", "disconnect")
+
+		// Send initial game state to the newly connected client, including
+		// anything earned while they were away
+		extra := map[string]interface{}{"playerID": playerID}
+		if offlineGains != nil {
+			extra["offlineGains"] = offlineGains
+		}
+		if issuedToken != "" {
+			extra["token"] = issuedToken
+		}
+		initialState := gameServer.MarshalPlayerMessage("gameState", player, extra)
 		gameServer.BroadcastToClient(conn, initialState)
 
+		conn.SetReadLimit(maxMessageSize)
+
+		// Keep the connection alive: every pong extends the read deadline, and a
+		// client that stops answering pings times out of the read loop below
+		conn.SetReadDeadline(time.Now().Add(pongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(pongWait))
+		})
+		go pingLoop(ctx, conn)
+
 		// Handle incoming messages from the client
 		for {
 			_, message, err := conn.ReadMessage()
 			if err != nil {
-				log.Printf("WebSocket read error: %v", err)
+				logReadError(logger, err)
 				break
 			}
 
-			var msg map[string]interface{}
-			if err := json.Unmarshal(message, &msg); err == nil {
-				handleClientMessage(gameServer, conn, msg)
+			msg, err := decodeClientMessage(message)
+			if err != nil {
+				logger.Debug("malformed client message", "event", "message", "error", err)
+				reply, _ := json.Marshal(errorReply(CodeInvalidRequest, "malformed message: "+err.Error()))
+				gameServer.BroadcastToClient(conn, reply)
+				continue
+			}
+			handleClientMessage(gameServer, conn, msg)
+		}
+	}
+}
+
+// decodeClientMessage parses a client message, which must be a JSON object
+// with a string type field.
+func decodeClientMessage(message []byte) (map[string]interface{}, error) {
+	var msg map[string]interface{}
+	if err := json.Unmarshal(message, &msg); err != nil {
+		return nil, err
+	}
+	if _, ok := msg["type"].(string); !ok {
+		return nil, errNoMessageType
+	}
+	return msg, nil
+}
+
+// cookieValue returns the value of the named request cookie as set by
+// playerCookie, or an empty string when the request does not carry it.
+func cookieValue(r *http.Request, name string) string {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	value, err := url.QueryUnescape(cookie.Value)
+	if err != nil {
+		return ""
+	}
+	return value
+}
+
+// playerCookie builds a long-lived cookie remembering a player's ID or token.
+// The value is escaped, since player IDs may hold characters cookies cannot.
+// It is hidden from scripts, and only sent over HTTPS when the handshake was.
+func playerCookie(r *http.Request, name, value string) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    url.QueryEscape(value),
+		Path:     "/",
+		MaxAge:   int(playerCookieAge / time.Second),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// bannedCloseMessage is the close frame a banned player's connection is closed with.
+var bannedCloseMessage = websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "banned")
+
+// rejectBanned turns away a banned player's handshake. Browsers cannot read
+// the status of a failed handshake, so the connection is upgraded and closed
+// straight away with bannedCloseMessage, whose reason the client can show; a
+// request that cannot be upgraded is answered with 403 Forbidden instead.
+func rejectBanned(w http.ResponseWriter, r *http.Request, gameServer *game.Server) {
+	if !websocket.IsWebSocketUpgrade(r) {
+		writeError(w, http.StatusForbidden, CodeBanned, "Player is banned")
+		return
+	}
+	upgrader := gameServer.GetUpgrader()
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // The upgrader has already answered with an HTTP error
+	}
+	defer conn.Close()
+	conn.WriteControl(websocket.CloseMessage, bannedCloseMessage, time.Now().Add(pingWait))
+}
+
+// expectedCloseCodes are the close codes of ordinary disconnects: the client
+// closing normally, navigating away, or dropping the connection without a close frame.
+var expectedCloseCodes = []int{
+	websocket.CloseNormalClosure,
+	websocket.CloseGoingAway,
+	websocket.CloseNoStatusReceived,
+	websocket.CloseAbnormalClosure,
+}
+
+// logReadError logs the error that ended a connection's read loop at a level
+// matching its cause. Ordinary disconnects and the server closing the connection
+// itself are debug noise, a client that stopped answering pings is worth an info
+// line, and anything else, such as a protocol error or a message over
+// maxMessageSize, is a warning.
+func logReadError(logger *slog.Logger, err error) {
+	var netErr net.Error
+	switch {
+	case websocket.IsCloseError(err, expectedCloseCodes...), errors.Is(err, net.ErrClosed):
+		logger.Debug("websocket closed", "event", "disconnect", "error", err)
+	case errors.Is(err, websocket.ErrReadLimit):
+		logger.Warn("websocket message too large", "event", "disconnect", "limit", maxMessageSize)
+	case errors.As(err, &netErr) && netErr.Timeout():
+		logger.Info("websocket timed out", "event", "disconnect", "error", err)
+	case websocket.IsUnexpectedCloseError(err, expectedCloseCodes...):
+		logger.Warn("websocket closed unexpectedly", "event", "disconnect", "error", err)
+	default:
+		logger.Warn("websocket read error", "event", "disconnect", "error", err)
+	}
+}
+
+// pingLoop sends a ping frame every pingPeriod until ctx is cancelled.
+// A failed ping closes the connection, which ends the handler's read loop.
+func pingLoop(ctx context.Context, conn *websocket.Conn) {
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(pingWait)); err != nil {
+				conn.Close()
+				return
 			}
 		}
 	}
@@ -60,6 +313,20 @@ func WebSocketHandler(gameServer *game.Server) http.HandlerFunc {
 
 // handleClientMessage processes messages received from WebSocket clients.
 // It handles different message types like upgrade requests.
+// An upgrade message with "dryRun": true is answered with an upgradePreview reply
+// to the sending connection only, and one with "max": true with an upgradeMax reply.
+// Other upgrades share the per-player rate limit with the HTTP endpoint, and a
+// rejected one is answered with an error reply giving the reason.
+// A downgrade message shares the same limit and is answered with a downgrade
+// reply carrying the refund, or an error reply.
+// A refresh message is answered with a fresh gameState for the sender alone.
+//
+// A message carrying a string or number requestId is also answered, after any
+// other reply, with an ack to the sending connection alone. The ack echoes the
+// requestId and the message type, and sets ok to whether the message
+// succeeded; a failed one carries the error code and reason too. Like every
+// reply, it is queued on the connection's serialized write path, in order
+// with the others.
 func handleClientMessage(gameServer *game.Server, conn *websocket.Conn, msg map[string]interface{}) {
 	player := gameServer.GetPlayerByConnection(conn)
 	if player == nil {
@@ -71,17 +338,261 @@ func handleClientMessage(gameServer *game.Server, conn *websocket.Conn, msg map[
 		return
 	}
 
+	err := dispatchClientMessage(gameServer, conn, player, msgType, msg)
+	switch requestID := msg["requestId"].(type) {
+	case string, float64:
+		response, _ := json.Marshal(ackReply(requestID, msgType, err))
+		gameServer.BroadcastToClient(conn, response)
+	}
+}
+
+// dispatchClientMessage performs a client message of the given type for the
+// player and sends the replies described on handleClientMessage. It returns
+// why the message failed, or nil when it succeeded.
+func dispatchClientMessage(gameServer *game.Server, conn *websocket.Conn, player *models.Player, msgType string, msg map[string]interface{}) error {
 	switch msgType {
 	case "upgrade":
 		station, ok := msg["station"].(string)
-		if ok {
-			gameServer.UpgradeStation(player, station)
+		if !ok {
+			reply := upgradeErrorReply("error", "", game.ErrUnknownStation)
+			response, _ := json.Marshal(reply)
+			gameServer.BroadcastToClient(conn, response)
+			return game.ErrUnknownStation
+		}
+
+		if dryRun, _ := msg["dryRun"].(bool); dryRun {
+			preview, err := gameServer.PreviewUpgrade(player, station)
+			reply := map[string]interface{}{
+				"type":    "upgradePreview",
+				"station": station,
+			}
+			if err == nil {
+				reply["preview"] = preview
+			} else {
+				reply = upgradeErrorReply("upgradePreview", station, err)
+			}
+			response, _ := json.Marshal(reply)
+			gameServer.BroadcastToClient(conn, response)
+			return err
+		}
+
+		if !gameServer.AllowUpgrade(player.ID) {
+			reply, _ := json.Marshal(errorReply(CodeRateLimited, errRateLimited.Error()))
+			gameServer.BroadcastToClient(conn, reply)
+			return errRateLimited
+		}
+
+		if upgradeMax, _ := msg["max"].(bool); upgradeMax {
+			result, err := gameServer.UpgradeStationMax(player, station)
+			reply := map[string]interface{}{
+				"type":    "upgradeMax",
+				"station": station,
+			}
+			if err == nil {
+				reply["result"] = result
+			} else {
+				reply = upgradeErrorReply("upgradeMax", station, err)
+			}
+			response, _ := json.Marshal(reply)
+			gameServer.BroadcastToClient(conn, response)
+			return err
+		}
+
+		_, err := gameServer.UpgradeOrReserve(player, station)
+		if err != nil {
+			response, _ := json.Marshal(upgradeErrorReply("error", station, err))
+			gameServer.BroadcastToClient(conn, response)
+		}
+		return err
+
+	case "downgrade":
+		station, _ := msg["station"].(string)
+		if !gameServer.AllowUpgrade(player.ID) {
+			reply, _ := json.Marshal(errorReply(CodeRateLimited, errRateLimited.Error()))
+			gameServer.BroadcastToClient(conn, reply)
+			return errRateLimited
+		}
+
+		result, err := gameServer.DowngradeStation(player, station)
+		reply := map[string]interface{}{
+			"type":   "downgrade",
+			"result": result,
+		}
+		if err != nil {
+			reply = upgradeErrorReply("error", station, err)
+		}
+		response, _ := json.Marshal(reply)
+		gameServer.BroadcastToClient(conn, response)
+		return err
+
+	case "setTimeZone":
+		timeZone, ok := msg["timeZone"].(string)
+		if !ok {
+			return missingField("timeZone")
+		}
+
+		err := gameServer.SetTimeZone(player, timeZone)
+		if err != nil {
+			reply, _ := json.Marshal(errorReply(errorCode(err, CodeInvalidRequest), err.Error()))
+			gameServer.BroadcastToClient(conn, reply)
+		}
+		return err
+
+	case "setDifficulty":
+		tier, ok := msg["difficulty"].(string)
+		if !ok {
+			return missingField("difficulty")
+		}
+
+		err := gameServer.SetDifficulty(player, tier)
+		if err != nil {
+			reply := errorReply(errorCode(err, CodeInvalidRequest), err.Error())
+			reply["validDifficulties"] = models.DifficultyTiers
+			response, _ := json.Marshal(reply)
+			gameServer.BroadcastToClient(conn, response)
+		}
+		return err
+
+	case "setAutoUpgrade":
+		mode, ok := msg["mode"].(string)
+		if !ok {
+			return missingField("mode")
+		}
+
+		err := gameServer.SetAutoUpgrade(player, mode)
+		if err != nil {
+			reply := errorReply(errorCode(err, CodeInvalidRequest), err.Error())
+			reply["validAutoUpgrades"] = models.AutoUpgradeModes()
+			response, _ := json.Marshal(reply)
+			gameServer.BroadcastToClient(conn, response)
+		}
+		return err
+
+	case "activateBuff":
+		buffType, ok := msg["buff"].(string)
+		if !ok {
+			return missingField("buff")
+		}
+
+		_, err := gameServer.ActivateBuff(player, buffType)
+		if err != nil {
+			reply := errorReply(errorCode(err, CodeInvalidRequest), err.Error())
+			reply["validBuffs"] = models.BuffTypes
+			response, _ := json.Marshal(reply)
+			gameServer.BroadcastToClient(conn, response)
+		}
+		return err
+
+	case "setName":
+		name, ok := msg["name"].(string)
+		if !ok {
+			return missingField("name")
 		}
+
+		return replyOnError(gameServer, conn, gameServer.SetName(player, name))
+
+	case "challenge":
+		opponentID, ok := msg["opponentID"].(string)
+		if !ok {
+			return missingField("opponentID")
+		}
+
+		// Both players are notified by the server on success
+		_, err := gameServer.Duel(player, opponentID)
+		return replyOnError(gameServer, conn, err)
+
+	case "refresh":
+		// Resend the full state to this connection only, for clients that missed updates
+		current, exists := gameServer.GetPlayer(player.ID)
+		if !exists {
+			reply, _ := json.Marshal(errorReply(CodePlayerNotFound, "player not found"))
+			gameServer.BroadcastToClient(conn, reply)
+			return game.ErrUnknownPlayer
+		}
+		gameServer.BroadcastToClient(conn, gameServer.MarshalPlayerMessage("gameState", current, nil))
+		return nil
+
+	case "prestige":
+		return replyOnError(gameServer, conn, gameServer.Prestige(player))
+
+	case "gemUpgrade":
+		return replyOnError(gameServer, conn, gameServer.BuyGemUpgrade(player))
+
+	case "unlockResearch":
+		node, ok := msg["node"].(string)
+		if !ok {
+			return missingField("node")
+		}
+
+		return replyOnError(gameServer, conn, gameServer.UnlockResearch(player, node))
+
+	case "claimQuest":
+		quest, ok := msg["quest"].(string)
+		if !ok {
+			return missingField("quest")
+		}
+
+		_, err := gameServer.ClaimQuest(player, quest)
+		return replyOnError(gameServer, conn, err)
+	}
+	return fmt.Errorf("unknown message type %q", msgType)
+}
+
+// missingField returns the error for a client message without the named string field.
+func missingField(name string) error {
+	return fmt.Errorf("message has no %s", name)
+}
+
+// replyOnError answers a failed client message with a generic error reply
+// carrying err's code and reason. It returns err either way.
+func replyOnError(gameServer *game.Server, conn *websocket.Conn, err error) error {
+	if err != nil {
+		reply, _ := json.Marshal(errorReply(errorCode(err, CodeInvalidRequest), err.Error()))
+		gameServer.BroadcastToClient(conn, reply)
+	}
+	return err
+}
+
+// ackReply builds the ack for a client message with the given request ID and
+// type, which failed with err unless it is nil.
+func ackReply(requestID interface{}, msgType string, err error) map[string]interface{} {
+	reply := map[string]interface{}{
+		"type":        "ack",
+		"requestId":   requestID,
+		"requestType": msgType,
+		"ok":          err == nil,
+	}
+	if err != nil {
+		reply["code"] = errorCode(err, CodeInvalidRequest)
+		reply["reason"] = err.Error()
 	}
+	return reply
+}
+
+// upgradeErrorReply builds the reply of the given type for a rejected upgrade.
+// Every reply carries the error code in its code field. Generic error replies
+// carry the reason in their reason field, while the
+// upgradePreview and upgradeMax replies carry it in error. The valid station
+// names are listed when the station was unknown.
+func upgradeErrorReply(replyType, station string, err error) map[string]interface{} {
+	reply := map[string]interface{}{
+		"type":    replyType,
+		"station": station,
+		"code":    errorCode(err, CodeInvalidRequest),
+	}
+	if replyType == "error" {
+		reply["reason"] = err.Error()
+	} else {
+		reply["error"] = "Upgrade failed - " + err.Error()
+	}
+	if errors.Is(err, game.ErrUnknownStation) {
+		reply["validStations"] = validStations()
+	}
+	return reply
 }
 
 // generatePlayerID creates a unique identifier for new players.
-// It uses the current timestamp in base36 encoding for uniqueness.
-func generatePlayerID() string {
-	return strconv.FormatInt(time.Now().UnixNano(), 36)
-}
\ No newline at end of file
+// It uses the given timestamp in base36 encoding for uniqueness.
+func generatePlayerID(now time.Time) string {
+	return strconv.FormatInt(now.UnixNano(), 36)
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+// The auto-upgrade modes besides naming a single station type.
+const (
+	AutoUpgradeOff      = "off"      // Never upgrade automatically; stored as an empty AutoUpgrade
+	AutoUpgradeCheapest = "cheapest" // Keep buying whichever station's next level costs least
+)
+
+// ErrUnknownAutoUpgrade is returned for an auto-upgrade mode that does not exist.
+var ErrUnknownAutoUpgrade = errors.New("unknown auto-upgrade mode")
+
+// AutoUpgradeModes lists every auto-upgrade mode: off, cheapest, and then each station type.
+func AutoUpgradeModes() []string {
+	modes := []string{AutoUpgradeOff, AutoUpgradeCheapest}
+	for _, stationType := range StationTypes {
+		modes = append(modes, string(stationType))
+	}
+	return modes
+}
+
+// ValidateAutoUpgrade checks that mode is one of the AutoUpgradeModes,
+// ignoring case and surrounding whitespace, and returns the value to store in
+// Player.AutoUpgrade: empty for off, otherwise the mode itself.
+func ValidateAutoUpgrade(mode string) (string, error) {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	for _, known := range AutoUpgradeModes() {
+		if known == mode {
+			if mode == AutoUpgradeOff {
+				return "", nil
+			}
+			return mode, nil
+		}
+	}
+	return "", ErrUnknownAutoUpgrade
+}
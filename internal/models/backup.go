@@ -0,0 +1,93 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// BackupVersion is the format version written into new backups.
+// Restores reject documents with any other version.
+const BackupVersion = 1
+
+// Backup is a versioned snapshot of the entire game world, used by operators
+// for migrations and disaster recovery.
+type Backup struct {
+	Version   int                `json:"version"`   // Backup format version
+	CreatedAt time.Time          `json:"createdAt"` // When the backup was taken
+	Players   map[string]*Player `json:"players"`   // Every player keyed by ID
+
+	HallOfFame []Season       `json:"hallOfFame,omitempty"` // Archived seasons, oldest first
+	Bans       map[string]Ban `json:"bans,omitempty"`       // Banned player IDs
+}
+
+// backupJSON is the serialized form of Backup, which keeps the players' token hashes.
+type backupJSON struct {
+	Version   int                     `json:"version"`
+	CreatedAt time.Time               `json:"createdAt"`
+	Players   map[string]StoredPlayer `json:"players"`
+
+	HallOfFame []Season       `json:"hallOfFame,omitempty"`
+	Bans       map[string]Ban `json:"bans,omitempty"`
+}
+
+// MarshalJSON serializes the backup with the players' token hashes, so
+// restoring it keeps every player's token working. Like saved state, it
+// leaves out transient player fields such as LastBattle.
+func (b Backup) MarshalJSON() ([]byte, error) {
+	var players map[string]StoredPlayer
+	if b.Players != nil {
+		players = StorePlayers(b.Players)
+	}
+	return json.Marshal(backupJSON{Version: b.Version, CreatedAt: b.CreatedAt, Players: players, HallOfFame: b.HallOfFame, Bans: b.Bans})
+}
+
+// UnmarshalJSON restores a backup written by MarshalJSON.
+func (b *Backup) UnmarshalJSON(data []byte) error {
+	var decoded backupJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*b = Backup{Version: decoded.Version, CreatedAt: decoded.CreatedAt, Players: UnwrapPlayers(decoded.Players), HallOfFame: decoded.HallOfFame, Bans: decoded.Bans}
+	return nil
+}
+
+// Validate checks that a backup is complete enough to replace the live game state.
+func (b *Backup) Validate() error {
+	if b.Version != BackupVersion {
+		return fmt.Errorf("unsupported backup version %d (expected %d)", b.Version, BackupVersion)
+	}
+	if b.Players == nil {
+		return fmt.Errorf("backup has no players section")
+	}
+	for id, player := range b.Players {
+		if err := validateStoredPlayer(id, player); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateStoredPlayer checks that a player decoded from a backup or export,
+// stored under the given ID, is complete enough to join the live game state.
+func validateStoredPlayer(id string, player *Player) error {
+	switch {
+	case player == nil:
+		return fmt.Errorf("player %q is empty", id)
+	case player.ID != id:
+		return fmt.Errorf("player %q is stored under key %q", player.ID, id)
+	case player.Factory == nil:
+		return fmt.Errorf("player %q has no factory", id)
+	case player.Progress == nil:
+		return fmt.Errorf("player %q has no progress", id)
+	}
+	return nil
+}
+
+// ReplacePlayers atomically swaps the full set of players for a new one.
+// The hall of fame and banlist are left as they are.
+func (gs *GameState) ReplacePlayers(players map[string]*Player) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	gs.Players = players
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// Ban keeps a player from connecting and from using their token until an
+// admin lifts it.
+type Ban struct {
+	BannedAt time.Time `json:"bannedAt"`         // When the player was banned
+	Reason   string    `json:"reason,omitempty"` // Why, as given by the admin
+}
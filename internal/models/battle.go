@@ -3,7 +3,63 @@ package models
 // BattleResult represents the outcome of a hero's battle against a dungeon enemy.
 // It contains information about victory/defeat and rewards earned.
 type BattleResult struct {
-	Victory     bool `json:"victory"`     // Whether the hero won the battle
-	GoldReward  int  `json:"goldReward"`  // Gold earned from the battle
-	ExpReward   int  `json:"expReward"`   // Experience points earned from the battle
-}
\ No newline at end of file
+	Victory    bool `json:"victory"`    // Whether the hero won the battle
+	GoldReward int  `json:"goldReward"` // Gold earned from the battle
+	ExpReward  int  `json:"expReward"`  // Experience points earned from the battle
+	IsBoss     bool `json:"isBoss"`     // Whether the enemy was a boss
+
+	EnemyType EnemyType `json:"enemyType"` // Archetype of the enemy fought
+
+	Item *Item    `json:"item,omitempty"` // Item dropped by the enemy, if any
+	Buff BuffType `json:"buff,omitempty"` // Buff item dropped by a defeated boss, if any
+	Gems int      `json:"gems,omitempty"` // Gems awarded for defeating a boss
+	Seed int64    `json:"seed"`           // Random seed the battle rolled from, for replaying it
+}
+
+// BattleTurn is one attack in a replayed battle.
+type BattleTurn struct {
+	Turn     int    `json:"turn"`     // Attack number, starting at 1
+	Attacker string `json:"attacker"` // "hero" or "enemy"
+	Damage   int    `json:"damage"`   // Damage dealt by the attack
+	Crit     bool   `json:"crit"`     // Whether the attack was a critical hit
+	HeroHP   int    `json:"heroHp"`   // Hero HP remaining after the attack
+	EnemyHP  int    `json:"enemyHp"`  // Enemy HP remaining after the attack
+
+	Dodged       bool `json:"dodged"`       // Whether the defender avoided the attack, which then dealt no damage
+	DoubleStrike bool `json:"doubleStrike"` // Whether this was the hero's second attack of the turn
+}
+
+// BattleSummary counts the attacks of a replayed battle by outcome.
+type BattleSummary struct {
+	HeroHits      int `json:"heroHits"`      // Hero attacks that landed
+	HeroMisses    int `json:"heroMisses"`    // Hero attacks the enemy dodged
+	EnemyHits     int `json:"enemyHits"`     // Enemy attacks that landed
+	EnemyMisses   int `json:"enemyMisses"`   // Enemy attacks the hero dodged
+	Crits         int `json:"crits"`         // Hero attacks that landed as critical hits
+	DoubleStrikes int `json:"doubleStrikes"` // Turns in which the hero attacked twice
+}
+
+// SummarizeTurns counts the hits and misses of each side over the given turns.
+func SummarizeTurns(turns []BattleTurn) BattleSummary {
+	var summary BattleSummary
+	for _, turn := range turns {
+		hero := turn.Attacker == "hero"
+		switch {
+		case hero && turn.Dodged:
+			summary.HeroMisses++
+		case hero:
+			summary.HeroHits++
+		case turn.Dodged:
+			summary.EnemyMisses++
+		default:
+			summary.EnemyHits++
+		}
+		if hero && turn.Crit {
+			summary.Crits++
+		}
+		if turn.DoubleStrike {
+			summary.DoubleStrikes++
+		}
+	}
+	return summary
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// BuffType identifies a kind of temporary hero buff.
+type BuffType string
+
+// The buff types a player can own and activate.
+const (
+	BuffAttack BuffType = "attack" // Doubles hero attack
+	BuffArmor  BuffType = "armor"  // Doubles hero armor
+	BuffHP     BuffType = "hp"     // Doubles hero HP
+	BuffLoot   BuffType = "loot"   // Doubles hero loot
+)
+
+// BuffTypes lists every buff type in display order.
+var BuffTypes = []BuffType{BuffAttack, BuffArmor, BuffHP, BuffLoot}
+
+// BuffDefinition describes what a buff type does once activated.
+type BuffDefinition struct {
+	Stat       StationType   // Hero stat the buff multiplies
+	Multiplier float64       // Factor applied to the stat while the buff is active
+	Duration   time.Duration // How long the buff lasts after activation
+}
+
+// BuffDefinitions holds the effect of every buff type.
+var BuffDefinitions = map[BuffType]BuffDefinition{
+	BuffAttack: {Stat: StationAttack, Multiplier: 2, Duration: 60 * time.Second},
+	BuffArmor:  {Stat: StationArmor, Multiplier: 2, Duration: 60 * time.Second},
+	BuffHP:     {Stat: StationHP, Multiplier: 2, Duration: 60 * time.Second},
+	BuffLoot:   {Stat: StationLoot, Multiplier: 2, Duration: 60 * time.Second},
+}
+
+var (
+	// ErrUnknownBuff is returned for a buff type that does not exist.
+	ErrUnknownBuff = errors.New("unknown buff")
+	// ErrNoBuffItem is returned when activating a buff type the player owns none of.
+	ErrNoBuffItem = errors.New("no buff of that type to activate")
+)
+
+// Buff is an activated buff, in effect until ExpiresAt.
+type Buff struct {
+	Type      BuffType  `json:"type"`      // Kind of buff
+	ExpiresAt time.Time `json:"expiresAt"` // When the buff stops applying
+}
+
+// Active reports whether the buff still applies at now.
+func (b Buff) Active(now time.Time) bool {
+	return now.Before(b.ExpiresAt)
+}
+
+// AddBuffItem gives the player one more unactivated buff of the given type.
+func (p *Player) AddBuffItem(buffType BuffType) {
+	if p.BuffItems == nil {
+		p.BuffItems = make(map[BuffType]int)
+	}
+	p.BuffItems[buffType]++
+}
+
+// ActivateBuff uses up one of the player's buff items of the given type and
+// starts its effect at now. Activating a type that is already active extends
+// it by the buff's duration instead of stacking a second multiplier. It
+// returns the buff as now in effect, or ErrUnknownBuff or ErrNoBuffItem.
+func (p *Player) ActivateBuff(buffType BuffType, now time.Time) (Buff, error) {
+	definition, known := BuffDefinitions[buffType]
+	if !known {
+		return Buff{}, ErrUnknownBuff
+	}
+	if p.BuffItems[buffType] <= 0 {
+		return Buff{}, ErrNoBuffItem
+	}
+
+	p.BuffItems[buffType]--
+	if p.BuffItems[buffType] == 0 {
+		delete(p.BuffItems, buffType)
+	}
+
+	for i, buff := range p.Buffs {
+		if buff.Type == buffType && buff.Active(now) {
+			p.Buffs[i].ExpiresAt = buff.ExpiresAt.Add(definition.Duration)
+			return p.Buffs[i], nil
+		}
+	}
+	buff := Buff{Type: buffType, ExpiresAt: now.Add(definition.Duration)}
+	p.Buffs = append(p.Buffs, buff)
+	return buff, nil
+}
+
+// BuffMultiplier returns the product of the multipliers of every buff on the
+// given stat that is active at now, or 1 when there is none.
+func (p *Player) BuffMultiplier(stat StationType, now time.Time) float64 {
+	multiplier := 1.0
+	for _, buff := range p.Buffs {
+		if definition := BuffDefinitions[buff.Type]; definition.Stat == stat && buff.Active(now) {
+			multiplier *= definition.Multiplier
+		}
+	}
+	return multiplier
+}
+
+// PruneBuffs drops every buff that has expired by now.
+func (p *Player) PruneBuffs(now time.Time) {
+	active := p.Buffs[:0]
+	for _, buff := range p.Buffs {
+		if buff.Active(now) {
+			active = append(active, buff)
+		}
+	}
+	clear(p.Buffs[len(active):])
+	p.Buffs = active
+	if len(p.Buffs) == 0 {
+		p.Buffs = nil
+	}
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+// DifficultyTier is a dungeon difficulty a player can choose. Harder tiers
+// field stronger enemies but pay more for beating them.
+type DifficultyTier string
+
+// The difficulty tiers, easiest first.
+const (
+	DifficultyNormal    DifficultyTier = "normal"
+	DifficultyHard      DifficultyTier = "hard"
+	DifficultyNightmare DifficultyTier = "nightmare"
+)
+
+// DifficultyTiers lists every difficulty tier, easiest first.
+var DifficultyTiers = []DifficultyTier{DifficultyNormal, DifficultyHard, DifficultyNightmare}
+
+// ErrUnknownDifficulty is returned for a difficulty tier name that does not exist.
+var ErrUnknownDifficulty = errors.New("unknown difficulty tier")
+
+// ValidateDifficulty checks that name is one of the DifficultyTiers, ignoring
+// case and surrounding whitespace, and returns the matching tier.
+func ValidateDifficulty(name string) (DifficultyTier, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, tier := range DifficultyTiers {
+		if string(tier) == name {
+			return tier, nil
+		}
+	}
+	return "", ErrUnknownDifficulty
+}
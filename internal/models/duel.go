@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// MaxDuelHistory is the number of recent duel results kept on each player.
+// Older results are dropped as new ones arrive.
+const MaxDuelHistory = 20
+
+// DuelResult represents the outcome of a duel between two players' current heroes.
+type DuelResult struct {
+	ChallengerID string    `json:"challengerId"` // Player who issued the challenge
+	OpponentID   string    `json:"opponentId"`   // Player who was challenged
+	WinnerID     string    `json:"winnerId"`     // ID of the winning player
+	Rounds       int       `json:"rounds"`       // Number of exchange rounds fought
+	Time         time.Time `json:"time"`         // When the duel was resolved
+}
+
+// ChallengePreview is the predicted outcome of a duel between two players'
+// current heroes. It is informational only: nothing is recorded on either player.
+type ChallengePreview struct {
+	AttackerID   string `json:"attackerId"`   // Player who would strike first
+	DefenderID   string `json:"defenderId"`   // Player being challenged
+	WinnerID     string `json:"winnerId"`     // ID of the player who would win
+	Rounds       int    `json:"rounds"`       // Number of exchange rounds the duel would last
+	AttackerHero Hero   `json:"attackerHero"` // Attacker's hero as built from their factory
+	DefenderHero Hero   `json:"defenderHero"` // Defender's hero as built from their factory
+}
+
+// AddDuel records a duel result on the player, keeping only the most recent MaxDuelHistory entries.
+func (p *Player) AddDuel(result DuelResult) {
+	p.Duels = append(p.Duels, result)
+	if len(p.Duels) > MaxDuelHistory {
+		p.Duels = append([]DuelResult(nil), p.Duels[len(p.Duels)-MaxDuelHistory:]...)
+	}
+}
@@ -0,0 +1,17 @@
+package models
+
+// EnemyType is the archetype of a dungeon enemy. Each archetype shifts the
+// enemy's HP, attack, and rewards, so different station builds are favored
+// against different enemies.
+type EnemyType string
+
+// The enemy archetypes.
+const (
+	EnemyStandard    EnemyType = "standard"    // Plain scaling, as every enemy was before archetypes
+	EnemyTank        EnemyType = "tank"        // Lots of HP and a weak attack; favors attack
+	EnemyGlassCannon EnemyType = "glassCannon" // Little HP and a piercing attack; favors HP and a fast kill
+	EnemySwarm       EnemyType = "swarm"       // Many small hits, each reduced by armor; favors armor
+)
+
+// EnemyTypes lists every enemy archetype, in the order dungeon levels cycle through them.
+var EnemyTypes = []EnemyType{EnemyStandard, EnemyTank, EnemySwarm, EnemyGlassCannon}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// MaxEvents is the number of recent activity events kept on each player.
+// Older events are dropped as new ones arrive.
+const MaxEvents = 50
+
+// The kinds of event recorded in a player's activity feed.
+const (
+	EventBossKill = "bossKill" // A hero defeated a boss
+	EventRareLoot = "rareLoot" // A victory dropped an item rarer than common
+	EventPrestige = "prestige" // The player prestiged
+
+	EventGemUpgrade = "gemUpgrade" // The player bought a gem upgrade
+	EventResearch   = "research"   // The player unlocked a research node
+	EventQuest      = "quest"      // The player claimed a daily quest reward
+)
+
+// Event is a notable moment in a player's activity feed.
+type Event struct {
+	Type    string    `json:"type"`    // Kind of event, one of the Event constants
+	Message string    `json:"message"` // Human-readable description of what happened
+	Time    time.Time `json:"time"`    // When it happened
+}
+
+// AddEvent records an event in the player's activity feed, keeping only the most recent MaxEvents entries.
+func (p *Player) AddEvent(event Event) {
+	p.Events = append(p.Events, event)
+	if len(p.Events) > MaxEvents {
+		p.Events = append([]Event(nil), p.Events[len(p.Events)-MaxEvents:]...)
+	}
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ExportVersion is the format version written into new player exports.
+// Imports reject exports with any other version, including those of version
+// 1, which had no nonce.
+const ExportVersion = 2
+
+// PlayerExport is a snapshot of one player's full state, for moving progress
+// to another browser or player ID.
+type PlayerExport struct {
+	Version    int       `json:"version"`    // Export format version
+	ExportedAt time.Time `json:"exportedAt"` // When the export was taken
+	Nonce      string    `json:"nonce"`      // Random ID of the export, recorded once it is imported
+	Player     *Player   `json:"player"`     // The exported player
+}
+
+// Validate checks that an export is complete enough to import.
+func (e *PlayerExport) Validate() error {
+	if e.Version != ExportVersion {
+		return fmt.Errorf("unsupported export version %d (expected %d)", e.Version, ExportVersion)
+	}
+	if e.Nonce == "" {
+		return fmt.Errorf("export has no nonce")
+	}
+	if e.Player == nil {
+		return fmt.Errorf("export has no player")
+	}
+	return validateStoredPlayer(e.Player.ID, e.Player)
+}
+
+// SignedExport is a PlayerExport together with the server's signature over its
+// exact encoded bytes. Data must be sent back unchanged for the signature to verify.
+type SignedExport struct {
+	Data      json.RawMessage `json:"data"`      // Encoded PlayerExport
+	Signature string          `json:"signature"` // Hex-encoded HMAC-SHA256 of Data
+}
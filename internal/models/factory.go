@@ -0,0 +1,97 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// StationType identifies one of the upgradeable stations in a hero factory.
+type StationType string
+
+// The station types every factory contains.
+const (
+	StationHP     StationType = "hp"     // Increases hero health points
+	StationArmor  StationType = "armor"  // Increases hero armor/defense
+	StationLoot   StationType = "loot"   // Increases gold rewards from battles
+	StationAttack StationType = "attack" // Increases hero attack damage
+	StationCrit   StationType = "crit"   // Increases hero critical hit chance
+)
+
+// StationTypes lists every station type in display order.
+// Adding a station to the game starts with adding it here, and giving it a
+// name and description in stationDescriptions.
+var StationTypes = []StationType{StationHP, StationArmor, StationLoot, StationAttack, StationCrit}
+
+// stationJSONSuffix is appended to a station type to form its JSON key (e.g. "hpStation").
+const stationJSONSuffix = "Station"
+
+// Factory represents the hero production facility that generates heroes for battle.
+// It holds one upgradeable station per entry in StationTypes.
+//
+// In JSON each station is written under its own "<type>Station" key, so the
+// payload keeps the layout clients and saved data already use.
+type Factory struct {
+	Stations map[StationType]*Station // Stations keyed by type
+}
+
+// Station represents an upgradeable facility within the hero factory.
+// Each station can be upgraded to increase its effectiveness multiplier.
+type Station struct {
+	Level      int     `json:"level"`      // Current upgrade level of the station (starts at 1)
+	Multiplier float64 `json:"multiplier"` // Effectiveness multiplier (increases with upgrades)
+	Cost       int64   `json:"cost"`       // Gold cost to upgrade to the next level; 64-bit so it cannot overflow on 32-bit builds
+}
+
+// NewStation creates a station at level 1 with the default multiplier and cost.
+func NewStation() *Station {
+	return &Station{Level: 1, Multiplier: 1.0, Cost: BaseStationCost}
+}
+
+// NewFactory creates a factory with every station type at level 1.
+func NewFactory() *Factory {
+	factory := &Factory{Stations: make(map[StationType]*Station, len(StationTypes))}
+	for _, stationType := range StationTypes {
+		factory.Stations[stationType] = NewStation()
+	}
+	return factory
+}
+
+// Station returns the station of the given type, or nil if the type is
+// unknown, the factory has no such station, or the factory itself is nil.
+func (f *Factory) Station(stationType StationType) *Station {
+	if f == nil {
+		return nil
+	}
+	return f.Stations[stationType]
+}
+
+// MarshalJSON writes each station under its "<type>Station" key.
+func (f *Factory) MarshalJSON() ([]byte, error) {
+	stations := make(map[string]*Station, len(f.Stations))
+	for stationType, station := range f.Stations {
+		stations[string(stationType)+stationJSONSuffix] = station
+	}
+	return json.Marshal(stations)
+}
+
+// UnmarshalJSON reads stations from their "<type>Station" keys.
+// Keys for unknown station types are ignored, and any known station missing
+// from the data (for example one added after the data was saved) starts at level 1.
+func (f *Factory) UnmarshalJSON(data []byte) error {
+	var stations map[string]*Station
+	if err := json.Unmarshal(data, &stations); err != nil {
+		return err
+	}
+
+	f.Stations = make(map[StationType]*Station, len(StationTypes))
+	for _, stationType := range StationTypes {
+		f.Stations[stationType] = NewStation()
+	}
+	for key, station := range stations {
+		stationType := StationType(strings.TrimSuffix(key, stationJSONSuffix))
+		if _, known := f.Stations[stationType]; known && station != nil {
+			f.Stations[stationType] = station
+		}
+	}
+	return nil
+}
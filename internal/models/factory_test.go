@@ -0,0 +1,81 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestFactoryJSONRoundTrip(t *testing.T) {
+	factory := NewFactory()
+	for i, stationType := range StationTypes {
+		station := factory.Station(stationType)
+		station.Level = i + 2
+		station.Multiplier = 1.5 + float64(i)
+		station.Cost = int64(1000 * (i + 1))
+	}
+
+	data, err := json.Marshal(factory)
+	if err != nil {
+		t.Fatalf("encode factory: %v", err)
+	}
+	var decoded Factory
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("decode factory: %v", err)
+	}
+	if !reflect.DeepEqual(&decoded, factory) {
+		t.Errorf("decoded factory = %+v, want %+v", decoded.Stations, factory.Stations)
+	}
+}
+
+func TestFactoryJSONKeepsStationKeys(t *testing.T) {
+	data, err := json.Marshal(NewFactory())
+	if err != nil {
+		t.Fatalf("encode factory: %v", err)
+	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		t.Fatalf("decode factory keys: %v", err)
+	}
+	for _, key := range []string{"hpStation", "armorStation", "lootStation", "attackStation", "critStation"} {
+		if _, found := keys[key]; !found {
+			t.Errorf("factory JSON %s lacks %q", data, key)
+		}
+	}
+}
+
+func TestFactoryDecodesOlderLayout(t *testing.T) {
+	// Saved before the crit station existed, with a key no build knows
+	data := `{"hpStation":{"level":3,"multiplier":1.4,"cost":225},"armorStation":{"level":1,"multiplier":1,"cost":100},` +
+		`"lootStation":{"level":1,"multiplier":1,"cost":100},"attackStation":{"level":2,"multiplier":1.2,"cost":150},"speedStation":{"level":9}}`
+	var factory Factory
+	if err := json.Unmarshal([]byte(data), &factory); err != nil {
+		t.Fatalf("decode factory: %v", err)
+	}
+
+	if station := factory.Station(StationHP); station.Level != 3 || station.Cost != 225 {
+		t.Errorf("hp station = %+v, want level 3 costing 225", *station)
+	}
+	if station := factory.Station(StationCrit); !reflect.DeepEqual(station, NewStation()) {
+		t.Errorf("missing crit station decoded as %+v, want a level 1 station", station)
+	}
+	if len(factory.Stations) != len(StationTypes) {
+		t.Errorf("factory has %d stations, want %d", len(factory.Stations), len(StationTypes))
+	}
+}
+
+func TestFactoryStationLookup(t *testing.T) {
+	factory := NewFactory()
+	for _, stationType := range StationTypes {
+		if station := factory.Station(stationType); station == nil || station.Level != 1 {
+			t.Errorf("Station(%s) = %+v, want a level 1 station", stationType, station)
+		}
+	}
+	if station := factory.Station("speed"); station != nil {
+		t.Errorf("Station(speed) = %+v, want nil", station)
+	}
+	var missing *Factory
+	if station := missing.Station(StationHP); station != nil {
+		t.Errorf("Station on a nil factory = %+v, want nil", station)
+	}
+}
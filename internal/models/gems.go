@@ -0,0 +1,37 @@
+package models
+
+import "errors"
+
+// Gem upgrades are bought with gems rather than gold. Each one adds
+// GemUpgradeBonus to a permanent multiplier on every hero stat but crit chance,
+// and costs GemUpgradeCost more gems than the one before.
+const (
+	GemUpgradeBonus = 0.05
+	GemUpgradeCost  = 5
+)
+
+// ErrNotEnoughGems is returned when buying a gem upgrade the player cannot afford.
+var ErrNotEnoughGems = errors.New("not enough gems")
+
+// NextGemUpgradeCost returns the gems the player's next gem upgrade costs.
+func (p *Player) NextGemUpgradeCost() int {
+	return GemUpgradeCost * (p.GemUpgrades + 1)
+}
+
+// GemMultiplier returns the permanent hero multiplier from the player's gem upgrades.
+func (p *Player) GemMultiplier() float64 {
+	return 1 + GemUpgradeBonus*float64(p.GemUpgrades)
+}
+
+// BuyGemUpgrade spends the gems for one more gem upgrade. When the player
+// cannot afford it, it returns ErrNotEnoughGems and spends nothing, so gems
+// never go below zero.
+func (p *Player) BuyGemUpgrade() error {
+	cost := p.NextGemUpgradeCost()
+	if p.Progress.Gems < cost {
+		return ErrNotEnoughGems
+	}
+	p.Progress.Gems -= cost
+	p.GemUpgrades++
+	return nil
+}
@@ -0,0 +1,12 @@
+package models
+
+// Guild is a group of players who share a bonus on every member's hero stats.
+// Membership is stored on each member's Player, so a guild exists exactly as
+// long as it has at least one member.
+type Guild struct {
+	Name          string   `json:"name"`          // Unique guild name
+	Members       []string `json:"members"`       // IDs of the member players, sorted
+	ActiveMembers int      `json:"activeMembers"` // Members seen recently, who count towards the bonus
+	Contribution  int      `json:"contribution"`  // Lifetime battles won by all members combined
+	Bonus         float64  `json:"bonus"`         // Multiplier applied to every member's hero stats
+}
@@ -0,0 +1,34 @@
+package models
+
+// MaxHeroes is the number of heroes a player can field at once, counting the first.
+const MaxHeroes = 3
+
+// HeroSlot is an additional hero a player has unlocked. Each one fights its
+// own way down the dungeon, while gold and experience go to the shared Progress.
+type HeroSlot struct {
+	DungeonLevel int `json:"dungeonLevel"` // Dungeon level this hero has reached
+}
+
+// HeroCount returns how many heroes the player fields, including the first.
+func (p *Player) HeroCount() int {
+	return 1 + len(p.Heroes)
+}
+
+// DungeonLevels returns the dungeon level of each of the player's heroes for
+// updating in place. The first hero's track is Progress.DungeonLevel, so
+// players saved before extra heroes existed keep a single hero.
+func (p *Player) DungeonLevels() []*int {
+	levels := []*int{&p.Progress.DungeonLevel}
+	for _, hero := range p.Heroes {
+		levels = append(levels, &hero.DungeonLevel)
+	}
+	return levels
+}
+
+// TrackMaxDungeonLevel raises Progress.MaxDungeonLevel to the deepest level
+// any of the player's heroes is on now. It never lowers it.
+func (p *Player) TrackMaxDungeonLevel() {
+	for _, level := range p.DungeonLevels() {
+		p.Progress.MaxDungeonLevel = max(p.Progress.MaxDungeonLevel, *level)
+	}
+}
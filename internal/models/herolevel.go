@@ -0,0 +1,16 @@
+package models
+
+import "math"
+
+// HeroLevelExperience tunes the hero level curve: reaching level n takes
+// HeroLevelExperience * (n-1)^2 experience, so each level costs more than the last.
+const HeroLevelExperience = 100
+
+// HeroLevel returns the hero level earned with the given experience.
+// Heroes start at level 1 with no experience; negative experience counts as none.
+func HeroLevel(experience int) int {
+	if experience <= 0 {
+		return 1
+	}
+	return 1 + int(math.Sqrt(float64(experience)/HeroLevelExperience))
+}
@@ -0,0 +1,30 @@
+package models
+
+// HeroBreakdown is a player's current hero together with how each of its
+// stats was derived, so players can see what upgrading each station would do.
+type HeroBreakdown struct {
+	Hero               Hero            `json:"hero"`               // The hero as it fights
+	HeroLevel          int             `json:"heroLevel"`          // Hero level earned from experience
+	PrestigeMultiplier float64         `json:"prestigeMultiplier"` // Multiplier from prestige, applied to every stat but crit chance
+	GuildMultiplier    float64         `json:"guildMultiplier"`    // Multiplier from the player's guild, applied to every stat but crit chance
+	GemMultiplier      float64         `json:"gemMultiplier"`      // Multiplier from gem upgrades, applied to every stat but crit chance
+	Stats              []StatBreakdown `json:"stats"`              // One entry per station type, in display order
+}
+
+// StatBreakdown shows how one station turns into a hero stat. Value is
+// Base times EffectiveMultiplier, times the prestige, guild, gem, buff, and
+// research multipliers, truncated to an integer, plus LevelBonus and
+// ItemBonus. Crit chance is the exception: it is Base times
+// EffectiveMultiplier and ResearchMultiplier alone, capped, and not truncated.
+type StatBreakdown struct {
+	Stat                StationType `json:"stat"`                // Station and hero stat
+	Base                float64     `json:"base"`                // Stat value before any multiplier
+	StationLevel        int         `json:"stationLevel"`        // Current level of the station
+	StationMultiplier   float64     `json:"stationMultiplier"`   // The station's own multiplier
+	EffectiveMultiplier float64     `json:"effectiveMultiplier"` // The station multiplier after the soft cap
+	BuffMultiplier      float64     `json:"buffMultiplier"`      // Product of the active buffs on the stat
+	ResearchMultiplier  float64     `json:"researchMultiplier"`  // Multiplier from the unlocked research nodes on the stat
+	LevelBonus          int         `json:"levelBonus"`          // Flat bonus from the hero level
+	ItemBonus           int         `json:"itemBonus"`           // Flat bonus from equipped items
+	Value               float64     `json:"value"`               // Final stat value, as on Hero
+}
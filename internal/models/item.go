@@ -0,0 +1,36 @@
+package models
+
+// MaxInventorySize is the number of items a player can hold.
+// Items dropped while the inventory is full are lost.
+const MaxInventorySize = 50
+
+// Rarity grades how strong an item is.
+type Rarity string
+
+// The item rarities, from most to least common.
+const (
+	RarityCommon    Rarity = "common"
+	RarityRare      Rarity = "rare"
+	RarityEpic      Rarity = "epic"
+	RarityLegendary Rarity = "legendary"
+)
+
+// Item is a piece of equipment dropped by a dungeon enemy.
+// While equipped, it adds a flat bonus to one hero stat.
+type Item struct {
+	ID       string      `json:"id"`       // Unique identifier for the item
+	Name     string      `json:"name"`     // Display name, e.g. "Rare Sword"
+	Rarity   Rarity      `json:"rarity"`   // How rare and strong the item is
+	Stat     StationType `json:"stat"`     // Hero stat the bonus applies to (hp, armor, or attack)
+	Bonus    int         `json:"bonus"`    // Flat amount added to the stat
+	Equipped bool        `json:"equipped"` // Whether the bonus applies to the player's heroes
+}
+
+// AddItem stores an item in the player's inventory, reporting false when it is full.
+func (p *Player) AddItem(item Item) bool {
+	if len(p.Inventory) >= MaxInventorySize {
+		return false
+	}
+	p.Inventory = append(p.Inventory, item)
+	return true
+}
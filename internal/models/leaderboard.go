@@ -0,0 +1,14 @@
+package models
+
+// LeaderboardEntry is a single player's row in the leaderboard.
+type LeaderboardEntry struct {
+	ID              string `json:"id"`              // Player ID
+	Name            string `json:"name"`            // Player display name
+	DungeonLevel    int    `json:"dungeonLevel"`    // Current dungeon level
+	MaxDungeonLevel int    `json:"maxDungeonLevel"` // Deepest dungeon level ever reached, which the ranking uses
+	Gold            int    `json:"gold"`            // Current gold
+
+	TotalBattles    int     `json:"totalBattles"`    // Lifetime battles fought
+	BattlesWon      int     `json:"battlesWon"`      // Lifetime battles won
+	PlaytimeSeconds float64 `json:"playtimeSeconds"` // Lifetime game time simulated
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SchemaVersion is the version of the persisted game state this build writes.
+// Version 1 is the state saved before it carried a version at all.
+// Adding a field that old saves lack a sensible zero value for means bumping
+// SchemaVersion and appending a migration.
+const SchemaVersion = 3
+
+// ErrUnsupportedSchemaVersion is returned when loading state saved by a newer
+// build, whose fields this build would silently drop on the next save.
+var ErrUnsupportedSchemaVersion = errors.New("unsupported schema version")
+
+// migrations[i] upgrades a player saved with schema version i+1 to version i+2.
+var migrations = []func(*Player){
+	migrateV1,
+	migrateV2,
+}
+
+// MigratePlayers upgrades players loaded from state saved with the given schema
+// version to SchemaVersion, in place. A version of zero means the state
+// predates versioning and is treated as version 1. It returns
+// ErrUnsupportedSchemaVersion, changing nothing, for versions newer than SchemaVersion.
+func MigratePlayers(version int, players map[string]*Player) error {
+	if version == 0 {
+		version = 1
+	}
+	if version < 1 || version > SchemaVersion {
+		return fmt.Errorf("%w %d (this build reads up to %d)", ErrUnsupportedSchemaVersion, version, SchemaVersion)
+	}
+
+	for ; version < SchemaVersion; version++ {
+		for _, player := range players {
+			migrations[version-1](player)
+		}
+	}
+	return nil
+}
+
+// migrateV1 fills in what version 1 saves may lack: a factory and progress for
+// players saved without them (Factory's decoding already adds stations such as
+// crit that were introduced later), the derived hero level, the base prestige
+// multiplier, and the normal difficulty tier.
+func migrateV1(player *Player) {
+	if player.Factory == nil {
+		player.Factory = NewFactory()
+	}
+	if player.Progress == nil {
+		player.Progress = &Progress{DungeonLevel: 1}
+	}
+	if player.Progress.DungeonLevel < 1 {
+		player.Progress.DungeonLevel = 1
+	}
+	player.Progress.HeroLevel = HeroLevel(player.Progress.Experience)
+	if player.PrestigeLevel == 0 && player.PrestigeMultiplier <= 0 {
+		player.PrestigeMultiplier = 1.0
+	}
+	if player.Difficulty == "" {
+		player.Difficulty = DifficultyNormal
+	}
+}
+
+// migrateV2 fills in the deepest dungeon level reached, which version 2 saves
+// lack, from the levels the player's heroes are on.
+func migrateV2(player *Player) {
+	player.TrackMaxDungeonLevel()
+}
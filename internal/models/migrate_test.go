@@ -0,0 +1,85 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// v1State is state saved before schema versions, the crit station, prestige,
+// and difficulty tiers existed.
+const v1State = `{"players":{"alice":{"id":"alice","name":"Alice",` +
+	`"factory":{"hpStation":{"level":4,"multiplier":1.6,"cost":337},"armorStation":{"level":1,"multiplier":1,"cost":100},` +
+	`"lootStation":{"level":1,"multiplier":1,"cost":100},"attackStation":{"level":2,"multiplier":1.2,"cost":150}},` +
+	`"progress":{"dungeonLevel":7,"gold":250,"experience":5000}},` +
+	`"bob":{"id":"bob","name":"Bob"}}}`
+
+func TestLoadMigratesV1State(t *testing.T) {
+	var gs GameState
+	if err := json.Unmarshal([]byte(v1State), &gs); err != nil {
+		t.Fatalf("load v1 state: %v", err)
+	}
+
+	alice := gs.Players["alice"]
+	if station := alice.Factory.Station(StationCrit); station == nil || station.Level != 1 {
+		t.Errorf("missing crit station loaded as %+v, want a level 1 station", station)
+	}
+	if station := alice.Factory.Station(StationHP); station.Level != 4 || station.Cost != 337 {
+		t.Errorf("saved hp station loaded as %+v", *station)
+	}
+	if alice.Progress.HeroLevel != HeroLevel(5000) {
+		t.Errorf("hero level = %d, want %d derived from experience", alice.Progress.HeroLevel, HeroLevel(5000))
+	}
+	if alice.PrestigeMultiplier != 1 || alice.Difficulty != DifficultyNormal {
+		t.Errorf("prestige multiplier %g and difficulty %q, want 1 and normal", alice.PrestigeMultiplier, alice.Difficulty)
+	}
+	if alice.Progress.MaxDungeonLevel != 7 {
+		t.Errorf("deepest level reached = %d, want the current 7", alice.Progress.MaxDungeonLevel)
+	}
+
+	// A player saved without a factory or progress gets fresh ones
+	bob := gs.Players["bob"]
+	if bob.Factory == nil || bob.Progress == nil || bob.Progress.DungeonLevel != 1 {
+		t.Errorf("player without factory or progress loaded as %+v", bob)
+	}
+}
+
+func TestLoadMigratesV2State(t *testing.T) {
+	data := `{"schemaVersion":2,"players":{"alice":{"id":"alice","factory":{},"progress":{"dungeonLevel":12},` +
+		`"heroes":[{"dungeonLevel":30}],"prestigeMultiplier":1,"difficulty":"hard"}}}`
+	var gs GameState
+	if err := json.Unmarshal([]byte(data), &gs); err != nil {
+		t.Fatalf("load v2 state: %v", err)
+	}
+	if alice := gs.Players["alice"]; alice.Progress.MaxDungeonLevel != 30 || alice.Difficulty != DifficultyHard {
+		t.Errorf("v2 player loaded with deepest level %d and difficulty %q, want 30 and hard", alice.Progress.MaxDungeonLevel, alice.Difficulty)
+	}
+}
+
+func TestLoadRejectsNewerSchemaVersion(t *testing.T) {
+	data := fmt.Sprintf(`{"schemaVersion":%d,"players":{"alice":{"id":"alice"}}}`, SchemaVersion+1)
+	gs := NewGameState()
+	if err := json.Unmarshal([]byte(data), gs); !errors.Is(err, ErrUnsupportedSchemaVersion) {
+		t.Fatalf("loading a newer schema version returned %v, want ErrUnsupportedSchemaVersion", err)
+	}
+	if len(gs.Players) != 0 {
+		t.Errorf("rejected state still replaced the players with %v", gs.Players)
+	}
+}
+
+func TestSaveWritesCurrentSchemaVersion(t *testing.T) {
+	data, err := json.Marshal(NewGameState())
+	if err != nil {
+		t.Fatalf("save state: %v", err)
+	}
+	var saved struct {
+		SchemaVersion int `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("decode saved state: %v", err)
+	}
+	if saved.SchemaVersion != SchemaVersion {
+		t.Errorf("saved schema version %d, want %d", saved.SchemaVersion, SchemaVersion)
+	}
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxNameLength is the longest display name, in characters, a player may choose.
+const MaxNameLength = 24
+
+var (
+	// ErrNameEmpty is returned for a name that is blank after trimming.
+	ErrNameEmpty = errors.New("name must not be empty")
+	// ErrNameTooLong is returned for a name longer than MaxNameLength characters.
+	ErrNameTooLong = errors.New("name must be at most 24 characters")
+	// ErrNameInvalid is returned for a name containing control characters.
+	ErrNameInvalid = errors.New("name must not contain control characters")
+)
+
+// ValidateName trims surrounding whitespace from a requested display name and
+// checks it is 1 to MaxNameLength characters with no control characters.
+// It returns the trimmed name when valid.
+func ValidateName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	switch {
+	case !utf8.ValidString(name):
+		return "", ErrNameInvalid
+	case name == "":
+		return "", ErrNameEmpty
+	case utf8.RuneCountInString(name) > MaxNameLength:
+		return "", ErrNameTooLong
+	case strings.IndexFunc(name, unicode.IsControl) >= 0:
+		return "", ErrNameInvalid
+	}
+	return name, nil
+}
+
+// SanitizeName makes a stored name safe to show: invalid UTF-8 and control
+// characters are dropped and surrounding whitespace is trimmed. Names that
+// passed ValidateName come out unchanged.
+func SanitizeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, strings.ToValidUTF8(name, ""))
+	return strings.TrimSpace(name)
+}
+
+// TruncateName shortens a name to at most length characters, ending a longer
+// name in an ellipsis that counts as one of them. A length of zero or less
+// leaves the name whole.
+func TruncateName(name string, length int) string {
+	runes := []rune(name)
+	if length <= 0 || len(runes) <= length {
+		return name
+	}
+	return string(runes[:length-1]) + "…"
+}
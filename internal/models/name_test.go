@@ -0,0 +1,68 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTruncateName(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		length int
+		want   string
+	}{
+		{"Alice", 24, "Alice"},
+		{"Alice", 5, "Alice"},
+		{"Alexandria", 5, "Alex…"},
+		{"Zoë Ångström", 4, "Zoë…"}, // Counted in characters, not bytes
+		{"Alexandria", 0, "Alexandria"},
+	} {
+		if got := TruncateName(test.name, test.length); got != test.want {
+			t.Errorf("TruncateName(%q, %d) = %q, want %q", test.name, test.length, got, test.want)
+		}
+	}
+}
+
+func TestSanitizeName(t *testing.T) {
+	for _, test := range []struct {
+		name, want string
+	}{
+		{"Alice", "Alice"},
+		{"  Alice\n", "Alice"},
+		{"Al\x1b[31mice", "Al[31mice"},
+		{"Al\xffice", "Alice"},
+		{"\x00\x07", ""},
+	} {
+		if got := SanitizeName(test.name); got != test.want {
+			t.Errorf("SanitizeName(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestValidateRepairsStoredNames(t *testing.T) {
+	for _, test := range []struct {
+		name, want string
+	}{
+		{"A name saved long before names were limited to 24", "A name saved long befor…"},
+		{"Bell\x07", "Bell"},
+		{"\x07\x07", "Player alice"},
+	} {
+		player := NewPlayer("alice")
+		player.Name = test.name
+		var invalid *InvalidFieldsError
+		if err := player.Validate(); !errors.As(err, &invalid) || invalid.Fields[0] != "name" {
+			t.Errorf("validating name %q returned %v, want the name reported", test.name, err)
+		}
+		if player.Name != test.want {
+			t.Errorf("stored name %q repaired to %q, want %q", test.name, player.Name, test.want)
+		}
+		if _, err := ValidateName(player.Name); err != nil {
+			t.Errorf("repaired name %q is still invalid: %v", player.Name, err)
+		}
+	}
+
+	player := NewPlayer("alice")
+	if err := player.Validate(); err != nil {
+		t.Errorf("validating a new player's name: %v", err)
+	}
+}
@@ -0,0 +1,8 @@
+package models
+
+// Notification is an event the server pushes to a player, such as a finished
+// duel or an upgrade completed from a reservation.
+type Notification struct {
+	Type string      `json:"type"`           // Kind of event (e.g. "duel", "upgradeCompleted")
+	Data interface{} `json:"data,omitempty"` // Event-specific payload
+}
@@ -0,0 +1,13 @@
+package models
+
+// OfflineGains summarizes the progress a player made while disconnected.
+// It is sent once in the initial gameState message after reconnecting.
+type OfflineGains struct {
+	Seconds    int `json:"seconds"`    // Offline time that was simulated (after capping)
+	Battles    int `json:"battles"`    // Number of battles fought while away
+	Victories  int `json:"victories"`  // Battles won while away
+	Gold       int `json:"gold"`       // Gold earned while away
+	Experience int `json:"experience"` // Experience earned while away
+	Gems       int `json:"gems"`       // Gems awarded by bosses beaten while away
+	Levels     int `json:"levels"`     // Dungeon levels gained while away
+}
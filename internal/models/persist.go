@@ -0,0 +1,131 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Persister saves and loads the complete game state so progress survives restarts.
+// Implementations must tolerate Save being called while the game is running.
+type Persister interface {
+	// Save writes a snapshot of the game state to storage.
+	Save(gs *GameState) error
+	// Load reads the stored game state, returning an empty state when nothing has been saved yet.
+	Load() (*GameState, error)
+}
+
+// PersistenceStatus reports how recent saves of the game state went. While
+// saves fail the game keeps running from memory, so progress made since
+// LastSaved is lost if the process exits before a save succeeds again.
+type PersistenceStatus struct {
+	Degraded            bool       `json:"degraded"`            // Whether the latest save failed
+	ConsecutiveFailures int        `json:"consecutiveFailures"` // Saves failed in a row since the last success
+	LastError           string     `json:"lastError,omitempty"` // Error of the latest failed save, while degraded
+	LastSaved           *time.Time `json:"lastSaved,omitempty"` // When a save last succeeded (nil before the first)
+}
+
+// PlayerLoader is implemented by persisters that can read back a single
+// stored player. The server only evicts idle players from memory when its
+// persister is a PlayerLoader, so they can be reloaded when they return.
+type PlayerLoader interface {
+	// LoadPlayer reads the stored player with the given ID, reporting false when none is stored.
+	LoadPlayer(playerID string) (*Player, bool, error)
+}
+
+// StateReplacer is implemented by persisters whose Save only adds to and
+// updates what is stored, such as one keeping a row per player. Replace makes
+// the stored state exactly the given one instead, deleting any player,
+// season, or ban it lacks, so a restored backup is all that a restart loads.
+// Persisters that rewrite everything on each Save need not implement it.
+type StateReplacer interface {
+	// Replace writes gs in place of everything stored.
+	Replace(gs *GameState) error
+}
+
+// StoredPlayer is the persisted form of a player. A player's own JSON is what
+// clients and exports see, so it leaves out the token hash; storage and
+// backups wrap the player to keep it.
+type StoredPlayer struct {
+	*Player
+	TokenHash string `json:"tokenHash,omitempty"` // SHA-256 hash of the player's access token
+}
+
+// StorePlayers wraps a copy of each player for persisting, leaving out
+// transient fields such as LastBattle.
+func StorePlayers(players map[string]*Player) map[string]StoredPlayer {
+	stored := make(map[string]StoredPlayer, len(players))
+	for id, player := range players {
+		if player == nil {
+			stored[id] = StoredPlayer{}
+			continue
+		}
+		persisted := *player
+		persisted.LastBattle = nil
+		stored[id] = StoredPlayer{Player: &persisted, TokenHash: player.TokenHash}
+	}
+	return stored
+}
+
+// Unwrap returns the stored player with its token hash put back, or nil when
+// nothing was stored.
+func (sp StoredPlayer) Unwrap() *Player {
+	if sp.Player != nil {
+		sp.Player.TokenHash = sp.TokenHash
+	}
+	return sp.Player
+}
+
+// UnwrapPlayers returns the players held by each stored player.
+func UnwrapPlayers(stored map[string]StoredPlayer) map[string]*Player {
+	if stored == nil {
+		return nil
+	}
+	players := make(map[string]*Player, len(stored))
+	for id, player := range stored {
+		players[id] = player.Unwrap()
+	}
+	return players
+}
+
+// gameStateJSON is the serialized form of GameState.
+type gameStateJSON struct {
+	SchemaVersion int                     `json:"schemaVersion"` // Layout of the players, see SchemaVersion; absent before version 2
+	Players       map[string]StoredPlayer `json:"players"`
+	HallOfFame    []Season                `json:"hallOfFame,omitempty"` // Archived seasons, absent before the first season reset
+	Bans          map[string]Ban          `json:"bans"`                 // Banned player IDs, null when the snapshot leaves the banlist alone
+}
+
+// MarshalJSON serializes the game state while holding its read lock,
+// so the players map cannot change part-way through a snapshot.
+// Transient player fields such as LastBattle are left out, and token hashes
+// are kept.
+func (gs *GameState) MarshalJSON() ([]byte, error) {
+	gs.mutex.RLock()
+	defer gs.mutex.RUnlock()
+
+	return json.Marshal(gameStateJSON{SchemaVersion: SchemaVersion, Players: StorePlayers(gs.Players), HallOfFame: gs.HallOfFame, Bans: gs.Bans})
+}
+
+// UnmarshalJSON restores a game state previously written by MarshalJSON,
+// migrating players saved with an older schema version. State from a newer
+// schema version is rejected with ErrUnsupportedSchemaVersion.
+func (gs *GameState) UnmarshalJSON(data []byte) error {
+	var decoded gameStateJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	players := UnwrapPlayers(decoded.Players)
+	if players == nil {
+		players = make(map[string]*Player)
+	}
+	if err := MigratePlayers(decoded.SchemaVersion, players); err != nil {
+		return err
+	}
+
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	gs.Players = players
+	gs.HallOfFame = decoded.HallOfFame
+	gs.Bans = decoded.Bans
+	return nil
+}
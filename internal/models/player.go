@@ -10,35 +10,58 @@ import (
 // Each player has a unique ID, factory for upgrading hero stats,
 // and progress tracking their advancement through the dungeon.
 type Player struct {
-	ID       string    `json:"id"`       // Unique identifier for the player
-	Name     string    `json:"name"`     // Display name for the player
-	Factory  *Factory  `json:"factory"`  // Hero factory with upgradeable stations
-	Progress *Progress `json:"progress"` // Player's dungeon progression and resources
-	LastSeen time.Time `json:"lastSeen"` // Last time the player was active
-}
+	ID           string         `json:"id"`                     // Unique identifier for the player
+	Name         string         `json:"name"`                   // Display name for the player
+	Factory      *Factory       `json:"factory"`                // Hero factory with upgradeable stations
+	Progress     *Progress      `json:"progress"`               // Player's dungeon progression and resources
+	LastSeen     time.Time      `json:"lastSeen"`               // Last time the player was active
+	TimeZone     string         `json:"timeZone,omitempty"`     // IANA time zone for daily resets (empty means UTC)
+	Duels        []DuelResult   `json:"duels,omitempty"`        // Most recent duel results, oldest first
+	Reservations []Reservation  `json:"reservations,omitempty"` // Upgrades waiting for enough gold, in request order
+	Inventory    []Item         `json:"inventory,omitempty"`    // Items dropped in battle, oldest first
+	Events       []Event        `json:"events,omitempty"`       // Most recent notable events, oldest first
+	Heroes       []*HeroSlot    `json:"heroes,omitempty"`       // Additional unlocked heroes beyond the first
+	TokenHash    string         `json:"-"`                      // SHA-256 hash of the player's access token; only persisted, see StoredPlayer
+	Guild        string         `json:"guild,omitempty"`        // Name of the guild the player belongs to (empty when in none)
+	Difficulty   DifficultyTier `json:"difficulty"`             // Dungeon difficulty tier the player's heroes fight on
+	AutoUpgrade  string         `json:"autoUpgrade,omitempty"`  // Upgrades bought automatically after battles: a station type, AutoUpgradeCheapest, or empty for none
 
-// Factory represents the hero production facility that generates heroes for battle.
-// It contains four different stations that can be upgraded to improve hero stats.
-type Factory struct {
-	HPStation     *Station `json:"hpStation"`     // Station that increases hero health points
-	ArmorStation  *Station `json:"armorStation"`  // Station that increases hero armor/defense
-	LootStation   *Station `json:"lootStation"`   // Station that increases gold rewards from battles
-	AttackStation *Station `json:"attackStation"` // Station that increases hero attack damage
-}
+	BuffItems map[BuffType]int `json:"buffItems,omitempty"` // Unactivated buffs the player owns, counted by type
+	Buffs     []Buff           `json:"buffs,omitempty"`     // Activated buffs; expired ones are pruned every tick
+
+	LastLoginDay   string    `json:"lastLoginDay,omitempty"` // Day of the latest login in the player's time zone, as YYYY-MM-DD
+	LoginDayStart  time.Time `json:"loginDayStart,omitzero"` // When the day of the latest counted login began
+	LoginStreak    int       `json:"loginStreak"`            // Consecutive days the player has logged in
+	NextLoginBonus int       `json:"nextLoginBonus"`         // Gold the next day's login will grant if the streak continues
+
+	PrestigeLevel      int     `json:"prestigeLevel"`      // Number of times the player has prestiged
+	PrestigeMultiplier float64 `json:"prestigeMultiplier"` // Permanent multiplier applied to every hero stat
+	GemUpgrades        int     `json:"gemUpgrades"`        // Permanent upgrades bought with gems; kept through prestige
+
+	Research []string `json:"research,omitempty"` // IDs of the unlocked research nodes, in unlock order; kept through prestige
+
+	ImportedExports   map[string]time.Time `json:"importedExports,omitempty"`  // When each already imported export of the player was taken, by nonce
+	ExportsVoidBefore time.Time            `json:"exportsVoidBefore,omitzero"` // Exports of the player taken before this can no longer be imported
 
-// Station represents an upgradeable facility within the hero factory.
-// Each station can be upgraded to increase its effectiveness multiplier.
-type Station struct {
-	Level      int     `json:"level"`      // Current upgrade level of the station (starts at 1)
-	Multiplier float64 `json:"multiplier"` // Effectiveness multiplier (increases with upgrades)
-	Cost       int     `json:"cost"`       // Gold cost to upgrade to the next level
+	Quests *DailyQuests `json:"quests,omitempty"` // Today's quests; drawn on the player's first battle or request of each day in their time zone
+
+	LastBattle *BattleResult `json:"lastBattle,omitempty"` // Outcome of the most recent battle; transient, never persisted
 }
 
 // Progress tracks a player's advancement and resources in the game.
 type Progress struct {
-	DungeonLevel int `json:"dungeonLevel"` // Current dungeon level the player has reached
-	Gold         int `json:"gold"`         // Currency used for upgrading factory stations
-	Experience   int `json:"experience"`   // Experience points gained from battles
+	DungeonLevel    int `json:"dungeonLevel"`    // Current dungeon level the player has reached
+	MaxDungeonLevel int `json:"maxDungeonLevel"` // Deepest dungeon level any of the player's heroes has reached; kept through prestige
+	Gold            int `json:"gold"`            // Currency used for upgrading factory stations
+	GoldPerTick     int `json:"goldPerTick"`     // Passive gold added every tick apart from battles, from the loot station
+	Gems            int `json:"gems"`            // Currency earned from bosses, spent on gem upgrades; kept through prestige
+	Experience      int `json:"experience"`      // Experience points gained from battles
+	HeroLevel       int `json:"heroLevel"`       // Hero level derived from Experience by HeroLevel
+
+	Combo           int     `json:"combo"`           // Victories in a row since the last defeat, which raise hero attack
+	TotalBattles    int     `json:"totalBattles"`    // Lifetime battles fought by all heroes, online and offline
+	BattlesWon      int     `json:"battlesWon"`      // Lifetime battles won
+	PlaytimeSeconds float64 `json:"playtimeSeconds"` // Lifetime game time simulated for the player, online and offline
 }
 
 // Hero represents a combat unit generated by the factory and sent into battle.
@@ -48,13 +71,25 @@ type Hero struct {
 	Armor  int `json:"armor"`  // Armor/defense - reduces incoming damage
 	Attack int `json:"attack"` // Attack damage - determines damage dealt to enemies
 	Loot   int `json:"loot"`   // Loot multiplier - increases gold rewards from victories
+
+	CritChance         float64 `json:"critChance"`         // Chance from 0 to 1 that an attack deals double damage
+	DodgeChance        float64 `json:"dodgeChance"`        // Chance from 0 to 1 that the hero avoids an enemy hit
+	DoubleStrikeChance float64 `json:"doubleStrikeChance"` // Chance from 0 to 1 that the hero attacks twice in a turn
 }
 
 // GameState holds the overall state of the game including all active players.
 // It uses a mutex to ensure thread-safe access to player data.
+//
+// The mutex guards the Players map, the fields of every player in it, the
+// hall of fame, and the banlist. Code that changes a player does so inside Update, and code
+// that reads a player's fields (including encoding it to JSON) does so inside
+// View, so each change is applied atomically per player and readers never see
+// it half-done.
 type GameState struct {
-	Players map[string]*Player `json:"players"` // Map of player ID to Player objects
-	mutex   sync.RWMutex                        // Read-write mutex for thread-safe access
+	Players    map[string]*Player `json:"players"`              // Map of player ID to Player objects
+	HallOfFame []Season           `json:"hallOfFame,omitempty"` // Archived seasons, oldest first
+	Bans       map[string]Ban     `json:"bans,omitempty"`       // Banned player IDs; nil in a partial snapshot that leaves the banlist alone
+	mutex      sync.RWMutex       // Read-write mutex for thread-safe access
 }
 
 // NewGameState creates and initializes a new GameState.
@@ -79,11 +114,30 @@ func (gs *GameState) SetPlayer(player *Player) {
 	gs.Players[player.ID] = player
 }
 
+// Update runs fn while holding the write lock, for changes to player fields.
+// fn must not call other GameState methods, which would deadlock.
+func (gs *GameState) Update(fn func()) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	fn()
+}
+
+// View runs fn while holding the read lock, for reading player fields.
+// fn must not call other GameState methods or modify any player.
+func (gs *GameState) View(fn func()) {
+	gs.mutex.RLock()
+	defer gs.mutex.RUnlock()
+	fn()
+}
+
 // GetAllPlayers safely retrieves all players from the game state.
+// Only the map is copied: the players in it are the live ones, so their fields
+// must still be read inside View and changed inside Update. SnapshotPlayers
+// returns copies that need no locking.
 func (gs *GameState) GetAllPlayers() map[string]*Player {
 	gs.mutex.RLock()
 	defer gs.mutex.RUnlock()
-	
+
 	// Create a copy to avoid race conditions
 	players := make(map[string]*Player)
 	for id, player := range gs.Players {
@@ -92,22 +146,31 @@ func (gs *GameState) GetAllPlayers() map[string]*Player {
 	return players
 }
 
+// defaultName builds the display name given to new players from the first
+// 8 characters of their ID, or the whole ID when it is shorter.
+func defaultName(playerID string) string {
+	runes := []rune(playerID)
+	if len(runes) > 8 {
+		runes = runes[:8]
+	}
+	return "Player " + string(runes)
+}
+
 // NewPlayer creates a new player with default factory and progress values.
 func NewPlayer(playerID string) *Player {
 	return &Player{
-		ID:   playerID,
-		Name: "Player " + playerID[:8], // Use first 8 chars of ID as display name
-		Factory: &Factory{
-			HPStation:     &Station{Level: 1, Multiplier: 1.0, Cost: 100},
-			ArmorStation:  &Station{Level: 1, Multiplier: 1.0, Cost: 100},
-			LootStation:   &Station{Level: 1, Multiplier: 1.0, Cost: 100},
-			AttackStation: &Station{Level: 1, Multiplier: 1.0, Cost: 100},
-		},
+		ID:      playerID,
+		Name:    defaultName(playerID),
+		Factory: NewFactory(),
 		Progress: &Progress{
-			DungeonLevel: 1,
-			Gold:         0,
-			Experience:   0,
+			DungeonLevel:    1,
+			MaxDungeonLevel: 1,
+			Gold:            0,
+			Experience:      0,
+			HeroLevel:       1,
 		},
-		LastSeen: time.Now(),
+		LastSeen:           time.Now(),
+		Difficulty:         DifficultyNormal,
+		PrestigeMultiplier: 1.0,
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,34 @@
+package models
+
+// PlayerProfile is the public view of a player that other players may see,
+// such as in the leaderboard UI. It leaves out resources, inventory,
+// reservations, and anything else private to the player.
+type PlayerProfile struct {
+	ID              string              `json:"id"`              // Player ID
+	Name            string              `json:"name"`            // Player display name
+	DungeonLevel    int                 `json:"dungeonLevel"`    // Current dungeon level
+	MaxDungeonLevel int                 `json:"maxDungeonLevel"` // Deepest dungeon level ever reached
+	PrestigeLevel   int                 `json:"prestigeLevel"`   // Number of times the player has prestiged
+	Stations        map[StationType]int `json:"stations"`        // Level of each station the factory has
+}
+
+// Profile returns the player's public profile. The caller must hold at least
+// the game-state read lock if the player is live.
+func (p *Player) Profile() PlayerProfile {
+	profile := PlayerProfile{
+		ID:            p.ID,
+		Name:          p.Name,
+		PrestigeLevel: p.PrestigeLevel,
+		Stations:      make(map[StationType]int, len(StationTypes)),
+	}
+	if p.Progress != nil {
+		profile.DungeonLevel = p.Progress.DungeonLevel
+		profile.MaxDungeonLevel = p.Progress.MaxDungeonLevel
+	}
+	for _, stationType := range StationTypes {
+		if station := p.Factory.Station(stationType); station != nil {
+			profile.Stations[stationType] = station.Level
+		}
+	}
+	return profile
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// QuestType is the goal of a daily quest.
+type QuestType string
+
+// The daily quest goals.
+const (
+	QuestWinBattles QuestType = "winBattles" // Win Target battles
+	QuestReachLevel QuestType = "reachLevel" // Bring any hero to dungeon level Target
+	QuestKillBosses QuestType = "killBosses" // Defeat Target bosses
+	QuestCombo      QuestType = "combo"      // Build a combo of Target victories in a row
+)
+
+// Quest is one of a player's daily quests. Each quest of a day has a
+// different type, which identifies it when claiming.
+type Quest struct {
+	Type       QuestType `json:"type"`                 // Goal of the quest
+	Target     int       `json:"target"`               // Count or level that completes the quest
+	Progress   int       `json:"progress"`             // Count or level reached so far, never above Target
+	RewardGold int       `json:"rewardGold"`           // Gold paid when the quest is claimed
+	RewardGems int       `json:"rewardGems,omitempty"` // Gems paid when the quest is claimed
+	Claimed    bool      `json:"claimed"`              // Whether the reward has been paid
+}
+
+// Complete reports whether the quest's target has been reached.
+func (q Quest) Complete() bool {
+	return q.Progress >= q.Target
+}
+
+// DailyQuests are the quests a player was given for one day in their time zone.
+type DailyQuests struct {
+	Day    string    `json:"day"`            // Day the quests were drawn for in the player's time zone, as YYYY-MM-DD
+	Start  time.Time `json:"start,omitzero"` // When that day began
+	Quests []Quest   `json:"quests"`         // The day's quests in the order they were drawn
+}
+
+// Quest returns the day's quest of the given type, or nil when there is none.
+func (d *DailyQuests) Quest(questType QuestType) *Quest {
+	for i := range d.Quests {
+		if d.Quests[i].Type == questType {
+			return &d.Quests[i]
+		}
+	}
+	return nil
+}
+
+// Clone returns a copy of the day's quests that shares nothing with the original.
+func (d *DailyQuests) Clone() *DailyQuests {
+	return &DailyQuests{Day: d.Day, Start: d.Start, Quests: slices.Clone(d.Quests)}
+}
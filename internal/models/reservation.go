@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Reservation is a station upgrade a player requested before they could afford it.
+// It completes automatically in the game loop once the player has enough gold.
+type Reservation struct {
+	Station    string    `json:"station"`    // Station type to upgrade
+	ReservedAt time.Time `json:"reservedAt"` // When the upgrade was requested
+}
+
+// Reserve records a pending upgrade for a station, replacing any earlier
+// reservation for the same station. Reservations complete in request order.
+func (p *Player) Reserve(station string, now time.Time) {
+	p.CancelReservation(station)
+	p.Reservations = append(p.Reservations, Reservation{Station: station, ReservedAt: now})
+}
+
+// CancelReservation removes any pending reservation for a station.
+func (p *Player) CancelReservation(station string) {
+	kept := p.Reservations[:0]
+	for _, reservation := range p.Reservations {
+		if reservation.Station != station {
+			kept = append(kept, reservation)
+		}
+	}
+	p.Reservations = kept
+	if len(p.Reservations) == 0 {
+		p.Reservations = nil
+	}
+}
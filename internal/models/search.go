@@ -0,0 +1,10 @@
+package models
+
+// SearchResult is a player found by a name search.
+type SearchResult struct {
+	ID              string `json:"id"`              // Player ID
+	Name            string `json:"name"`            // Player display name
+	MaxDungeonLevel int    `json:"maxDungeonLevel"` // Deepest dungeon level ever reached
+	PrestigeLevel   int    `json:"prestigeLevel"`   // Number of times the player has prestiged
+	Guild           string `json:"guild,omitempty"` // Guild the player belongs to (empty when in none)
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// HallOfFameSize is how many leaderboard entries are archived per season.
+const HallOfFameSize = 10
+
+// Season is an archived season of the hall of fame: the leaderboard top
+// players as they stood when an admin ended it.
+type Season struct {
+	Number  int                `json:"season"`  // Season number, counting from 1
+	EndedAt time.Time          `json:"endedAt"` // When the season was reset
+	Top     []LeaderboardEntry `json:"top"`     // Best players of the season, best first
+}
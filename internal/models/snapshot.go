@@ -0,0 +1,89 @@
+package models
+
+import (
+	"maps"
+	"slices"
+	"sort"
+)
+
+// Clone returns a deep copy of the player: the factory, progress, hero slots,
+// buff items, imported exports, quests, and last battle are copied along with every slice, so changes to the copy
+// never reach the original and vice versa. The caller must hold at least the
+// game-state read lock while cloning a player that is in the game state.
+func (p *Player) Clone() *Player {
+	clone := *p
+
+	if p.Factory != nil {
+		clone.Factory = &Factory{Stations: make(map[StationType]*Station, len(p.Factory.Stations))}
+		for stationType, station := range p.Factory.Stations {
+			stationCopy := *station
+			clone.Factory.Stations[stationType] = &stationCopy
+		}
+	}
+	if p.Progress != nil {
+		progress := *p.Progress
+		clone.Progress = &progress
+	}
+
+	clone.Duels = slices.Clone(p.Duels)
+	clone.Reservations = slices.Clone(p.Reservations)
+	clone.Inventory = slices.Clone(p.Inventory)
+	clone.BuffItems = maps.Clone(p.BuffItems)
+	clone.Buffs = slices.Clone(p.Buffs)
+	clone.Events = slices.Clone(p.Events)
+	clone.Research = slices.Clone(p.Research)
+	clone.ImportedExports = maps.Clone(p.ImportedExports)
+	if p.Quests != nil {
+		clone.Quests = p.Quests.Clone()
+	}
+
+	if p.Heroes != nil {
+		clone.Heroes = make([]*HeroSlot, len(p.Heroes))
+		for i, hero := range p.Heroes {
+			heroCopy := *hero
+			clone.Heroes[i] = &heroCopy
+		}
+	}
+	if p.LastBattle != nil {
+		battle := *p.LastBattle
+		if battle.Item != nil {
+			item := *battle.Item
+			battle.Item = &item
+		}
+		clone.LastBattle = &battle
+	}
+	return &clone
+}
+
+// SnapshotPlayers returns a deep copy of every player, ordered by ID, taken
+// under a single read lock so all copies come from the same moment. The copies
+// belong to the caller: they can be read and sorted without any lock, and
+// changing them has no effect on the game. Use UpdatePlayer to change a live player.
+func (gs *GameState) SnapshotPlayers() []*Player {
+	gs.mutex.RLock()
+	defer gs.mutex.RUnlock()
+
+	players := make([]*Player, 0, len(gs.Players))
+	for _, player := range gs.Players {
+		players = append(players, player.Clone())
+	}
+	sort.Slice(players, func(i, j int) bool {
+		return players[i].ID < players[j].ID
+	})
+	return players
+}
+
+// UpdatePlayer runs fn on the live player with the given ID while holding the
+// write lock, and reports whether the player exists. fn must not call other
+// GameState methods, which would deadlock, nor keep the player after returning.
+func (gs *GameState) UpdatePlayer(playerID string, fn func(player *Player)) bool {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	player, exists := gs.Players[playerID]
+	if !exists {
+		return false
+	}
+	fn(player)
+	return true
+}
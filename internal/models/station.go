@@ -0,0 +1,38 @@
+package models
+
+// BaseStationCost is what the first upgrade of every station costs.
+const BaseStationCost = 100
+
+// StationInfo describes a station type and how its upgrades progress, so
+// clients can list the stations without hardcoding them.
+type StationInfo struct {
+	Type                StationType `json:"type"`                // Key used in upgrade requests and, with a "Station" suffix, in the factory JSON
+	Name                string      `json:"name"`                // Display name
+	Description         string      `json:"description"`         // Short description of what upgrading the station does
+	BaseCost            int64       `json:"baseCost"`            // Cost of the first upgrade
+	MultiplierIncrement float64     `json:"multiplierIncrement"` // Multiplier gained per upgrade
+	CostGrowth          float64     `json:"costGrowth"`          // Factor each upgrade multiplies the next one's cost by
+}
+
+// stationDescriptions holds the display name and description of each station type.
+var stationDescriptions = map[StationType]struct{ name, description string }{
+	StationHP:     {"HP", "Increases hero health points"},
+	StationArmor:  {"Armor", "Reduces the damage enemies deal to the hero"},
+	StationLoot:   {"Loot", "Increases gold rewards and item drop chances"},
+	StationAttack: {"Attack", "Increases hero attack damage"},
+	StationCrit:   {"Crit", "Increases the chance of a hero attack dealing double damage"},
+}
+
+// NewStationInfo describes a station type whose upgrades add multiplierIncrement
+// to its multiplier and grow the next upgrade's cost by costGrowth.
+func NewStationInfo(stationType StationType, multiplierIncrement, costGrowth float64) StationInfo {
+	description := stationDescriptions[stationType]
+	return StationInfo{
+		Type:                stationType,
+		Name:                description.name,
+		Description:         description.description,
+		BaseCost:            BaseStationCost,
+		MultiplierIncrement: multiplierIncrement,
+		CostGrowth:          costGrowth,
+	}
+}
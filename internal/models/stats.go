@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// ServerStats summarizes the whole game for operators and curious players.
+// The player figures cover every player in memory, so evicted players are not counted.
+type ServerStats struct {
+	Players             int       `json:"players"`             // Players in memory
+	ConnectedClients    int       `json:"connectedClients"`    // Open WebSocket connections
+	TotalDungeonLevels  int64     `json:"totalDungeonLevels"`  // Sum of every player's current dungeon level
+	AverageDungeonLevel float64   `json:"averageDungeonLevel"` // Mean current dungeon level, 0 with no players
+	TotalGold           int64     `json:"totalGold"`           // Gold held by all players together
+	HighestDungeonLevel int       `json:"highestDungeonLevel"` // Deepest dungeon level any player has ever reached
+	ComputedAt          time.Time `json:"computedAt"`          // When the figures were computed
+}
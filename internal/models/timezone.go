@@ -0,0 +1,62 @@
+package models
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MinDayLength is the shortest a calendar day gets in any time zone: 24 hours
+// less the two-hour daylight saving shift of Antarctica/Troll. Two daily
+// periods starting less than this far apart are the same day seen from two
+// time zones.
+const MinDayLength = 22 * time.Hour
+
+// locations caches loaded time zones by name, since loading one reads the
+// time zone database.
+var locations sync.Map
+
+// ValidateTimeZone checks that name is a loadable IANA time zone such as "Europe/Berlin".
+// An empty name is valid and means UTC.
+func ValidateTimeZone(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("invalid time zone %q: %w", name, err)
+	}
+	return nil
+}
+
+// Location returns the player's time zone used for daily reset boundaries.
+// It falls back to UTC when no time zone is set or the stored name cannot be loaded.
+func (p *Player) Location() *time.Location {
+	if p.TimeZone == "" {
+		return time.UTC
+	}
+	if loc, ok := locations.Load(p.TimeZone); ok {
+		return loc.(*time.Location)
+	}
+	loc, err := time.LoadLocation(p.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	locations.Store(p.TimeZone, loc)
+	return loc
+}
+
+// DayStart returns local midnight of the calendar day containing t in loc.
+// Building the boundary from the calendar date keeps it correct across DST
+// transitions, where a day can be 23 or 25 hours long.
+func DayStart(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+}
+
+// BeginsNewDay reports whether a day starting at start is a new day after
+// the one that started at previous, or previous is zero. A day only counts as
+// new once MinDayLength has passed since the previous one began, so changing
+// time zones moves a player's days but never fits an extra one in between.
+func BeginsNewDay(previous, start time.Time) bool {
+	return previous.IsZero() || !start.Before(previous.Add(MinDayLength))
+}
@@ -0,0 +1,49 @@
+package models
+
+// PlayerUpdate is the part of a player sent in routine update messages: what
+// battles, passive income, buffs, and completed upgrades change from tick to
+// tick, with the same JSON field names as Player. Histories and bookkeeping,
+// such as events, duels, inventory, reservations, quests, research, and
+// imported exports, only travel with the full player, which a client gets in
+// gameState messages and from the player endpoint.
+type PlayerUpdate struct {
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	Factory     *Factory       `json:"factory"`
+	Progress    *Progress      `json:"progress"`
+	Heroes      []*HeroSlot    `json:"heroes,omitempty"`
+	Guild       string         `json:"guild,omitempty"`
+	Difficulty  DifficultyTier `json:"difficulty"`
+	AutoUpgrade string         `json:"autoUpgrade,omitempty"`
+
+	BuffItems map[BuffType]int `json:"buffItems,omitempty"`
+	Buffs     []Buff           `json:"buffs,omitempty"`
+
+	PrestigeLevel      int     `json:"prestigeLevel"`
+	PrestigeMultiplier float64 `json:"prestigeMultiplier"`
+	GemUpgrades        int     `json:"gemUpgrades"`
+
+	LastBattle *BattleResult `json:"lastBattle,omitempty"`
+}
+
+// Update returns the player's routine update. It shares the player's factory,
+// progress, and collections rather than copying them, so the caller must hold
+// at least the game-state read lock until the update has been encoded.
+func (p *Player) Update() PlayerUpdate {
+	return PlayerUpdate{
+		ID:                 p.ID,
+		Name:               p.Name,
+		Factory:            p.Factory,
+		Progress:           p.Progress,
+		Heroes:             p.Heroes,
+		Guild:              p.Guild,
+		Difficulty:         p.Difficulty,
+		AutoUpgrade:        p.AutoUpgrade,
+		BuffItems:          p.BuffItems,
+		Buffs:              p.Buffs,
+		PrestigeLevel:      p.PrestigeLevel,
+		PrestigeMultiplier: p.PrestigeMultiplier,
+		GemUpgrades:        p.GemUpgrades,
+		LastBattle:         p.LastBattle,
+	}
+}
@@ -0,0 +1,63 @@
+package models
+
+// UpgradePreview describes what a factory station upgrade would do to a player
+// without applying it. It is returned for dry-run upgrade requests.
+type UpgradePreview struct {
+	Station       string  `json:"station"`       // Station type being upgraded
+	NewLevel      int     `json:"newLevel"`      // Station level after the upgrade
+	NewMultiplier float64 `json:"newMultiplier"` // Station multiplier after the upgrade
+	NewCost       int64   `json:"newCost"`       // Cost of the following upgrade
+	GoldSpent     int     `json:"goldSpent"`     // Gold the upgrade costs
+	RemainingGold int     `json:"remainingGold"` // Player gold left after the upgrade
+}
+
+// BulkUpgradeResult reports the outcome of buying as many levels of a station as
+// the player can afford in one request.
+type BulkUpgradeResult struct {
+	Station       string `json:"station"`       // Station type that was upgraded
+	Levels        int    `json:"levels"`        // Number of levels purchased
+	GoldSpent     int    `json:"goldSpent"`     // Total gold spent across all levels
+	NewLevel      int    `json:"newLevel"`      // Station level after the upgrades
+	RemainingGold int    `json:"remainingGold"` // Player gold left after the upgrades
+}
+
+// DowngradeResult reports the outcome of selling back one level of a station.
+type DowngradeResult struct {
+	Station       string  `json:"station"`       // Station type that was downgraded
+	NewLevel      int     `json:"newLevel"`      // Station level after the downgrade
+	NewMultiplier float64 `json:"newMultiplier"` // Station multiplier after the downgrade
+	NewCost       int64   `json:"newCost"`       // Cost of buying the level back
+	Refund        int     `json:"refund"`        // Gold returned to the player
+	RemainingGold int     `json:"remainingGold"` // Player gold after the refund
+}
+
+// UpgradeCosts holds the total gold needed to buy the next 1, 10, and 100
+// levels of a station, so clients can show bulk prices without redoing the cost math.
+type UpgradeCosts struct {
+	Next1   int64 `json:"1"`   // Cost of the next level
+	Next10  int64 `json:"10"`  // Total cost of the next 10 levels
+	Next100 int64 `json:"100"` // Total cost of the next 100 levels
+}
+
+// UpgradeSimulation predicts how one more level of a station would change a
+// player's battles on their current dungeon level. The upgrade is hypothetical:
+// it is simulated whether or not the player can afford it, and nothing changes.
+type UpgradeSimulation struct {
+	Station      string `json:"station"`      // Station type upgraded in the simulation
+	DungeonLevel int    `json:"dungeonLevel"` // Dungeon level the battles were fought on
+	Cost         int64  `json:"cost"`         // Gold the upgrade would cost
+	Affordable   bool   `json:"affordable"`   // Whether the player has that much gold now
+
+	Before   BattleOdds `json:"before"`   // Battles fought by the player's current hero
+	After    BattleOdds `json:"after"`    // The same battles fought by the upgraded hero
+	WouldWin bool       `json:"wouldWin"` // Whether the upgraded hero wins at least half of them
+}
+
+// BattleOdds summarizes a batch of battles fought by one hero.
+type BattleOdds struct {
+	Hero        Hero    `json:"hero"`        // Hero that fought the battles
+	Battles     int     `json:"battles"`     // Number of battles fought
+	WinRate     float64 `json:"winRate"`     // Share of the battles won, from 0 to 1
+	HeroHPLeft  float64 `json:"heroHpLeft"`  // Average share of hero HP left after a win, the margin of victory
+	EnemyHPLeft float64 `json:"enemyHpLeft"` // Average share of enemy HP left after a loss, the margin of defeat
+}
@@ -0,0 +1,188 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// MaxMultiplier bounds station and prestige multipliers. Legitimate play stays
+// far below it; anything above it is corruption that would overflow hero stats.
+const MaxMultiplier = 1_000_000
+
+// InvalidFieldsError lists the fields Validate found out of range, by their
+// JSON path (such as "factory.hpStation.multiplier"), after repairing them.
+type InvalidFieldsError struct {
+	Fields []string // JSON paths of the repaired fields, in validation order
+}
+
+// Error lists the invalid fields.
+func (e *InvalidFieldsError) Error() string {
+	return "invalid fields: " + strings.Join(e.Fields, ", ")
+}
+
+// fieldChecker collects the paths of the fields that failed validation.
+type fieldChecker struct {
+	fields []string
+}
+
+// invalid records the field at path as invalid.
+func (c *fieldChecker) invalid(path string) {
+	c.fields = append(c.fields, path)
+}
+
+// atLeast raises *value to minimum, recording path when it was below.
+func (c *fieldChecker) atLeast(path string, value *int, minimum int) {
+	if *value < minimum {
+		*value = minimum
+		c.invalid(path)
+	}
+}
+
+// multiplier resets a non-finite *value to 1 and clamps it to [1, MaxMultiplier],
+// recording path when it was out of range.
+func (c *fieldChecker) multiplier(path string, value *float64) {
+	switch {
+	case math.IsNaN(*value) || math.IsInf(*value, 0) || *value < 1:
+		*value = 1
+	case *value > MaxMultiplier:
+		*value = MaxMultiplier
+	default:
+		return
+	}
+	c.invalid(path)
+}
+
+// err returns an InvalidFieldsError for the recorded fields, or nil when there are none.
+func (c *fieldChecker) err() error {
+	if len(c.fields) == 0 {
+		return nil
+	}
+	return &InvalidFieldsError{Fields: c.fields}
+}
+
+// Validate repairs every station of the factory: a missing station starts
+// over at level 1, levels are at least 1, multipliers are finite and within
+// [1, MaxMultiplier], and costs are positive. It returns an
+// *InvalidFieldsError naming each field it had to repair, or nil.
+func (f *Factory) Validate() error {
+	var checker fieldChecker
+	f.validate(&checker, "factory.")
+	return checker.err()
+}
+
+// validate repairs the factory, recording invalid fields under the given path prefix.
+func (f *Factory) validate(checker *fieldChecker, prefix string) {
+	if f.Stations == nil {
+		f.Stations = make(map[StationType]*Station, len(StationTypes))
+	}
+	for _, stationType := range StationTypes {
+		path := prefix + string(stationType) + stationJSONSuffix
+		station := f.Stations[stationType]
+		if station == nil {
+			f.Stations[stationType] = NewStation()
+			checker.invalid(path)
+			continue
+		}
+
+		checker.atLeast(path+".level", &station.Level, 1)
+		checker.multiplier(path+".multiplier", &station.Multiplier)
+		if station.Cost <= 0 {
+			station.Cost = BaseStationCost
+			checker.invalid(path + ".cost")
+		}
+	}
+}
+
+// Malformed reports whether the player lacks any of the structure game logic
+// dereferences: the factory, any of its stations, the progress, or a hero
+// slot. It only looks for missing pieces, not out-of-range values, so it is
+// cheap enough to check before every battle; Validate repairs all of them.
+func (p *Player) Malformed() bool {
+	if p.Factory == nil || p.Progress == nil {
+		return true
+	}
+	for _, stationType := range StationTypes {
+		if p.Factory.Stations[stationType] == nil {
+			return true
+		}
+	}
+	for _, hero := range p.Heroes {
+		if hero == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate repairs a player whose stats game logic could never have produced,
+// so a bug or a hand-edited save cannot feed negative or non-finite values
+// into hero creation. Besides the factory checks of Factory.Validate, a
+// name is sanitized and truncated to MaxNameLength, falling back to the
+// default name when nothing is left, a
+// missing factory or progress is recreated, gold, gems, gem upgrades,
+// experience, playtime, and item bonuses are not negative, buff item counts are positive and of known
+// types, dungeon levels are at least 1, and the
+// prestige multiplier is finite and within [1, MaxMultiplier]. It returns an
+// *InvalidFieldsError naming each field it had to repair, or nil.
+func (p *Player) Validate() error {
+	var checker fieldChecker
+
+	if name := TruncateName(SanitizeName(p.Name), MaxNameLength); name != p.Name {
+		if name == "" {
+			name = defaultName(p.ID)
+		}
+		p.Name = name
+		checker.invalid("name")
+	}
+
+	if p.Factory == nil {
+		p.Factory = NewFactory()
+		checker.invalid("factory")
+	} else {
+		p.Factory.validate(&checker, "factory.")
+	}
+
+	if p.Progress == nil {
+		p.Progress = &Progress{DungeonLevel: 1, MaxDungeonLevel: 1, HeroLevel: 1}
+		checker.invalid("progress")
+	}
+	progress := p.Progress
+	checker.atLeast("progress.dungeonLevel", &progress.DungeonLevel, 1)
+	checker.atLeast("progress.maxDungeonLevel", &progress.MaxDungeonLevel, progress.DungeonLevel)
+	checker.atLeast("progress.gold", &progress.Gold, 0)
+	checker.atLeast("progress.gems", &progress.Gems, 0)
+	checker.atLeast("progress.experience", &progress.Experience, 0)
+	checker.atLeast("progress.totalBattles", &progress.TotalBattles, 0)
+	checker.atLeast("progress.battlesWon", &progress.BattlesWon, 0)
+	checker.atLeast("progress.combo", &progress.Combo, 0)
+	if math.IsNaN(progress.PlaytimeSeconds) || math.IsInf(progress.PlaytimeSeconds, 0) || progress.PlaytimeSeconds < 0 {
+		progress.PlaytimeSeconds = 0
+		checker.invalid("progress.playtimeSeconds")
+	}
+
+	for i, hero := range p.Heroes {
+		if hero == nil {
+			p.Heroes[i] = &HeroSlot{DungeonLevel: 1}
+			checker.invalid(fmt.Sprintf("heroes[%d]", i))
+			continue
+		}
+		checker.atLeast(fmt.Sprintf("heroes[%d].dungeonLevel", i), &hero.DungeonLevel, 1)
+	}
+	for i := range p.Inventory {
+		checker.atLeast(fmt.Sprintf("inventory[%d].bonus", i), &p.Inventory[i].Bonus, 0)
+	}
+
+	for buffType, count := range p.BuffItems {
+		if _, known := BuffDefinitions[buffType]; !known || count <= 0 {
+			delete(p.BuffItems, buffType)
+			checker.invalid("buffItems." + string(buffType))
+		}
+	}
+
+	checker.atLeast("prestigeLevel", &p.PrestigeLevel, 0)
+	checker.atLeast("gemUpgrades", &p.GemUpgrades, 0)
+	checker.multiplier("prestigeMultiplier", &p.PrestigeMultiplier)
+
+	return checker.err()
+}
@@ -0,0 +1,75 @@
+// Package storage contains Persister implementations for saving the game state.
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// JSONFilePersister stores the whole game state as a single JSON document on disk.
+type JSONFilePersister struct {
+	path string // Location of the state file
+}
+
+// NewJSONFilePersister creates a persister that reads and writes the file at path.
+func NewJSONFilePersister(path string) *JSONFilePersister {
+	return &JSONFilePersister{path: path}
+}
+
+// Save writes the game state to a temporary file and renames it into place,
+// so a crash mid-write never leaves a truncated state file behind.
+func (p *JSONFilePersister) Save(gs *models.GameState) error {
+	data, err := json.Marshal(gs)
+	if err != nil {
+		return fmt.Errorf("encode game state: %w", err)
+	}
+
+	dir := filepath.Dir(p.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create state directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(p.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temporary state file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once the rename succeeds
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write game state: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sync game state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close game state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), p.path); err != nil {
+		return fmt.Errorf("replace state file: %w", err)
+	}
+	return nil
+}
+
+// Load reads the game state from disk. A missing file yields an empty game state.
+func (p *JSONFilePersister) Load() (*models.GameState, error) {
+	data, err := os.ReadFile(p.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return models.NewGameState(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read state file: %w", err)
+	}
+
+	gs := models.NewGameState()
+	if err := json.Unmarshal(data, gs); err != nil {
+		return nil, fmt.Errorf("decode state file %s: %w", p.path, err)
+	}
+	return gs, nil
+}
@@ -0,0 +1,333 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+	_ "modernc.org/sqlite" // Registers the pure-Go "sqlite" database/sql driver
+)
+
+// sqliteSchema creates the players, seasons, and bans tables on first use.
+// Each player row holds the player's full JSON document in data, which is what Load
+// reads back; the dungeon level, gold, experience and factory columns mirror it
+// so operators can query progress without decoding JSON. Each season row holds
+// one archived season of the hall of fame as JSON, and each ban row one banned
+// player's ban as JSON. The primary key already indexes player IDs, so the
+// duplicate index earlier versions created is dropped.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS players (
+	id            TEXT PRIMARY KEY,
+	dungeon_level INTEGER NOT NULL,
+	gold          INTEGER NOT NULL,
+	experience    INTEGER NOT NULL,
+	factory       TEXT NOT NULL,
+	data          TEXT NOT NULL
+);
+DROP INDEX IF EXISTS players_id_idx;
+CREATE TABLE IF NOT EXISTS seasons (
+	number INTEGER PRIMARY KEY,
+	data   TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS bans (
+	player_id TEXT PRIMARY KEY,
+	data      TEXT NOT NULL
+);
+`
+
+// sqliteUpsert inserts a player row or replaces the existing row with the same ID.
+const sqliteUpsert = `
+INSERT INTO players (id, dungeon_level, gold, experience, factory, data)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT (id) DO UPDATE SET
+	dungeon_level = excluded.dungeon_level,
+	gold          = excluded.gold,
+	experience    = excluded.experience,
+	factory       = excluded.factory,
+	data          = excluded.data
+`
+
+// sqliteSeasonUpsert inserts an archived season or replaces the row with the same number.
+const sqliteSeasonUpsert = `
+INSERT INTO seasons (number, data) VALUES (?, ?)
+ON CONFLICT (number) DO UPDATE SET data = excluded.data
+`
+
+// SQLitePersister stores one row per player in a SQLite database.
+// It scales to far more players than JSONFilePersister because each save is a
+// set of row upserts rather than a rewrite of one large document.
+type SQLitePersister struct {
+	db *sql.DB // Open database handle
+}
+
+// NewSQLitePersister opens (or creates) the SQLite database at path and ensures the schema exists.
+func NewSQLitePersister(path string) (*SQLitePersister, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite database: %w", err)
+	}
+	// SQLite allows a single writer; one connection avoids "database is locked" errors
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create sqlite schema: %w", err)
+	}
+	return &SQLitePersister{db: db}, nil
+}
+
+// Save upserts every player and archived season in a single transaction, so a
+// failed save leaves the previous snapshot intact. Rows for players no longer
+// in memory, and seasons missing from a partial snapshot, are kept. The bans
+// table is replaced by the snapshot's banlist, unless the snapshot has none
+// at all, as with a partial one.
+// The database's user_version records the schema version the rows were written with.
+func (p *SQLitePersister) Save(gs *models.GameState) error {
+	return p.save(gs, false)
+}
+
+// Replace is like Save, but first deletes every stored player, season, and
+// ban, in the same transaction, so afterwards the database holds exactly gs.
+// A snapshot without a banlist leaves no bans.
+func (p *SQLitePersister) Replace(gs *models.GameState) error {
+	return p.save(gs, true)
+}
+
+// save writes gs in a single transaction, replacing everything stored when
+// replace is set and upserting into it otherwise.
+func (p *SQLitePersister) save(gs *models.GameState, replace bool) error {
+	// Encode under the game state's read lock before touching the database
+	encoded, err := json.Marshal(gs)
+	if err != nil {
+		return fmt.Errorf("encode game state: %w", err)
+	}
+	var snapshot struct {
+		Players    map[string]models.StoredPlayer `json:"players"`
+		HallOfFame []models.Season                `json:"hallOfFame"`
+		Bans       map[string]models.Ban          `json:"bans"`
+	}
+	if err := json.Unmarshal(encoded, &snapshot); err != nil {
+		return fmt.Errorf("decode game state snapshot: %w", err)
+	}
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin save transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	if replace {
+		for _, table := range []string{"players", "seasons", "bans"} {
+			if _, err := tx.Exec(`DELETE FROM ` + table); err != nil {
+				return fmt.Errorf("clear %s: %w", table, err)
+			}
+		}
+		if snapshot.Bans == nil {
+			snapshot.Bans = map[string]models.Ban{}
+		}
+	}
+
+	stmt, err := tx.Prepare(sqliteUpsert)
+	if err != nil {
+		return fmt.Errorf("prepare player upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	for id, player := range snapshot.Players {
+		factory, err := json.Marshal(player.Factory)
+		if err != nil {
+			return fmt.Errorf("encode factory for player %s: %w", id, err)
+		}
+		data, err := json.Marshal(player)
+		if err != nil {
+			return fmt.Errorf("encode player %s: %w", id, err)
+		}
+
+		progress := player.Progress
+		if _, err := stmt.Exec(id, progress.DungeonLevel, progress.Gold, progress.Experience, string(factory), string(data)); err != nil {
+			return fmt.Errorf("save player %s: %w", id, err)
+		}
+	}
+
+	for _, season := range snapshot.HallOfFame {
+		data, err := json.Marshal(season)
+		if err != nil {
+			return fmt.Errorf("encode season %d: %w", season.Number, err)
+		}
+		if _, err := tx.Exec(sqliteSeasonUpsert, season.Number, string(data)); err != nil {
+			return fmt.Errorf("save season %d: %w", season.Number, err)
+		}
+	}
+
+	if snapshot.Bans != nil {
+		if _, err := tx.Exec(`DELETE FROM bans`); err != nil {
+			return fmt.Errorf("clear bans: %w", err)
+		}
+		for playerID, ban := range snapshot.Bans {
+			data, err := json.Marshal(ban)
+			if err != nil {
+				return fmt.Errorf("encode ban of player %s: %w", playerID, err)
+			}
+			if _, err := tx.Exec(`INSERT INTO bans (player_id, data) VALUES (?, ?)`, playerID, string(data)); err != nil {
+				return fmt.Errorf("save ban of player %s: %w", playerID, err)
+			}
+		}
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, models.SchemaVersion)); err != nil {
+		return fmt.Errorf("record schema version: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit save transaction: %w", err)
+	}
+	return nil
+}
+
+// Load reads every stored player into a new game state, migrating rows written
+// with an older schema version. A database written by a newer build is rejected
+// with models.ErrUnsupportedSchemaVersion.
+func (p *SQLitePersister) Load() (*models.GameState, error) {
+	var version int
+	if err := p.db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return nil, fmt.Errorf("read schema version: %w", err)
+	}
+
+	rows, err := p.db.Query(`SELECT id, data FROM players`)
+	if err != nil {
+		return nil, fmt.Errorf("query players: %w", err)
+	}
+	defer rows.Close()
+
+	players := make(map[string]*models.Player)
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, fmt.Errorf("scan player row: %w", err)
+		}
+
+		var stored models.StoredPlayer
+		if err := json.Unmarshal([]byte(data), &stored); err != nil {
+			return nil, fmt.Errorf("decode player %s: %w", id, err)
+		}
+		player := stored.Unwrap()
+		if player == nil {
+			return nil, fmt.Errorf("decode player %s: row holds no player", id)
+		}
+		players[player.ID] = player
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read player rows: %w", err)
+	}
+
+	if err := models.MigratePlayers(version, players); err != nil {
+		return nil, fmt.Errorf("migrate players: %w", err)
+	}
+	hallOfFame, err := p.loadHallOfFame()
+	if err != nil {
+		return nil, err
+	}
+	bans, err := p.loadBans()
+	if err != nil {
+		return nil, err
+	}
+
+	gs := models.NewGameState()
+	gs.ReplacePlayers(players)
+	gs.HallOfFame = hallOfFame
+	gs.Bans = bans
+	return gs, nil
+}
+
+// loadHallOfFame reads every archived season, oldest first.
+func (p *SQLitePersister) loadHallOfFame() ([]models.Season, error) {
+	rows, err := p.db.Query(`SELECT number, data FROM seasons ORDER BY number`)
+	if err != nil {
+		return nil, fmt.Errorf("query seasons: %w", err)
+	}
+	defer rows.Close()
+
+	var hallOfFame []models.Season
+	for rows.Next() {
+		var number int
+		var data string
+		if err := rows.Scan(&number, &data); err != nil {
+			return nil, fmt.Errorf("scan season row: %w", err)
+		}
+
+		var season models.Season
+		if err := json.Unmarshal([]byte(data), &season); err != nil {
+			return nil, fmt.Errorf("decode season %d: %w", number, err)
+		}
+		hallOfFame = append(hallOfFame, season)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read season rows: %w", err)
+	}
+	return hallOfFame, nil
+}
+
+// loadBans reads the banlist, keyed by player ID.
+func (p *SQLitePersister) loadBans() (map[string]models.Ban, error) {
+	rows, err := p.db.Query(`SELECT player_id, data FROM bans`)
+	if err != nil {
+		return nil, fmt.Errorf("query bans: %w", err)
+	}
+	defer rows.Close()
+
+	bans := make(map[string]models.Ban)
+	for rows.Next() {
+		var playerID, data string
+		if err := rows.Scan(&playerID, &data); err != nil {
+			return nil, fmt.Errorf("scan ban row: %w", err)
+		}
+
+		var ban models.Ban
+		if err := json.Unmarshal([]byte(data), &ban); err != nil {
+			return nil, fmt.Errorf("decode ban of player %s: %w", playerID, err)
+		}
+		bans[playerID] = ban
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read ban rows: %w", err)
+	}
+	return bans, nil
+}
+
+// LoadPlayer reads the stored player with the given ID, migrating a row
+// written with an older schema version. It reports false when no row exists.
+func (p *SQLitePersister) LoadPlayer(playerID string) (*models.Player, bool, error) {
+	var version int
+	if err := p.db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return nil, false, fmt.Errorf("read schema version: %w", err)
+	}
+
+	var data string
+	err := p.db.QueryRow(`SELECT data FROM players WHERE id = ?`, playerID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("query player %s: %w", playerID, err)
+	}
+
+	var stored models.StoredPlayer
+	if err := json.Unmarshal([]byte(data), &stored); err != nil {
+		return nil, false, fmt.Errorf("decode player %s: %w", playerID, err)
+	}
+	player := stored.Unwrap()
+	if player == nil {
+		return nil, false, fmt.Errorf("decode player %s: row holds no player", playerID)
+	}
+	if err := models.MigratePlayers(version, map[string]*models.Player{player.ID: player}); err != nil {
+		return nil, false, fmt.Errorf("migrate player %s: %w", playerID, err)
+	}
+	return player, true, nil
+}
+
+// Close releases the database handle.
+func (p *SQLitePersister) Close() error {
+	return p.db.Close()
+}
@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/models"
+)
+
+// newTestSQLite opens a SQLite persister on a fresh database that is closed when the test ends.
+func newTestSQLite(t *testing.T) *SQLitePersister {
+	t.Helper()
+	persister, err := NewSQLitePersister(filepath.Join(t.TempDir(), "game.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { persister.Close() })
+	return persister
+}
+
+// stateWith returns a game state holding new players with the given IDs, one
+// archived season, and a ban of each ID in bans.
+func stateWith(ids []string, bans ...string) *models.GameState {
+	players := make(map[string]*models.Player, len(ids))
+	for _, id := range ids {
+		players[id] = &models.Player{ID: id, Factory: models.NewFactory(), Progress: &models.Progress{DungeonLevel: 1}}
+	}
+	gs := models.NewGameState()
+	gs.ReplacePlayers(players)
+	gs.HallOfFame = []models.Season{{Number: len(ids)}}
+	gs.Bans = make(map[string]models.Ban)
+	for _, id := range bans {
+		gs.Bans[id] = models.Ban{}
+	}
+	return gs
+}
+
+func TestSQLiteSaveKeepsAbsentRows(t *testing.T) {
+	persister := newTestSQLite(t)
+	if err := persister.Save(stateWith([]string{"a", "b"}, "x")); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if err := persister.Save(stateWith([]string{"a"})); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded, err := persister.Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if count := len(loaded.GetAllPlayers()); count != 2 {
+		t.Errorf("loaded %d players, want 2", count)
+	}
+	if len(loaded.HallOfFame) != 2 {
+		t.Errorf("loaded %d seasons, want 2", len(loaded.HallOfFame))
+	}
+}
+
+func TestSQLiteReplaceDeletesAbsentRows(t *testing.T) {
+	persister := newTestSQLite(t)
+	if err := persister.Save(stateWith([]string{"a", "b"}, "x")); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	replacement := stateWith([]string{"a"})
+	replacement.Bans = nil
+	if err := persister.Replace(replacement); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+
+	loaded, err := persister.Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if _, exists := loaded.GetPlayer("b"); exists {
+		t.Error("player missing from the replacement is still stored")
+	}
+	if _, exists := loaded.GetPlayer("a"); !exists {
+		t.Error("player in the replacement is not stored")
+	}
+	if len(loaded.HallOfFame) != 1 || loaded.HallOfFame[0].Number != 1 {
+		t.Errorf("hall of fame = %+v, want only season 1", loaded.HallOfFame)
+	}
+	if len(loaded.Bans) != 0 {
+		t.Errorf("banlist = %v, want empty", loaded.Bans)
+	}
+	if _, exists, err := persister.LoadPlayer("b"); err != nil || exists {
+		t.Errorf("LoadPlayer(b) = %v, %v; want not stored", exists, err)
+	}
+}
+
+func TestSQLiteKeepsTokenHashes(t *testing.T) {
+	persister := newTestSQLite(t)
+	gs := stateWith([]string{"a"})
+	player, _ := gs.GetPlayer("a")
+	player.TokenHash = "hash"
+	if err := persister.Save(gs); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded, err := persister.Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if player, _ := loaded.GetPlayer("a"); player.TokenHash != "hash" {
+		t.Errorf("loaded token hash = %q, want hash", player.TokenHash)
+	}
+	player, exists, err := persister.LoadPlayer("a")
+	if err != nil || !exists || player.TokenHash != "hash" {
+		t.Errorf("LoadPlayer(a) = %v, %v; want the token hash kept", exists, err)
+	}
+}
+
+func TestSQLiteLeavesOutLastBattle(t *testing.T) {
+	persister := newTestSQLite(t)
+	gs := stateWith([]string{"a"})
+	player, _ := gs.GetPlayer("a")
+	player.LastBattle = &models.BattleResult{Victory: true, GoldReward: 10}
+	if err := persister.Save(gs); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if player.LastBattle == nil {
+		t.Error("saving cleared the live player's lastBattle")
+	}
+	stored, _, err := persister.LoadPlayer("a")
+	if err != nil {
+		t.Fatalf("load player: %v", err)
+	}
+	if stored.LastBattle != nil {
+		t.Errorf("stored player has lastBattle %+v, want none", stored.LastBattle)
+	}
+}
+
+func TestSQLiteRejectsNewerSchemaVersion(t *testing.T) {
+	persister := newTestSQLite(t)
+	if err := persister.Save(stateWith([]string{"a"})); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if _, err := persister.db.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, models.SchemaVersion+1)); err != nil {
+		t.Fatalf("set schema version: %v", err)
+	}
+
+	if _, err := persister.Load(); !errors.Is(err, models.ErrUnsupportedSchemaVersion) {
+		t.Errorf("loading a newer database returned %v, want ErrUnsupportedSchemaVersion", err)
+	}
+}
+
+func TestSQLiteDropsDuplicateIDIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "game.db")
+	persister, err := NewSQLitePersister(path)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	// Databases created by earlier versions carry a second index on the primary key
+	if _, err := persister.db.Exec(`CREATE UNIQUE INDEX players_id_idx ON players (id)`); err != nil {
+		t.Fatalf("create old index: %v", err)
+	}
+	persister.Close()
+
+	persister, err = NewSQLitePersister(path)
+	if err != nil {
+		t.Fatalf("reopen database: %v", err)
+	}
+	defer persister.Close()
+	var count int
+	if err := persister.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = 'players_id_idx'`).Scan(&count); err != nil {
+		t.Fatalf("query indexes: %v", err)
+	}
+	if count != 0 {
+		t.Error("reopened database still has the duplicate players_id_idx index")
+	}
+}
@@ -3,23 +3,48 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	_ "time/tzdata" // Embed the time zone database so player time zones load on minimal hosts
 
 	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
 	"github.com/evevioletrose-hash/idle-dungeon/internal/handlers"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
-	// Initialize the game server
-	gameServer := game.NewServer()
-	
+	// Log structured JSON at the level chosen by LOG_LEVEL
+	logger := newLogger()
+	slog.SetDefault(logger)
+
+	// Initialize the game server from environment configuration, loading saved state
+	persister, err := loadPersister()
+	if err != nil {
+		fatal("failed to initialize storage", err)
+	}
+	gameServer, err := game.NewServer(loadConfig(logger), persister)
+	if err != nil {
+		fatal("failed to initialize game server", err)
+	}
+
+	// Run until the process is asked to stop
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Start the game server background processes
-	gameServer.Start()
+	gameServer.Start(ctx)
 
-	// Setup HTTP routes
-	setupRoutes(gameServer)
+	// Setup HTTP routes; admin endpoints stay disabled unless ADMIN_TOKEN is set,
+	// and debug endpoints unless DEBUG_ENDPOINTS is true
+	setupRoutes(gameServer, os.Getenv("ADMIN_TOKEN"), envBool("DEBUG_ENDPOINTS", false))
 
 	// Get port from environment or use default
 	port := os.Getenv("PORT")
@@ -27,30 +52,182 @@ func main() {
 		port = "8080"
 	}
 
-	log.Printf("🏰 Idle Dungeon server starting on port %s", port)
-	log.Printf("🌐 Game available at http://localhost:%s", port)
-	
+	// Serve HTTPS (and wss) directly when a certificate and key are configured
+	certFile := os.Getenv("TLS_CERT_FILE")
+	keyFile := os.Getenv("TLS_KEY_FILE")
+	if (certFile == "") != (keyFile == "") {
+		fatal("failed to configure TLS", errors.New("both TLS_CERT_FILE and TLS_KEY_FILE must be set"))
+	}
+
+	scheme := "http"
+	if certFile != "" {
+		scheme = "https"
+	}
+
+	logger.Info("🏰 Idle Dungeon server starting", "port", port, "scheme", scheme,
+		"url", scheme+"://localhost:"+port)
+
 	// Start the HTTP server
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatal("Failed to start server:", err)
+	httpServer := &http.Server{
+		Addr:     ":" + port,
+		ErrorLog: slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+	listener, err := net.Listen("tcp", httpServer.Addr)
+	if err != nil {
+		fatal("failed to start server", err)
+	}
+	go func() {
+		if err := serve(httpServer, listener, certFile, keyFile); !errors.Is(err, http.ErrServerClosed) {
+			fatal("failed to start server", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop() // A second signal kills the process immediately
+	logger.Info("🛑 Shutting down, saving game state", "event", "shutdown")
+
+	// Stop accepting requests, then let the game loop finish its current tick
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		logger.Error("failed to shut down HTTP server", "event", "shutdown", "error", err)
+	}
+	gameServer.Wait()
+
+	if err := gameServer.Save(); err != nil {
+		logger.Error("failed to save game state", "event", "save", "error", err)
+	}
+}
+
+// serve serves HTTP on listener until httpServer is shut down, or HTTPS,
+// WebSocket upgrades included, when a certificate file is given.
+func serve(httpServer *http.Server, listener net.Listener, certFile, keyFile string) error {
+	if certFile != "" {
+		return httpServer.ServeTLS(listener, certFile, keyFile)
 	}
+	return httpServer.Serve(listener)
+}
+
+// fatal logs an error that prevents the server from running and exits.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
 
+// shutdownTimeout bounds how long in-flight HTTP requests may take to finish on shutdown.
+const shutdownTimeout = 10 * time.Second
+
 // setupRoutes configures all HTTP endpoints for the game server.
-func setupRoutes(gameServer *game.Server) {
+// Debug endpoints are only registered when debug is true.
+func setupRoutes(gameServer *game.Server, adminToken string, debug bool) {
+	// API endpoints answer browsers only from the origins in ALLOWED_ORIGINS
+	api := func(pattern string, handler http.HandlerFunc) {
+		http.HandleFunc(pattern, handlers.CORS(gameServer, handler))
+	}
+
 	// Serve static files (HTML, CSS, JavaScript)
 	http.Handle("/", http.FileServer(http.Dir("./static/")))
-	
+
 	// WebSocket endpoint for real-time multiplayer communication
 	http.HandleFunc("/ws", handlers.WebSocketHandler(gameServer))
-	
-	// REST API endpoints
-	http.HandleFunc("/api/player", handlers.PlayerHandler(gameServer))
-	http.HandleFunc("/api/upgrade", handlers.UpgradeHandler(gameServer))
-	
-	log.Println("📡 Routes configured:")
-	log.Println("  GET  /           - Game web interface")
-	log.Println("  WS   /ws         - WebSocket for real-time updates") 
-	log.Println("  GET  /api/player - Player data API")
-	log.Println("  POST /api/upgrade- Factory upgrade API")
-}
\ No newline at end of file
+
+	// REST API endpoints; those acting on a player require its bearer token
+	api("/api/player", handlers.RequirePlayerToken(gameServer, "id", handlers.PlayerHandler(gameServer)))
+	api("/api/stations", handlers.StationsHandler(gameServer))
+	api("/api/stats", handlers.StatsHandler(gameServer))
+	api("/api/upgrade", handlers.RequirePlayerToken(gameServer, "playerID", handlers.UpgradeHandler(gameServer)))
+	api("/api/duels", handlers.RequirePlayerToken(gameServer, "playerID", handlers.DuelsHandler(gameServer)))
+	api("/api/export", handlers.RequirePlayerToken(gameServer, "id", handlers.ExportHandler(gameServer)))
+	api("/api/import", handlers.RequirePlayerToken(gameServer, "id", handlers.ImportHandler(gameServer)))
+	api("/api/downgrade", handlers.RequirePlayerToken(gameServer, "playerID", handlers.DowngradeHandler(gameServer)))
+	api("/api/events", handlers.RequirePlayerToken(gameServer, "id", handlers.EventsHandler(gameServer)))
+	api("/api/hero", handlers.RequirePlayerToken(gameServer, "id", handlers.HeroHandler(gameServer)))
+	api("/api/battle/preview", handlers.RequirePlayerToken(gameServer, "playerID", handlers.BattlePreviewHandler(gameServer)))
+	api("/api/simulate", handlers.RequirePlayerToken(gameServer, "id", handlers.SimulateHandler(gameServer)))
+	api("/api/challenge", handlers.ChallengeHandler(gameServer))
+	api("/api/players", handlers.PlayersHandler(gameServer))
+	api("/api/leaderboard", handlers.LeaderboardHandler(gameServer))
+	api("/api/search", handlers.SearchHandler(gameServer))
+	api("/api/halloffame", handlers.HallOfFameHandler(gameServer))
+	api("/api/prestige", handlers.RequirePlayerToken(gameServer, "playerID", handlers.PrestigeHandler(gameServer)))
+	api("/api/reset", handlers.RequirePlayerToken(gameServer, "id", handlers.ResetHandler(gameServer)))
+	api("/api/gems/upgrade", handlers.RequirePlayerToken(gameServer, "playerID", handlers.GemUpgradeHandler(gameServer)))
+	api("/api/research", handlers.ResearchHandler(gameServer))
+	api("/api/research/unlock", handlers.RequirePlayerToken(gameServer, "playerID", handlers.UnlockResearchHandler(gameServer)))
+	api("/api/quests", handlers.RequirePlayerToken(gameServer, "id", handlers.QuestsHandler(gameServer)))
+	api("/api/quests/claim", handlers.RequirePlayerToken(gameServer, "id", handlers.ClaimQuestHandler(gameServer)))
+	api("/api/guild", handlers.GuildHandler(gameServer))
+	api("/api/guild/create", handlers.RequirePlayerToken(gameServer, "playerID", handlers.CreateGuildHandler(gameServer)))
+	api("/api/guild/join", handlers.RequirePlayerToken(gameServer, "playerID", handlers.JoinGuildHandler(gameServer)))
+	api("/api/guild/leave", handlers.RequirePlayerToken(gameServer, "playerID", handlers.LeaveGuildHandler(gameServer)))
+
+	// Prometheus metrics for the live game, alongside the default Go runtime metrics
+	prometheus.MustRegister(gameServer.Collectors()...)
+	http.Handle("/metrics", promhttp.Handler())
+
+	// Liveness and readiness probes for container orchestration
+	http.HandleFunc("/healthz", handlers.HealthHandler(gameServer))
+	http.HandleFunc("/readyz", handlers.ReadyHandler(gameServer))
+
+	// Admin endpoints, protected by the X-Admin-Token header
+	api("/api/admin/recompute", handlers.RequireAdmin(adminToken, handlers.RecomputeHandler(gameServer)))
+	api("/api/admin/backup", handlers.RequireAdmin(adminToken, handlers.BackupHandler(gameServer)))
+	api("/api/admin/restore", handlers.RequireAdmin(adminToken, handlers.RestoreHandler(gameServer)))
+	api("/api/admin/grant", handlers.RequireAdmin(adminToken, handlers.GrantHandler(gameServer)))
+	api("/api/admin/season/reset", handlers.RequireAdmin(adminToken, handlers.SeasonResetHandler(gameServer)))
+	api("/api/admin/kick", handlers.RequireAdmin(adminToken, handlers.KickHandler(gameServer)))
+	api("/api/admin/unban", handlers.RequireAdmin(adminToken, handlers.UnbanHandler(gameServer)))
+	api("/api/admin/token", handlers.RequireAdmin(adminToken, handlers.TokenHandler(gameServer)))
+
+	// Debug endpoints for investigating balance; never enable them on a public server
+	if debug {
+		api("/api/debug/replay", handlers.ReplayHandler(gameServer))
+	}
+
+	slog.Info("📡 Routes configured")
+	logRoute("GET", "/", "Game web interface")
+	logRoute("WS", "/ws", "WebSocket for real-time updates")
+	logRoute("GET", "/api/player", "Player data API")
+	logRoute("POST", "/api/upgrade", "Factory upgrade API")
+	logRoute("GET", "/api/duels", "Duel history (POST to challenge)")
+	logRoute("GET", "/api/export", "Download a signed copy of a player's progress")
+	logRoute("POST", "/api/import", "Restore a player from a signed export")
+	logRoute("POST", "/api/downgrade", "Sell back a station level for a partial refund")
+	logRoute("GET", "/api/events", "A player's recent notable events")
+	logRoute("GET", "/api/hero", "A player's hero with a per-station stat breakdown")
+	logRoute("GET", "/api/simulate", "Predict battle odds after one hypothetical upgrade")
+	logRoute("GET", "/api/challenge", "Predict a duel without recording it")
+	logRoute("GET", "/api/leaderboard", "Top players by dungeon level")
+	logRoute("GET", "/api/search", "Find players by name prefix")
+	logRoute("GET", "/api/halloffame", "Top players of every past season")
+	logRoute("POST", "/api/prestige", "Reset progress for a permanent hero multiplier")
+	logRoute("POST", "/api/reset", "Reset a player to a fresh start")
+	logRoute("POST", "/api/gems/upgrade", "Spend gems on a permanent hero multiplier")
+	logRoute("GET", "/api/research", "The research tree")
+	logRoute("POST", "/api/research/unlock", "Spend gold on a research node")
+	logRoute("GET", "/api/quests", "A player's daily quests")
+	logRoute("POST", "/api/quests/claim", "Claim a completed daily quest's reward")
+	logRoute("GET", "/api/guild", "Guild members and contribution")
+	logRoute("POST", "/api/guild/create", "Found a guild")
+	logRoute("POST", "/api/guild/join", "Join a guild by name")
+	logRoute("POST", "/api/guild/leave", "Leave the player's guild")
+	logRoute("GET", "/metrics", "Prometheus metrics")
+	logRoute("GET", "/healthz", "Liveness probe")
+	logRoute("GET", "/readyz", "Readiness probe, 503 until the first tick")
+	logRoute("POST", "/api/admin/recompute", "Recompute derived player fields (admin)")
+	logRoute("GET", "/api/admin/backup", "Download full game state backup (admin)")
+	logRoute("POST", "/api/admin/restore", "Restore game state from a backup (admin)")
+	logRoute("POST", "/api/admin/grant", "Adjust a player's gold (admin)")
+	logRoute("POST", "/api/admin/season/reset", "Archive the season top 10 and reset every player (admin)")
+	logRoute("POST", "/api/admin/kick", "Disconnect a player, optionally banning them (admin)")
+	logRoute("POST", "/api/admin/unban", "Lift a player's ban (admin)")
+	logRoute("POST", "/api/admin/token", "Issue a player a new token (admin)")
+	if debug {
+		logRoute("GET", "/api/debug/replay", "Replay a battle from its seed (debug)")
+	}
+}
+
+// logRoute logs one configured HTTP endpoint.
+func logRoute(method, path, description string) {
+	slog.Info("route", "method", method, "path", path, "description", description)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/game/testutil"
+	"github.com/evevioletrose-hash/idle-dungeon/internal/handlers"
+	"github.com/gorilla/websocket"
+)
+
+// writeSelfSignedCert writes a certificate for 127.0.0.1 and its key to
+// files in a temporary directory, returning their paths and the certificate.
+func writeSelfSignedCert(t *testing.T) (certFile, keyFile string, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "idle-dungeon test"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	if cert, err = x509.ParseCertificate(der); err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("encode key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	return certFile, keyFile, cert
+}
+
+func TestServeTLS(t *testing.T) {
+	h := testutil.New(t, game.DefaultConfig())
+	certFile, keyFile, cert := writeSelfSignedCert(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", handlers.WebSocketHandler(h.Server))
+	mux.HandleFunc("/healthz", handlers.HealthHandler(h.Server))
+	httpServer := &http.Server{Handler: mux}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- serve(httpServer, listener, certFile, keyFile) }()
+	t.Cleanup(func() {
+		httpServer.Close()
+		<-done
+	})
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	tlsConfig := &tls.Config{RootCAs: roots}
+	host := listener.Addr().String()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	resp, err := client.Get("https://" + host + "/healthz")
+	if err != nil {
+		t.Fatalf("HTTPS request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("HTTPS request: status %d, want 200", resp.StatusCode)
+	}
+
+	// A browser page served over HTTPS sends its own origin, which must pass CheckOrigin
+	dialer := websocket.Dialer{TLSClientConfig: tlsConfig}
+	conn, _, err := dialer.Dial("wss://"+host+"/ws?playerID=alice", http.Header{"Origin": {"https://" + host}})
+	if err != nil {
+		t.Fatalf("wss upgrade: %v", err)
+	}
+	defer conn.Close()
+	if _, _, err := conn.ReadMessage(); err != nil {
+		t.Errorf("read initial state over wss: %v", err)
+	}
+}
+
+func TestServeWithoutCertificateServesPlainHTTP(t *testing.T) {
+	httpServer := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- serve(httpServer, listener, "", "") }()
+	t.Cleanup(func() {
+		httpServer.Close()
+		<-done
+	})
+
+	resp, err := http.Get("http://" + listener.Addr().String() + "/")
+	if err != nil {
+		t.Fatalf("HTTP request: %v", err)
+	}
+	resp.Body.Close()
+}
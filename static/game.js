@@ -1,14 +1,26 @@
+// Display names of the enemy archetypes reported in battle results
+const ENEMY_NAMES = {
+    standard: 'monster',
+    tank: 'hulking tank',
+    glassCannon: 'glass cannon',
+    swarm: 'swarm',
+};
+
 class IdleDungeonGame {
     constructor() {
         this.ws = null;
         this.playerID = this.getOrCreatePlayerID();
+        this.token = localStorage.getItem('playerToken') || '';
         this.player = null;
         this.battleLog = [];
-        this.onlinePlayers = {};
+        this.topPlayers = [];
+        this.nextRequestID = 1;
+        this.pendingRequests = new Map(); // requestId -> callback awaiting its ack
         
         this.initializeUI();
         this.connect();
         this.startUpdateLoop();
+        this.startLeaderboardLoop();
     }
 
     getOrCreatePlayerID() {
@@ -22,12 +34,13 @@ class IdleDungeonGame {
 
     initializeUI() {
         document.getElementById('player-id').textContent = this.playerID;
+        document.getElementById('player-name').addEventListener('click', () => this.promptForName());
         this.updateConnectionStatus('Connecting...', 'connecting');
     }
 
     connect() {
         const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
-        const wsUrl = `${protocol}//${window.location.host}/ws?playerID=${this.playerID}`;
+        const wsUrl = `${protocol}//${window.location.host}/ws?playerID=${encodeURIComponent(this.playerID)}&token=${encodeURIComponent(this.token)}`;
         
         this.ws = new WebSocket(wsUrl);
         
@@ -45,8 +58,14 @@ class IdleDungeonGame {
             }
         };
         
-        this.ws.onclose = () => {
+        this.ws.onclose = (event) => {
             console.log('Disconnected from game server');
+            this.pendingRequests.clear(); // Unanswered requests never will be on this connection
+            if (event.code === 1008) {
+                // Kicked or banned by an administrator; reconnecting would only be refused
+                this.updateConnectionStatus(`Disconnected (${event.reason || 'kicked'})`, 'disconnected');
+                return;
+            }
             this.updateConnectionStatus('Disconnected', 'disconnected');
             // Attempt to reconnect after 3 seconds
             setTimeout(() => this.connect(), 3000);
@@ -62,21 +81,76 @@ class IdleDungeonGame {
         switch (data.type) {
             case 'gameState':
                 this.player = data.player;
+                if (data.token) {
+                    // Issued once, when the player is created
+                    this.token = data.token;
+                    localStorage.setItem('playerToken', data.token);
+                }
+                if (data.reset) {
+                    this.addBattleLogEntry('🔄 Your progress was reset for a fresh start');
+                }
+                if (data.offlineGains) {
+                    const gains = data.offlineGains;
+                    this.addBattleLogEntry(`While you were away: ${gains.battles} battles, +${gains.gold} gold, +${gains.experience} exp, +${gains.gems} gems, +${gains.levels} levels`, 'victory');
+                }
                 this.updateUI();
                 break;
             case 'update':
-                if (data.players && data.players[this.playerID]) {
+                if (data.player) {
                     const oldLevel = this.player?.progress?.dungeonLevel || 0;
-                    this.player = data.players[this.playerID];
+                    // Updates carry only what ticks change; keep the rest of the last full state
+                    this.player = { ...this.player, ...data.player };
                     
                     // Check for level progression
                     if (this.player.progress.dungeonLevel > oldLevel) {
-                        this.addBattleLogEntry(`Victory! Advanced to dungeon level ${this.player.progress.dungeonLevel}`, 'victory');
+                        const enemy = ENEMY_NAMES[this.player.lastBattle?.enemyType] || 'enemy';
+                        this.addBattleLogEntry(`Victory over a ${enemy}! Advanced to dungeon level ${this.player.progress.dungeonLevel}`, 'victory');
                     }
                 }
-                this.onlinePlayers = data.players || {};
                 this.updateUI();
                 break;
+            case 'events':
+                (data.events || []).forEach(event => this.handleEvent(event));
+                break;
+            case 'error':
+                this.addBattleLogEntry(`⚠️ ${data.reason}`);
+                break;
+            case 'ack': {
+                // Failures are already reported by their error reply
+                const onAck = this.pendingRequests.get(data.requestId);
+                this.pendingRequests.delete(data.requestId);
+                if (onAck) onAck(data);
+                break;
+            }
+        }
+    }
+
+    handleEvent(event) {
+        switch (event.type) {
+            case 'upgradeCompleted':
+                this.addBattleLogEntry(`Reserved ${event.data.station} upgrade completed (level ${event.data.level})`, 'victory');
+                break;
+            case 'buffDrop':
+                this.addBattleLogEntry(`✨ The boss dropped a ${event.data.buff} buff`, 'victory');
+                break;
+            case 'itemDrop':
+                this.addBattleLogEntry(`🎁 Found a ${event.data.name} (+${event.data.bonus} ${event.data.stat})`, 'victory');
+                break;
+            case 'bossBattle':
+                if (event.data.victory) {
+                    this.addBattleLogEntry(`👑 Boss of dungeon level ${event.data.dungeonLevel} defeated! +${event.data.gems} gems`, 'victory');
+                } else {
+                    this.addBattleLogEntry(`👑 The boss of dungeon level ${event.data.dungeonLevel} holds firm, retrying...`);
+                }
+                break;
+            case 'dailyLogin':
+                this.addBattleLogEntry(`📅 Day ${event.data.streak} login bonus: +${event.data.gold} gold`, 'victory');
+                break;
+            case 'duel': {
+                const won = event.data.winnerId === this.playerID;
+                this.addBattleLogEntry(`Duel ${won ? 'won' : 'lost'} in ${event.data.rounds} rounds`, won ? 'victory' : '');
+                break;
+            }
         }
     }
 
@@ -88,19 +162,62 @@ class IdleDungeonGame {
 
         // Update progress
         document.getElementById('dungeon-level').textContent = this.player.progress.dungeonLevel;
-        document.getElementById('gold').textContent = this.player.progress.gold;
+        const goldPerTick = this.player.progress.goldPerTick || 0;
+        document.getElementById('gold').textContent = goldPerTick > 0 ? `${this.player.progress.gold} (+${goldPerTick}/tick)` : this.player.progress.gold;
         document.getElementById('experience').textContent = this.player.progress.experience;
+        document.getElementById('hero-level').textContent = this.player.progress.heroLevel || 1;
+        const heroes = [this.player.progress.dungeonLevel, ...(this.player.heroes || []).map(hero => hero.dungeonLevel)];
+        document.getElementById('hero-count').textContent = `${heroes.length}/3 (levels ${heroes.join(', ')})`;
+        document.getElementById('unlock-hero-btn').disabled = heroes.length >= 3;
+        document.getElementById('prestige').textContent = `${this.player.prestigeLevel} (${(this.player.prestigeMultiplier || 1).toFixed(1)}x)`;
+        const gemUpgrades = this.player.gemUpgrades || 0;
+        const gemCost = 5 * (gemUpgrades + 1);
+        document.getElementById('gems').textContent = `${this.player.progress.gems || 0} (${(1 + 0.05 * gemUpgrades).toFixed(2)}x)`;
+        document.getElementById('gem-upgrade-btn').textContent = `Gem Upgrade (${gemCost} gems)`;
+        document.getElementById('gem-upgrade-btn').disabled = (this.player.progress.gems || 0) < gemCost;
+        const combo = this.player.progress.combo || 0;
+        document.getElementById('combo').textContent = combo > 0 ? `${combo} (+${Math.min(50, 2 * combo)}% attack)` : '0';
+        document.getElementById('difficulty').value = this.player.difficulty || 'normal';
+        document.getElementById('auto-upgrade').value = this.player.autoUpgrade || 'off';
+        this.updateBuffs();
 
         // Update factory stations
         this.updateStation('hp', this.player.factory.hpStation);
         this.updateStation('armor', this.player.factory.armorStation);
         this.updateStation('attack', this.player.factory.attackStation);
         this.updateStation('loot', this.player.factory.lootStation);
+        this.updateStation('crit', this.player.factory.critStation);
 
-        // Update online players
+        // Update top players
         this.updatePlayersList();
     }
 
+    updateBuffs() {
+        const container = document.getElementById('buffs');
+        container.innerHTML = '';
+
+        const owned = this.player.buffItems || {};
+        const now = Date.now();
+        for (const buffType of ['attack', 'armor', 'hp', 'loot']) {
+            const active = (this.player.buffs || []).find(buff => buff.type === buffType && Date.parse(buff.expiresAt) > now);
+            if (!owned[buffType] && !active) continue;
+
+            const button = document.createElement('button');
+            button.className = 'upgrade-btn';
+            button.disabled = !owned[buffType];
+            button.textContent = `2x ${buffType} (${owned[buffType] || 0})`;
+            if (active) {
+                button.textContent += ` ${Math.ceil((Date.parse(active.expiresAt) - now) / 1000)}s left`;
+            }
+            button.onclick = () => activateBuff(buffType);
+            container.appendChild(button);
+        }
+
+        if (!container.children.length) {
+            container.textContent = 'None';
+        }
+    }
+
     updateStation(stationType, station) {
         document.getElementById(`${stationType}-level`).textContent = station.level;
         document.getElementById(`${stationType}-multiplier`).textContent = station.multiplier.toFixed(1) + 'x';
@@ -121,9 +238,7 @@ class IdleDungeonGame {
         const playersContainer = document.getElementById('online-players');
         playersContainer.innerHTML = '';
 
-        const playerList = Object.values(this.onlinePlayers).sort((a, b) => {
-            return b.progress.dungeonLevel - a.progress.dungeonLevel;
-        });
+        const playerList = this.topPlayers;
 
         playerList.forEach((player, index) => {
             const playerElement = document.createElement('div');
@@ -132,19 +247,21 @@ class IdleDungeonGame {
                 playerElement.classList.add('current-player');
             }
 
+            // Names are set as text, never parsed as markup
             playerElement.innerHTML = `
-                <div class="player-name">${player.name}</div>
+                <div class="player-name"></div>
                 <div class="player-stats">
-                    Level: ${player.progress.dungeonLevel}<br>
-                    Gold: ${player.progress.gold}
+                    Best level: ${player.maxDungeonLevel} (now ${player.dungeonLevel})<br>
+                    Gold: ${player.gold}
                 </div>
             `;
+            playerElement.querySelector('.player-name').textContent = player.name;
 
             playersContainer.appendChild(playerElement);
         });
 
         if (playerList.length === 0) {
-            playersContainer.innerHTML = '<div class="loading">No players online</div>';
+            playersContainer.innerHTML = '<div class="loading">No players yet</div>';
         }
     }
 
@@ -174,26 +291,59 @@ class IdleDungeonGame {
         setInterval(() => {
             if (this.player) {
                 const hero = this.calculateCurrentHero();
-                const message = `Hero deployed: HP:${hero.hp} ATK:${hero.attack} ARM:${hero.armor} LOOT:${hero.loot}`;
+                const message = `Hero deployed: HP:${hero.hp} ATK:${hero.attack} ARM:${hero.armor} LOOT:${hero.loot} CRIT:${Math.round(hero.crit * 100)}%`;
                 this.addBattleLogEntry(message);
             }
         }, 5000);
     }
 
+    startLeaderboardLoop() {
+        // Updates only carry this player, so the player list is polled separately
+        const refresh = () => fetch('/api/leaderboard?limit=10')
+            .then(response => response.json())
+            .then(players => {
+                this.topPlayers = players;
+                this.updatePlayersList();
+            })
+            .catch(error => console.error('Error fetching leaderboard:', error));
+        refresh();
+        setInterval(refresh, 5000);
+    }
+
     calculateCurrentHero() {
-        if (!this.player) return { hp: 0, attack: 0, armor: 0, loot: 0 };
+        if (!this.player) return { hp: 0, attack: 0, armor: 0, loot: 0, crit: 0 };
 
         const factory = this.player.factory;
+        const permanent = (this.player.prestigeMultiplier || 1) * (1 + 0.05 * (this.player.gemUpgrades || 0));
+        const levelsGained = (this.player.progress.heroLevel || 1) - 1;
+        const now = Date.now();
+        const buff = stat => (this.player.buffs || [])
+            .filter(active => active.type === stat && Date.parse(active.expiresAt) > now)
+            .reduce(multiplier => multiplier * 2, 1);
         return {
-            hp: Math.floor(100 * factory.hpStation.multiplier),
-            attack: Math.floor(20 * factory.attackStation.multiplier),
-            armor: Math.floor(10 * factory.armorStation.multiplier),
-            loot: Math.floor(1 * factory.lootStation.multiplier)
+            hp: Math.floor(100 * factory.hpStation.multiplier * permanent * buff('hp')) + 5 * levelsGained,
+            attack: Math.floor(20 * factory.attackStation.multiplier * permanent * buff('attack')) + levelsGained,
+            armor: Math.floor(10 * factory.armorStation.multiplier * permanent * buff('armor')),
+            loot: Math.floor(1 * factory.lootStation.multiplier * permanent * buff('loot')),
+            crit: Math.min(0.5, 0.1 * factory.critStation.multiplier)
         };
     }
 
-    sendMessage(message) {
+    promptForName() {
+        const name = window.prompt('Choose a display name (up to 24 characters):', this.player?.name || '');
+        if (name !== null) {
+            this.sendMessage({ type: 'setName', name: name });
+        }
+    }
+
+    // Sends a message to the server. When onAck is given, the message carries a
+    // requestId and onAck is called with the server's ack for it.
+    sendMessage(message, onAck) {
         if (this.ws && this.ws.readyState === WebSocket.OPEN) {
+            if (onAck) {
+                message.requestId = this.nextRequestID++;
+                this.pendingRequests.set(message.requestId, onAck);
+            }
             this.ws.send(JSON.stringify(message));
         }
     }
@@ -205,10 +355,42 @@ function upgradeStation(stationType) {
         window.game.sendMessage({
             type: 'upgrade',
             station: stationType
+        }, ack => {
+            if (ack.ok) window.game.addBattleLogEntry(`🔧 ${stationType} upgrade accepted`);
         });
     }
 }
 
+function setDifficulty(difficulty) {
+    if (window.game) {
+        window.game.sendMessage({ type: 'setDifficulty', difficulty: difficulty });
+    }
+}
+
+function setAutoUpgrade(mode) {
+    if (window.game) {
+        window.game.sendMessage({ type: 'setAutoUpgrade', mode: mode });
+    }
+}
+
+function activateBuff(buffType) {
+    if (window.game) {
+        window.game.sendMessage({ type: 'activateBuff', buff: buffType });
+    }
+}
+
+function gemUpgrade() {
+    if (window.game) {
+        window.game.sendMessage({ type: 'gemUpgrade' });
+    }
+}
+
+function prestige() {
+    if (window.game && window.confirm('Prestige? Your gold, dungeon level and stations will reset.')) {
+        window.game.sendMessage({ type: 'prestige' });
+    }
+}
+
 // Initialize game when page loads
 document.addEventListener('DOMContentLoaded', () => {
     window.game = new IdleDungeonGame();